@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/structure"
+	"github.com/codehakase/md/internal/theme"
+)
+
+var (
+	astJSON  bool
+	astColor string
+)
+
+// astTextLimit is the number of characters of a node's text shown in the
+// tree; --json always includes all of it
+const astTextLimit = 60
+
+var astCmd = &cobra.Command{
+	Use:   "ast FILE",
+	Short: "Show the syntax tree of a markdown file",
+	Long: `Show the syntax tree md parses a markdown file into, with the kind, source
+position (line:column) and attributes of every node. The parser uses the same
+extensions as rendering, so the tree matches what is displayed. --json prints
+the full tree, including the complete text of text and code nodes, for tools.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		source, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", args[0], err)
+		}
+		parser, err := newParser(filepath.Dir(args[0]))
+		if err != nil {
+			return err
+		}
+		doc := structure.AST(parser, source)
+
+		out := cmd.OutOrStdout()
+		if astJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(doc)
+		}
+
+		themeManager, err := newTheme(filepath.Dir(args[0]), astColor)
+		if err != nil {
+			return err
+		}
+		printAST(out, themeManager, doc, "", "")
+		return nil
+	},
+}
+
+// printAST writes a node and its children as a tree. prefix starts the
+// node's own line and indent the lines of its children.
+func printAST(out io.Writer, tm *theme.ThemeManager, node *structure.Node, prefix, indent string) {
+	line := tm.Style(prefix, theme.TableBorder) + tm.Style(node.Kind, theme.Bold)
+	if node.Position != nil {
+		position := fmt.Sprintf("%d:%d-%d:%d", node.Position.Start.Line, node.Position.Start.Column,
+			node.Position.End.Line, node.Position.End.Column)
+		line += " " + tm.Style(position, theme.TableBorder)
+	}
+	for _, field := range nodeFields(node) {
+		line += " " + field
+	}
+	if node.Text != "" {
+		line += " " + tm.Style(quoteText(node.Text), theme.Code)
+	}
+	fmt.Fprintln(out, line)
+
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			printAST(out, tm, child, indent+"└─ ", indent+"   ")
+		} else {
+			printAST(out, tm, child, indent+"├─ ", indent+"│  ")
+		}
+	}
+}
+
+// nodeFields returns a node's properties and attributes as sorted name=value
+// pairs
+func nodeFields(node *structure.Node) []string {
+	var fields []string
+	for name, value := range node.Properties {
+		if s, ok := value.(string); ok {
+			value = strconv.Quote(s)
+		}
+		fields = append(fields, fmt.Sprintf("%s=%v", name, value))
+	}
+	sort.Strings(fields)
+
+	var attributes []string
+	for name, value := range node.Attributes {
+		attributes = append(attributes, fmt.Sprintf("%s=%s", name, strconv.Quote(value)))
+	}
+	sort.Strings(attributes)
+	return append(fields, attributes...)
+}
+
+// quoteText quotes a node's text, shortened to astTextLimit characters
+func quoteText(text string) string {
+	if utf8.RuneCountInString(text) > astTextLimit {
+		text = string([]rune(text)[:astTextLimit-1]) + "…"
+	}
+	return strings.TrimSpace(strconv.Quote(text))
+}
+
+func init() {
+	astCmd.Flags().BoolVar(&astJSON, "json", false, "Print the tree as JSON")
+	astCmd.Flags().StringVar(&astColor, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.AddCommand(astCmd)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+var codeThemesCmd = &cobra.Command{
+	Use:   "code-themes",
+	Short: "List the available code highlighting themes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, name := range theme.ChromaThemes() {
+			fmt.Fprintln(cmd.OutOrStdout(), name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(codeThemesCmd)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/config"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config [markdown-file]",
+	Short: "Show the effective settings and where each came from",
+	Long: `Show the settings md would use for a file in the current directory, or for
+the given file, after applying the user config, the nearest .md.toml project
+config and MD_* environment variables.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = filepath.Dir(args[0])
+		}
+
+		cfg, err := config.Load(dir)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		userPath := config.UserPath()
+		if _, err := os.Stat(userPath); err != nil {
+			userPath += " (not found)"
+		}
+		projectPath := config.FindProjectFile(dir)
+		if projectPath == "" {
+			projectPath = "(none)"
+		}
+		fmt.Fprintf(out, "user config:    %s\n", userPath)
+		fmt.Fprintf(out, "project config: %s\n\n", projectPath)
+
+		tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+		for _, key := range config.Keys {
+			value := cfg.Value(key)
+			if value == "" {
+				value = `""`
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", key, value, cfg.Origin(key))
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/codehakase/md/internal/config"
+	"github.com/codehakase/md/internal/diff"
+	"github.com/codehakase/md/internal/highlighter"
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+var (
+	diffGitRev     string
+	diffSideBySide bool
+	diffContext    int
+	diffColorMode  string
+)
+
+// minDiffColumn is the narrowest a rendered block is laid out
+const minDiffColumn = 20
+
+var diffCmd = &cobra.Command{
+	Use:   "diff OLD NEW | diff --git REV [--] FILE",
+	Short: "Show the rendered changes between two markdown documents",
+	Long: `Compare two markdown documents block by block and show the changes rendered:
+removed blocks are marked with -, added blocks with + and edited blocks with ~,
+with the words that changed inside paragraphs, headings, lists and quotes
+highlighted. Changes to line wrapping or markup style that don't change the
+rendered document are ignored.
+
+With --git, FILE is compared with its version at the git revision REV.
+--side-by-side shows the old document on the left and the new one on the
+right. Runs of unchanged blocks are shortened to --context blocks around the
+changes; a negative --context shows the whole document.`,
+	ValidArgsFunction: completeFileArg,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if diffGitRev != "" {
+			if len(args) != 1 {
+				return fmt.Errorf("--git takes exactly one file, got %d", len(args))
+			}
+			return nil
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("diff takes an old and a new file, got %d argument(s)", len(args))
+		}
+		return nil
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// Unreadable files and revisions are not usage errors
+		cmd.SilenceUsage = true
+
+		var oldName, newName string
+		var oldSource, newSource []byte
+		var err error
+		if diffGitRev != "" {
+			newName = args[0]
+			oldName = diffGitRev + ":" + newName
+			if oldSource, err = gitShow(diffGitRev, newName); err != nil {
+				return err
+			}
+		} else {
+			oldName, newName = args[0], args[1]
+			if oldSource, err = readSource(cmd.InOrStdin(), oldName); err != nil {
+				return err
+			}
+		}
+		if newSource, err = readSource(cmd.InOrStdin(), newName); err != nil {
+			return err
+		}
+
+		mode, err := theme.ParseColorMode(diffColorMode)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(newName)
+		cfg, err := loadConfig(cmd, dir)
+		if err != nil {
+			return err
+		}
+		themeManager, options, codeHighlighter, err := renderSetup(cfg, mode)
+		if err != nil {
+			return err
+		}
+		options.BaseDir = dir
+		options.EmbedRoot = projectRoot(dir)
+
+		width := cfg.Width
+		if width <= 0 {
+			width = 80
+			if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+				width = w
+			}
+		}
+		// The gutter takes two columns, and side by side the separator three
+		options.Width = max(width-2, minDiffColumn)
+		if diffSideBySide {
+			options.Width = max((width-3)/2-2, minDiffColumn)
+		}
+
+		p := &diffPrinter{
+			out:         cmd.OutOrStdout(),
+			theme:       themeManager,
+			renderer:    renderer.NewWithOptions(themeManager, options),
+			highlighter: codeHighlighter,
+			column:      options.Width + 2,
+		}
+		changes := diff.Markdown(p.renderer, oldSource, newSource)
+		return p.print(oldName, newName, changes)
+	},
+}
+
+// gitShow returns the content of file at a git revision
+func gitShow(rev, file string) ([]byte, error) {
+	// Revisions are never options, such as --output=FILE
+	if rev == "" || strings.HasPrefix(rev, "-") {
+		return nil, fmt.Errorf("invalid git revision %q", rev)
+	}
+	dir, base := filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	git := exec.Command("git", "-C", dir, "show", "--end-of-options", rev+":./"+base)
+	var stderr bytes.Buffer
+	git.Stderr = &stderr
+	content, err := git.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("failed to read %s at %s: %s", file, rev, message)
+		}
+		return nil, fmt.Errorf("failed to read %s at %s: %w", file, rev, err)
+	}
+	return content, nil
+}
+
+// diffPrinter renders block changes in the unified or side by side layout
+type diffPrinter struct {
+	out         io.Writer
+	theme       *theme.ThemeManager
+	renderer    *renderer.Renderer
+	highlighter *highlighter.Highlighter
+	// column is the width of a side in the side by side layout, gutter
+	// included
+	column int
+}
+
+func (p *diffPrinter) print(oldName, newName string, changes []diff.BlockChange) error {
+	fmt.Fprintln(p.out, p.theme.Style("--- "+renderer.SanitizeText(oldName), theme.Deleted))
+	fmt.Fprintln(p.out, p.theme.Style("+++ "+renderer.SanitizeText(newName), theme.Inserted))
+
+	for i := 0; i < len(changes); i++ {
+		if changes[i].Kind == diff.Unchanged && diffContext >= 0 {
+			end := i
+			for end < len(changes) && changes[end].Kind == diff.Unchanged {
+				end++
+			}
+			// Keep the context after the previous change and before the next
+			from, to := i, end
+			if i > 0 {
+				from = min(i+diffContext, end)
+			}
+			if end < len(changes) {
+				to = max(end-diffContext, from)
+			}
+			if to > from {
+				for ; i < from; i++ {
+					if err := p.printChange(changes[i]); err != nil {
+						return err
+					}
+				}
+				fmt.Fprintln(p.out)
+				fmt.Fprintln(p.out, p.theme.Style(fmt.Sprintf("⋯ %d unchanged block(s)", to-from), theme.TableBorder))
+				i = to - 1
+				continue
+			}
+		}
+		if err := p.printChange(changes[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gutters are the marks in front of the lines of each kind of change
+var gutters = map[diff.ChangeKind]struct {
+	mark string
+	key  theme.ColorKey
+}{
+	diff.Added:   {"+", theme.Inserted},
+	diff.Removed: {"-", theme.Deleted},
+	diff.Changed: {"~", theme.Warning},
+}
+
+func (p *diffPrinter) printChange(change diff.BlockChange) error {
+	fmt.Fprintln(p.out)
+	if diffSideBySide {
+		return p.printSideBySide(change)
+	}
+
+	text := change.New
+	switch change.Kind {
+	case diff.Removed:
+		text = change.Old
+	case diff.Changed:
+		if change.Merged == "" {
+			// Blocks without word changes are shown whole, before and after
+			if err := p.printLines(diff.Removed, change.Old); err != nil {
+				return err
+			}
+			return p.printLines(diff.Added, change.New)
+		}
+		text = change.Merged
+	}
+	return p.printLines(change.Kind, text)
+}
+
+func (p *diffPrinter) printLines(kind diff.ChangeKind, markdown string) error {
+	lines, err := p.render(markdown)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintln(p.out, p.gutter(kind)+line)
+	}
+	return nil
+}
+
+func (p *diffPrinter) printSideBySide(change diff.BlockChange) error {
+	left, err := p.render(change.Old)
+	if err != nil {
+		return err
+	}
+	right := left
+	if change.Kind != diff.Unchanged {
+		if right, err = p.render(change.New); err != nil {
+			return err
+		}
+	}
+
+	leftKind, rightKind := change.Kind, change.Kind
+	switch change.Kind {
+	case diff.Added:
+		leftKind = diff.Unchanged
+	case diff.Removed:
+		rightKind = diff.Unchanged
+	}
+	separator := p.theme.Style(" │ ", theme.TableBorder)
+	for i := 0; i < max(len(left), len(right)); i++ {
+		var l, r string
+		if i < len(left) {
+			l = p.gutter(leftKind) + p.truncate(left[i])
+		}
+		if i < len(right) {
+			r = p.gutter(rightKind) + p.truncate(right[i])
+		}
+		padding := strings.Repeat(" ", max(p.column-renderer.VisibleWidth(l), 0))
+		fmt.Fprintln(p.out, strings.TrimRight(l+padding+separator+r, " "))
+	}
+	return nil
+}
+
+// truncate cuts a rendered line that doesn't fit its side, such as a long
+// line of code
+func (p *diffPrinter) truncate(line string) string {
+	line = renderer.TruncateANSI(line, p.column-2)
+	if !p.theme.SupportsColor() {
+		return renderer.StripANSI(line)
+	}
+	return line
+}
+
+func (p *diffPrinter) gutter(kind diff.ChangeKind) string {
+	g, ok := gutters[kind]
+	if !ok {
+		return "  "
+	}
+	return p.theme.Style(g.mark, g.key) + " "
+}
+
+// render renders a block to lines, with the changed words it marks styled
+func (p *diffPrinter) render(markdown string) ([]string, error) {
+	if markdown == "" {
+		return nil, nil
+	}
+	if !p.theme.SupportsColor() {
+		markdown = plainWords.Replace(markdown)
+	}
+	rendered, err := p.renderer.RenderContent([]byte(markdown), p.highlighter)
+	if err != nil {
+		return nil, fmt.Errorf("rendering error: %v", err)
+	}
+	rendered = strings.Trim(rendered, "\n")
+
+	var lines []string
+	for _, line := range strings.Split(rendered, "\n") {
+		lines = append(lines, p.styleWords(line))
+	}
+	// Rendered blocks can start or end with blank lines of padding
+	for len(lines) > 0 && strings.TrimSpace(renderer.StripANSI(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(renderer.StripANSI(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines, nil
+}
+
+// plainWords delimits changed words without colors, like git's plain word
+// diff. The delimiters are put in the markdown before rendering, so they are
+// counted when lines are wrapped.
+var plainWords = strings.NewReplacer(
+	string(diff.DeletedStart), "[-", string(diff.DeletedEnd), "-]",
+	string(diff.InsertedStart), "{+", string(diff.InsertedEnd), "+}",
+)
+
+var sgrPattern = regexp.MustCompile(`^\x1b\[[0-9;]*m`)
+
+// styleWords replaces the change markers in a rendered line with the
+// inserted and deleted styles, restoring the style of the surrounding text
+// after each word
+func (p *diffPrinter) styleWords(line string) string {
+	if !strings.ContainsAny(line, string([]rune{diff.DeletedStart, diff.InsertedStart})) {
+		return line
+	}
+
+	var b strings.Builder
+	// active is the style of the text around the markers
+	var active string
+	for i := 0; i < len(line); {
+		if sequence := sgrPattern.FindString(line[i:]); sequence != "" {
+			if sequence == theme.ANSIReset {
+				active = ""
+			} else {
+				active += sequence
+			}
+			b.WriteString(sequence)
+			i += len(sequence)
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		switch r {
+		case diff.DeletedStart:
+			b.WriteString(p.theme.GetColor(theme.Deleted) + p.theme.GetColor(theme.Strikethrough))
+		case diff.InsertedStart:
+			b.WriteString(p.theme.GetColor(theme.Inserted) + p.theme.GetColor(theme.Bold))
+		case diff.DeletedEnd, diff.InsertedEnd:
+			b.WriteString(p.theme.Reset() + active)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func init() {
+	flags := diffCmd.Flags()
+	flags.StringVar(&diffGitRev, "git", "", "Compare FILE with its version at this git revision")
+	flags.BoolVar(&diffSideBySide, "side-by-side", false, "Show the old and new documents next to each other")
+	flags.IntVar(&diffContext, "context", 2, "Unchanged blocks to show around each change (negative shows all)")
+	flags.Int(config.KeyWidth, 0, "Total width of the output (default the terminal width, or 80)")
+	flags.StringVar(&diffColorMode, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.AddCommand(diffCmd)
+}
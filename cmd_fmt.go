@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/codehakase/md/internal/diff"
+	"github.com/codehakase/md/internal/format"
+	"github.com/codehakase/md/internal/renderer"
+)
+
+var (
+	fmtWrite   bool
+	fmtCheck   bool
+	fmtDiff    bool
+	fmtOptions = format.DefaultOptions()
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [file-or-directory...]",
+	Short: "Format markdown files",
+	Long: `Format markdown files as normalized CommonMark/GFM: ATX or setext headings,
+one bullet marker, renumbered ordered lists, aligned pipe tables, fenced code
+blocks and prose kept as written, wrapped at --width or unwrapped. Files are
+parsed with the configured markdown extensions. Reference links are written as
+inline links.
+
+The formatted document is printed unless --write replaces the files. --check
+lists the files that aren't formatted and --diff shows the changes formatting
+would make; both exit with a non-zero status when a file would change. With no
+arguments, standard input is formatted to standard output.`,
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := fmtOptions.Validate(); err != nil {
+			return err
+		}
+		if len(args) == 0 {
+			if fmtWrite {
+				return fmt.Errorf("--write needs files to write to")
+			}
+			args = []string{"-"}
+		}
+
+		var files []string
+		for _, arg := range args {
+			if arg == "-" {
+				files = append(files, arg)
+				continue
+			}
+			found, err := markdownFiles([]string{arg})
+			if err != nil {
+				return err
+			}
+			files = append(files, found...)
+		}
+		dir := "."
+		if args[0] != "-" {
+			dir = filepath.Dir(args[0])
+		}
+		parser, err := newParser(dir)
+		if err != nil {
+			return err
+		}
+		// Unformatted files are not usage errors
+		cmd.SilenceUsage = true
+
+		out := cmd.OutOrStdout()
+		changed := 0
+		for _, file := range files {
+			source, err := readSource(cmd.InOrStdin(), file)
+			if err != nil {
+				return err
+			}
+			formatted := format.Format(parser, source, fmtOptions)
+			if bytes.Equal(source, formatted) {
+				if !fmtCheck && !fmtDiff && !fmtWrite {
+					out.Write(formatted)
+				}
+				continue
+			}
+			changed++
+
+			switch {
+			case fmtCheck || fmtDiff:
+				if fmtCheck {
+					fmt.Fprintln(out, renderer.SanitizeText(file))
+				}
+				if fmtDiff {
+					unified := diff.Unified(file, file+" (formatted)", string(source), string(formatted), 3)
+					// The document's own text must not reach a terminal as
+					// escape sequences; piped, the diff is kept exact
+					if isTerminal(out) {
+						unified = renderer.SanitizeText(unified)
+					}
+					fmt.Fprint(out, unified)
+				}
+			case fmtWrite:
+				if err := writeFormatted(file, formatted); err != nil {
+					return err
+				}
+			default:
+				out.Write(formatted)
+			}
+		}
+
+		if (fmtCheck || fmtDiff) && changed > 0 {
+			return fmt.Errorf("%d file(s) need formatting", changed)
+		}
+		return nil
+	},
+}
+
+// readSource reads a file, or standard input for "-"
+func readSource(stdin io.Reader, file string) ([]byte, error) {
+	if file == "-" {
+		source, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read standard input: %w", err)
+		}
+		return source, nil
+	}
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+	return source, nil
+}
+
+// writeFormatted replaces a file's content, keeping its permissions. The new
+// content is written to a temporary file first, so an interrupted write
+// can't truncate the document.
+func writeFormatted(file string, content []byte) error {
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", file, err)
+	}
+	return nil
+}
+
+// isTerminal reports whether w writes to a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
+func init() {
+	flags := fmtCmd.Flags()
+	flags.BoolVarP(&fmtWrite, "write", "w", false, "Write the formatted documents back to their files")
+	flags.BoolVar(&fmtCheck, "check", false, "List files that aren't formatted and exit non-zero if there are any")
+	flags.BoolVarP(&fmtDiff, "diff", "d", false, "Show the changes formatting would make and exit non-zero if there are any")
+	flags.StringVar(&fmtOptions.Wrap, "prose-wrap", fmtOptions.Wrap, "How to break prose into lines: preserve, always (at --width) or never")
+	flags.IntVar(&fmtOptions.Width, "width", fmtOptions.Width, "Line width for --prose-wrap always")
+	flags.StringVar(&fmtOptions.HeadingStyle, "heading-style", fmtOptions.HeadingStyle, "Heading style: atx or setext")
+	flags.StringVar(&fmtOptions.BulletMarker, "bullet", fmtOptions.BulletMarker, "Bullet list marker: -, * or +")
+	flags.StringVar(&fmtOptions.Numbering, "numbering", fmtOptions.Numbering, "Ordered list numbering: ascending or one")
+	fmtCmd.MarkFlagsMutuallyExclusive("write", "check")
+	fmtCmd.MarkFlagsMutuallyExclusive("write", "diff")
+	rootCmd.AddCommand(fmtCmd)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/search"
+	"github.com/codehakase/md/internal/theme"
+)
+
+var (
+	grepIgnoreCase   bool
+	grepFixedStrings bool
+	grepIn           string
+	grepColor        string
+)
+
+var grepCmd = &cobra.Command{
+	Use:   "grep PATTERN [file-or-directory...]",
+	Short: "Search the text of markdown files",
+	Long: `Search the text of markdown files for a regular expression, ignoring markup
+such as emphasis markers, link syntax and HTML comments. Each match is shown
+with its source line number and the path of headings it is under, such as
+"Setup › Linux › Packages". Directories are searched for markdown files; the
+default is the current directory.
+
+By default only prose is searched, including headings and inline code. Use
+--in to search code blocks, headings or link targets instead, or a comma
+separated combination such as --in text,code. md exits with a non-zero status
+when nothing matches.`,
+	Args: cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeFileArg(cmd, args, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		expr := args[0]
+		if grepFixedStrings {
+			expr = regexp.QuoteMeta(expr)
+		}
+		if grepIgnoreCase {
+			expr = "(?i)" + expr
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %w", err)
+		}
+		scope, err := search.ParseScope(grepIn)
+		if err != nil {
+			return err
+		}
+
+		paths := args[1:]
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		files, err := markdownFiles(paths)
+		if err != nil {
+			return err
+		}
+		parser, err := newParser(filepath.Dir(paths[0]))
+		if err != nil {
+			return err
+		}
+		themeManager, err := newTheme(filepath.Dir(paths[0]), grepColor)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		found := 0
+		for _, file := range files {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			for _, match := range search.Search(parser, source, pattern, scope) {
+				printMatch(out, themeManager, file, match)
+				found++
+			}
+		}
+
+		if found == 0 {
+			// Like grep, no matches is reported by the exit status alone
+			cmd.SilenceErrors = true
+			cmd.SilenceUsage = true
+			return fmt.Errorf("no matches")
+		}
+		return nil
+	},
+}
+
+// printMatch writes a match as a file:line and breadcrumb line followed by
+// the indented text of the line, with the matches highlighted
+func printMatch(out io.Writer, tm *theme.ThemeManager, file string, match search.Match) {
+	location := tm.Style(fmt.Sprintf("%s:%d", renderer.SanitizeText(file), match.Line), theme.TableBorder)
+	if len(match.Breadcrumb) > 0 {
+		location += "  " + tm.Style(renderer.SanitizeText(strings.Join(match.Breadcrumb, " › ")), theme.Bold)
+	}
+	fmt.Fprintln(out, location)
+
+	var text strings.Builder
+	last := 0
+	for _, r := range match.Ranges {
+		text.WriteString(renderer.SanitizeText(match.Text[last:r[0]]))
+		text.WriteString(tm.Style(renderer.SanitizeText(match.Text[r[0]:r[1]]), theme.Match))
+		last = r[1]
+	}
+	text.WriteString(renderer.SanitizeText(match.Text[last:]))
+	fmt.Fprintf(out, "    %s\n", text.String())
+}
+
+func init() {
+	grepCmd.Flags().BoolVarP(&grepIgnoreCase, "ignore-case", "i", false, "Match case-insensitively")
+	grepCmd.Flags().BoolVarP(&grepFixedStrings, "fixed-strings", "F", false, "Treat PATTERN as a literal string")
+	grepCmd.Flags().StringVar(&grepIn, "in", "text", "What to search: text, code, headings or links, or a comma separated list")
+	grepCmd.Flags().StringVar(&grepColor, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.AddCommand(grepCmd)
+}
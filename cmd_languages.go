@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/config"
+	"github.com/codehakase/md/internal/highlighter"
+)
+
+var languagesCmd = &cobra.Command{
+	Use:   "languages [markdown-file]",
+	Short: "List the languages and aliases available for code blocks",
+	Long: `List every language that code blocks can be highlighted as, with the names
+and file name patterns that select it. Aliases from the user and project
+config are included; give a file to use the project config next to it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := "."
+		if len(args) == 1 {
+			dir = filepath.Dir(args[0])
+		}
+		cmd.SilenceUsage = true
+
+		cfg, err := config.Load(dir)
+		if err != nil {
+			return err
+		}
+
+		if _, err := highlighter.RegisterLexers(highlighter.LexerDir()); err != nil {
+			return err
+		}
+		languages := highlighter.NewLanguages()
+		if err := languages.AddAliases(cfg.Aliases); err != nil {
+			return settingError(cfg, config.KeyAliases, err)
+		}
+
+		tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "LANGUAGE\tALIASES\tFILENAMES")
+		for _, lang := range languages.List() {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", lang.Name, strings.Join(lang.Aliases, ", "), strings.Join(lang.Filenames, ", "))
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(languagesCmd)
+}
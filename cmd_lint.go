@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/lint"
+	"github.com/codehakase/md/internal/renderer"
+)
+
+var lintJSON bool
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [file-or-directory...]",
+	Short: "Check markdown files for structural problems and broken links",
+	Long: `Check markdown files for heading level jumps, duplicate heading anchors,
+empty links, images without alt text, relative links to missing files and
+#anchors that match no heading in the linked file. Directories are searched
+for markdown files; the default is the current directory.
+
+Problems are printed as file:line:col and md exits with a non-zero status
+when any are found.`,
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := markdownFiles(args)
+		if err != nil {
+			return err
+		}
+		parser, err := newParser(filepath.Dir(args[0]))
+		if err != nil {
+			return err
+		}
+		// Problems in the documents are not usage errors
+		cmd.SilenceUsage = true
+
+		linter := lint.New(parser)
+		issues := []lint.Issue{}
+		for _, file := range files {
+			found, err := linter.LintFile(file)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, found...)
+		}
+
+		out := cmd.OutOrStdout()
+		if lintJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(issues); err != nil {
+				return err
+			}
+		} else {
+			for _, issue := range issues {
+				fmt.Fprintln(out, renderer.SanitizeText(issue.String()))
+			}
+		}
+
+		if len(issues) > 0 {
+			return fmt.Errorf("%d problem(s) found in %d file(s)", len(issues), countFiles(issues))
+		}
+		return nil
+	},
+}
+
+// countFiles returns the number of distinct files with issues
+func countFiles(issues []lint.Issue) int {
+	files := map[string]bool{}
+	for _, issue := range issues {
+		files[issue.File] = true
+	}
+	return len(files)
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "Print problems as a JSON array")
+	rootCmd.AddCommand(lintCmd)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/structure"
+	"github.com/codehakase/md/internal/theme"
+)
+
+var (
+	outlineJSON  bool
+	outlineColor string
+)
+
+// outlineReport is the outline of one file, as printed by --json
+type outlineReport struct {
+	File     string               `json:"file"`
+	Headings []*structure.Heading `json:"headings"`
+}
+
+var outlineCmd = &cobra.Command{
+	Use:   "outline [file-or-directory...]",
+	Short: "Show the heading tree of markdown files",
+	Long: `Show the headings of markdown files as a tree, with the anchor of each heading
+and the source lines of its section, up to the next heading of the same or a
+higher level. Directories are searched for markdown files; the default is the
+current directory. --json prints the same data for tools.`,
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := markdownFiles(args)
+		if err != nil {
+			return err
+		}
+		parser, err := newParser(filepath.Dir(args[0]))
+		if err != nil {
+			return err
+		}
+
+		reports := []outlineReport{}
+		for _, file := range files {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			reports = append(reports, outlineReport{File: file, Headings: structure.Outline(parser, source)})
+		}
+
+		out := cmd.OutOrStdout()
+		if outlineJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(reports)
+		}
+
+		themeManager, err := newTheme(filepath.Dir(args[0]), outlineColor)
+		if err != nil {
+			return err
+		}
+		for i, report := range reports {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			fmt.Fprintln(out, renderer.SanitizeText(report.File))
+			printOutline(out, themeManager, report.Headings, 0)
+		}
+		return nil
+	},
+}
+
+// headerKeys are the theme colors of headings by level
+var headerKeys = []theme.ColorKey{theme.Header1, theme.Header2, theme.Header3, theme.Header4, theme.Header5, theme.Header6}
+
+// printOutline writes headings indented by their depth in the tree
+func printOutline(out io.Writer, tm *theme.ThemeManager, headings []*structure.Heading, depth int) {
+	for _, h := range headings {
+		title := tm.Style(strings.Repeat("#", h.Level)+" "+renderer.SanitizeText(h.Title), headerKeys[min(max(h.Level, 1), 6)-1])
+		details := fmt.Sprintf("#%s  lines %d-%d", h.Anchor, h.StartLine, h.EndLine)
+		fmt.Fprintf(out, "%s%s  %s\n", strings.Repeat("  ", depth), title, tm.Style(details, theme.TableBorder))
+		printOutline(out, tm, h.Children, depth+1)
+	}
+}
+
+func init() {
+	outlineCmd.Flags().BoolVar(&outlineJSON, "json", false, "Print the outlines as a JSON array")
+	outlineCmd.Flags().StringVar(&outlineColor, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.AddCommand(outlineCmd)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/slides"
+	"github.com/codehakase/md/internal/theme"
+	"github.com/codehakase/md/internal/viewer"
+)
+
+var (
+	presentSplit     string
+	presentReveal    bool
+	presentNotes     string
+	presentColorMode string
+)
+
+var presentCmd = &cobra.Command{
+	Use:   "present FILE",
+	Short: "Present a markdown file as slides in the terminal",
+	Long: `Show a markdown file as a slide show, one slide at a time, centered and
+wrapped to the size of the terminal.
+
+Slides are split on thematic breaks (---), or on level 1 and 2 headings when the
+document has none; --split chooses one explicitly. HTML comments on a slide are
+its speaker notes: press s to show them under the slide, or use --notes to write
+them to another terminal as the slides change. --reveal shows the items of
+lists one at a time.
+
+Keys: space, enter, l or right arrow go forward and h, backspace or left arrow go
+back; g and G go to the first and last slide, a number followed by enter goes
+to that slide, and q quits.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		split, err := slides.ParseSplit(presentSplit)
+		if err != nil {
+			return err
+		}
+		mode, err := theme.ParseColorMode(presentColorMode)
+		if err != nil {
+			return err
+		}
+		filename := args[0]
+		source, err := os.ReadFile(filename)
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", filename, err)
+		}
+
+		dir := filepath.Dir(filename)
+		cmd.SilenceUsage = true
+		cfg, err := loadConfig(cmd, dir)
+		if err != nil {
+			return err
+		}
+		themeManager, options, codeHighlighter, err := renderSetup(cfg, mode)
+		if err != nil {
+			return err
+		}
+		options.BaseDir = dir
+		options.EmbedRoot = projectRoot(dir)
+
+		deck := slides.Split(renderer.NewWithOptions(themeManager, options), source, split)
+		// Slides are rendered at the width of the terminal, which can change
+		// during the presentation
+		renderers := map[int]*renderer.Renderer{}
+		render := func(markdown string, width int) (string, error) {
+			r, ok := renderers[width]
+			if !ok {
+				o := options
+				o.Width = width
+				r = renderer.NewWithOptions(themeManager, o)
+				renderers[width] = r
+			}
+			return r.RenderContent([]byte(markdown), codeHighlighter)
+		}
+
+		presentOptions := viewer.PresentOptions{Reveal: presentReveal}
+		if presentNotes != "" {
+			notes, err := os.OpenFile(presentNotes, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+			if err != nil {
+				return fmt.Errorf("failed to open notes output: %w", err)
+			}
+			defer notes.Close()
+			presentOptions.Notes = notes
+		}
+		return viewer.Present(deck, render, presentOptions)
+	},
+}
+
+func init() {
+	flags := presentCmd.Flags()
+	flags.StringVar(&presentSplit, "split", slides.SplitAuto, "Where slides start: auto, rule (after each ---) or heading (at each h1 and h2)")
+	flags.BoolVar(&presentReveal, "reveal", false, "Reveal the items of lists one at a time")
+	flags.StringVar(&presentNotes, "notes", "", "Write speaker notes to this file or terminal, such as /dev/pts/2")
+	flags.StringVar(&presentColorMode, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.AddCommand(presentCmd)
+}
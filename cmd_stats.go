@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/stats"
+)
+
+var (
+	statsJSON bool
+	statsWPM  int
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [file-or-directory...]",
+	Short: "Show word counts, reading time and readability of markdown files",
+	Long: `Show the words, characters (not counting spaces) and sentences of markdown
+files, excluding code, with an estimated reading time, the number of headings,
+links, images and code blocks per language, and Flesch reading ease and
+Flesch-Kincaid grade level scores. Each section, from a heading to the next
+heading, is also reported on its own.`,
+	ValidArgsFunction: completeFileArg,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			args = []string{"."}
+		}
+		files, err := markdownFiles(args)
+		if err != nil {
+			return err
+		}
+		parser, err := newParser(filepath.Dir(args[0]))
+		if err != nil {
+			return err
+		}
+
+		reports := []*stats.Report{}
+		for _, file := range files {
+			source, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read file %s: %w", file, err)
+			}
+			report := stats.Analyze(parser, source, statsWPM)
+			report.File = file
+			reports = append(reports, report)
+		}
+
+		out := cmd.OutOrStdout()
+		if statsJSON {
+			encoder := json.NewEncoder(out)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(reports)
+		}
+		for i, report := range reports {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			if err := printStats(out, report); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// printStats writes a report as a summary followed by a table of sections
+func printStats(out io.Writer, report *stats.Report) error {
+	fmt.Fprintln(out, renderer.SanitizeText(report.File))
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	rows := [][2]string{
+		{"Words", fmt.Sprint(report.Words)},
+		{"Characters", fmt.Sprint(report.Characters)},
+		{"Sentences", fmt.Sprint(report.Sentences)},
+		{"Reading time", formatMinutes(report.ReadingMinutes)},
+		{"Headings", fmt.Sprint(report.Headings)},
+		{"Links", fmt.Sprint(report.Links)},
+		{"Images", fmt.Sprint(report.Images)},
+		{"Code blocks", formatCodeBlocks(report.CodeBlocks)},
+		{"Reading ease", fmt.Sprintf("%.1f", report.FleschReadingEase)},
+		{"Grade level", fmt.Sprintf("%.1f", report.FleschKincaidGrade)},
+	}
+	for _, row := range rows {
+		fmt.Fprintf(tw, "  %s\t%s\n", row[0], row[1])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Sections) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	tw = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  LINE\tSECTION\tWORDS\tSENTENCES\tREADING\tGRADE")
+	for _, section := range report.Sections {
+		title := renderer.SanitizeText(section.Title)
+		if section.Level == 0 {
+			title = "(before the first heading)"
+		}
+		indent := strings.Repeat("  ", max(section.Level-1, 0))
+		fmt.Fprintf(tw, "  %d\t%s%s\t%d\t%d\t%s\t%.1f\n",
+			section.Line, indent, title, section.Words, section.Sentences,
+			formatMinutes(section.ReadingMinutes), section.FleschKincaidGrade)
+	}
+	return tw.Flush()
+}
+
+// formatMinutes rounds a reading time up to whole minutes
+func formatMinutes(minutes float64) string {
+	if minutes == 0 {
+		return "0 min"
+	}
+	if minutes < 1 {
+		return "<1 min"
+	}
+	return fmt.Sprintf("%d min", int(math.Ceil(minutes)))
+}
+
+// formatCodeBlocks formats code block counts as "5 (bash 2, go 3)"
+func formatCodeBlocks(counts map[string]int) string {
+	total := 0
+	languages := make([]string, 0, len(counts))
+	for language, count := range counts {
+		total += count
+		languages = append(languages, language)
+	}
+	if total == 0 {
+		return "0"
+	}
+	sort.Strings(languages)
+
+	parts := make([]string, len(languages))
+	for i, language := range languages {
+		parts[i] = fmt.Sprintf("%s %d", renderer.SanitizeText(language), counts[language])
+	}
+	return fmt.Sprintf("%d (%s)", total, strings.Join(parts, ", "))
+}
+
+func init() {
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Print the statistics as JSON")
+	statsCmd.Flags().IntVar(&statsWPM, "wpm", stats.DefaultWordsPerMinute, "Reading speed in words per minute for reading times")
+	rootCmd.AddCommand(statsCmd)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/config"
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+// markdownExtensions are the file extensions offered when completing the file argument
+var markdownExtensions = []string{"md", "markdown", "mdown", "mkd"}
+
+// completeFileArg completes markdown files, and the heading anchors of a
+// file once the argument contains a '#'
+func completeFileArg(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	i := strings.LastIndex(toComplete, "#")
+	if i <= 0 {
+		return markdownExtensions, cobra.ShellCompDirectiveFilterFileExt
+	}
+
+	filename, prefix := toComplete[:i], toComplete[i+1:]
+	source, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// A fixed background keeps completion from querying the terminal
+	mdRenderer := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+
+	var completions []string
+	for _, heading := range mdRenderer.Headings(source) {
+		if strings.HasPrefix(heading.Anchor, prefix) {
+			completions = append(completions, filename+"#"+heading.Anchor+"\t"+heading.Title)
+		}
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeValues returns a completion function offering a fixed list of values
+func completeValues(values func() []string) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return values(), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeThemes offers the built-in and user themes, and theme files
+func completeThemes(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.ContainsRune(toComplete, os.PathSeparator) {
+		return []string{"yaml", "yml", "toml", "json"}, cobra.ShellCompDirectiveFilterFileExt
+	}
+	return append([]string{"auto"}, theme.AvailableThemes()...), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeExtensions completes the last entry of a comma separated list of extensions
+func completeExtensions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	prefix := toComplete[:strings.LastIndex(toComplete, ",")+1]
+
+	var completions []string
+	for _, name := range renderer.Extensions() {
+		completions = append(completions, prefix+name)
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// registerCompletions adds completion functions for the flags of rootCmd and
+// its subcommands. It is called from main, once every command has its flags.
+func registerCompletions() {
+	completions := map[string]func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective){
+		config.KeyTheme:      completeThemes,
+		config.KeyCodeTheme:  completeValues(theme.ChromaThemes),
+		config.KeyLinkMode:   completeValues(func() []string { return []string{"inline", "footnote", "hidden"} }),
+		config.KeyExtensions: completeExtensions,
+		config.KeyInlineCode: completeValues(func() []string { return []string{"plain", "auto"} }),
+		"color":              completeValues(func() []string { return []string{"auto", "always", "never"} }),
+	}
+	for name, fn := range completions {
+		if err := rootCmd.RegisterFlagCompletionFunc(name, fn); err != nil {
+			panic(err)
+		}
+	}
+
+	// Subcommands that print their own output take a --color flag too
+	for _, cmd := range []*cobra.Command{grepCmd, outlineCmd, astCmd, diffCmd, presentCmd} {
+		if err := cmd.RegisterFlagCompletionFunc("color", completions["color"]); err != nil {
+			panic(err)
+		}
+	}
+	scopes := completeValues(func() []string { return []string{"text", "code", "headings", "links"} })
+	if err := grepCmd.RegisterFlagCompletionFunc("in", scopes); err != nil {
+		panic(err)
+	}
+
+	splits := completeValues(func() []string { return []string{"auto", "rule", "heading"} })
+	if err := presentCmd.RegisterFlagCompletionFunc("split", splits); err != nil {
+		panic(err)
+	}
+
+	fmtCompletions := map[string]func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective){
+		"prose-wrap":    completeValues(func() []string { return []string{"preserve", "always", "never"} }),
+		"heading-style": completeValues(func() []string { return []string{"atx", "setext"} }),
+		"bullet":        completeValues(func() []string { return []string{"-", "*", "+"} }),
+		"numbering":     completeValues(func() []string { return []string{"ascending", "one"} }),
+	}
+	for name, fn := range fmtCompletions {
+		if err := fmtCmd.RegisterFlagCompletionFunc(name, fn); err != nil {
+			panic(err)
+		}
+	}
+}
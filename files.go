@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/codehakase/md/internal/config"
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+// markdownFiles expands the given files and directories into the markdown
+// files they contain, in walk order. Hidden directories are skipped; files
+// named explicitly are kept whatever their extension.
+func markdownFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		err = filepath.WalkDir(path, func(name string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if name != path && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isMarkdownFile(name) {
+				files = append(files, name)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// isMarkdownFile reports whether name has one of the markdown extensions
+func isMarkdownFile(name string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), ".")
+	for _, candidate := range markdownExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// newParser returns a renderer configured with the markdown extensions of
+// the settings for dir, for commands that parse documents without
+// displaying them
+func newParser(dir string) (*renderer.Renderer, error) {
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := renderer.ValidateExtensions(cfg.Extensions); err != nil {
+		return nil, settingError(cfg, config.KeyExtensions, err)
+	}
+	return renderer.NewWithOptions(theme.NewWithBackground(theme.BackgroundDark), renderer.Options{
+		Extensions: cfg.Extensions,
+	}), nil
+}
+
+// newTheme returns the theme of the settings for dir, for commands that
+// print their own output instead of rendering a document
+func newTheme(dir, colorMode string) (*theme.ThemeManager, error) {
+	mode, err := theme.ParseColorMode(colorMode)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	themeManager := theme.New()
+	themeManager.SetColorMode(mode)
+	if err := themeManager.SetTheme(cfg.Theme); err != nil {
+		return nil, settingError(cfg, config.KeyTheme, err)
+	}
+	return themeManager, nil
+}
+
+// projectRoot returns the root of the project a document in dir belongs to:
+// the nearest git repository, or else the directory of the nearest project
+// config file, or dir itself when there is neither
+func projectRoot(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	for d := abs; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	if path := config.FindProjectFile(abs); path != "" {
+		return filepath.Dir(path)
+	}
+	return abs
+}
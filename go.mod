@@ -3,13 +3,17 @@ module github.com/codehakase/md
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/alecthomas/chroma v0.10.0
+	github.com/dlclark/regexp2 v1.10.0
 	github.com/spf13/cobra v1.9.1
 	github.com/yuin/goldmark v1.6.0
+	golang.org/x/sys v0.15.0
+	golang.org/x/term v0.15.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/dlclark/regexp2 v1.10.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.6 // indirect
 )
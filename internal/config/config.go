@@ -0,0 +1,562 @@
+// Package config loads md's persistent settings from the user configuration
+// file, a per-project file and the environment, and records where each
+// effective value came from.
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/codehakase/md/internal/xdg"
+)
+
+// ProjectFile is the name of the per-project configuration file
+const ProjectFile = ".md.toml"
+
+// Setting names, shared by the configuration files, the MD_* environment
+// variables and the command line flags
+const (
+	KeyTheme      = "theme"
+	KeyCodeTheme  = "code-theme"
+	KeyWidth      = "width"
+	KeyPager      = "pager"
+	KeyLinkMode   = "link-mode"
+	KeyHyperlinks = "hyperlinks"
+	KeyExtensions = "extensions"
+	KeyAliases    = "aliases"
+
+	KeyLineNumbers    = "line-numbers"
+	KeyCodeFrame      = "code-frame"
+	KeyCodeBackground = "code-background"
+	KeyInlineCode     = "inline-code"
+
+	KeyMaxInputSize     = "max-input-size"
+	KeyMaxNesting       = "max-nesting"
+	KeyMaxTableRows     = "max-table-rows"
+	KeyMaxTableColumns  = "max-table-columns"
+	KeyMaxCodeLines     = "max-code-lines"
+	KeyHighlightTimeout = "highlight-timeout"
+)
+
+// Keys lists every setting in display order
+var Keys = []string{
+	KeyTheme, KeyCodeTheme, KeyWidth, KeyPager,
+	KeyLinkMode, KeyHyperlinks, KeyExtensions, KeyAliases,
+	KeyLineNumbers, KeyCodeFrame, KeyCodeBackground, KeyInlineCode,
+	KeyMaxInputSize, KeyMaxNesting, KeyMaxTableRows, KeyMaxTableColumns,
+	KeyMaxCodeLines, KeyHighlightTimeout,
+}
+
+// Source is the kind of place a setting was taken from
+type Source int
+
+const (
+	// SourceDefault is md's built-in default
+	SourceDefault Source = iota
+	// SourceUser is the user configuration file
+	SourceUser
+	// SourceProject is a .md.toml file next to or above the document
+	SourceProject
+	// SourceEnv is an MD_* environment variable
+	SourceEnv
+	// SourceFlag is a command line flag
+	SourceFlag
+)
+
+// String returns a string representation of the source
+func (s Source) String() string {
+	switch s {
+	case SourceUser:
+		return "user config"
+	case SourceProject:
+		return "project config"
+	case SourceEnv:
+		return "environment"
+	case SourceFlag:
+		return "flag"
+	default:
+		return "default"
+	}
+}
+
+// Origin records where a setting's value came from
+type Origin struct {
+	Source Source
+	// Detail is the file path, environment variable or flag name
+	Detail string
+}
+
+// String returns a string representation of the origin
+func (o Origin) String() string {
+	if o.Detail == "" {
+		return o.Source.String()
+	}
+	return fmt.Sprintf("%s (%s)", o.Source, o.Detail)
+}
+
+// Config holds the effective settings
+type Config struct {
+	Theme      string
+	CodeTheme  string
+	Width      int
+	Pager      string
+	LinkMode   string
+	Hyperlinks bool
+	Extensions []string
+	// Aliases maps code block languages to the language to highlight them as
+	Aliases map[string]string
+
+	// Code block decorations, which fenced blocks can override individually
+	LineNumbers    bool
+	CodeFrame      bool
+	CodeBackground bool
+	// InlineCode is how inline code without a {:lang} hint is highlighted:
+	// plain, auto or a language name
+	InlineCode string
+
+	// Resource limits for rendering untrusted documents; 0 is unlimited.
+	// Project files can only make them stricter, so a limit can't be lifted
+	// by a file that comes with the document.
+	MaxInputSize     int
+	MaxNesting       int
+	MaxTableRows     int
+	MaxTableColumns  int
+	MaxCodeLines     int
+	HighlightTimeout time.Duration
+
+	origins map[string]Origin
+}
+
+// Default returns the built-in settings
+func Default() *Config {
+	return &Config{
+		Theme:      "auto",
+		LinkMode:   "inline",
+		InlineCode: "plain",
+		Extensions: []string{"gfm"},
+		Aliases:    map[string]string{},
+
+		MaxInputSize:     10 << 20,
+		MaxNesting:       100,
+		MaxTableRows:     10000,
+		MaxTableColumns:  100,
+		MaxCodeLines:     10000,
+		HighlightTimeout: 2 * time.Second,
+
+		origins: map[string]Origin{},
+	}
+}
+
+// UserPath returns the path of the user configuration file
+func UserPath() string {
+	return xdg.ConfigPath("config.toml")
+}
+
+// Load returns the settings for a document in dir: the defaults overridden
+// by the user configuration file, then the nearest project file, then the
+// MD_* environment variables. Missing files are skipped.
+func Load(dir string) (*Config, error) {
+	cfg := Default()
+
+	if err := cfg.loadIfExists(UserPath(), SourceUser); err != nil {
+		return nil, err
+	}
+	if path := FindProjectFile(dir); path != "" {
+		if err := cfg.LoadFile(path, SourceProject); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// FindProjectFile returns the first ProjectFile found in dir or one of its
+// parents, or "" if there is none
+func FindProjectFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		path := filepath.Join(dir, ProjectFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) loadIfExists(path string, source Source) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	return c.LoadFile(path, source)
+}
+
+// LoadFile applies the settings in a TOML configuration file
+func (c *Config) LoadFile(path string, source Source) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	raw := map[string]interface{}{}
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return fmt.Errorf("config %s: invalid toml: %w", path, err)
+	}
+
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	origin := Origin{Source: source, Detail: path}
+	for _, key := range keys {
+		if err := c.setValue(key, raw[key], origin); err != nil {
+			return fmt.Errorf("config %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// EnvName returns the environment variable for a setting, e.g. MD_CODE_THEME
+func EnvName(key string) string {
+	return "MD_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// LoadEnv applies the MD_* environment variables that are set
+func (c *Config) LoadEnv(lookup func(string) (string, bool)) error {
+	for _, key := range Keys {
+		name := EnvName(key)
+		value, ok := lookup(name)
+		if !ok || value == "" {
+			continue
+		}
+		if err := c.Set(key, value, Origin{Source: SourceEnv, Detail: name}); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Set parses a setting given as a string, as from a flag or the environment
+func (c *Config) Set(key, value string, origin Origin) error {
+	switch key {
+	case KeyWidth, KeyMaxNesting, KeyMaxTableRows, KeyMaxTableColumns, KeyMaxCodeLines:
+		width, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected a number, got %q", key, value)
+		}
+		return c.setValue(key, int64(width), origin)
+	case KeyHyperlinks, KeyLineNumbers, KeyCodeFrame, KeyCodeBackground:
+		enabled, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: expected true or false, got %q", key, value)
+		}
+		return c.setValue(key, enabled, origin)
+	default:
+		return c.setValue(key, value, origin)
+	}
+}
+
+// setValue validates and stores a decoded setting
+func (c *Config) setValue(key string, value interface{}, origin Origin) error {
+	switch key {
+	case KeyTheme, KeyCodeTheme, KeyPager, KeyLinkMode, KeyInlineCode:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a string, got %v", key, value)
+		}
+		switch key {
+		case KeyTheme:
+			c.Theme = s
+		case KeyCodeTheme:
+			c.CodeTheme = s
+		case KeyPager:
+			// md runs the pager, so a document's project can't choose it
+			if origin.Source == SourceProject {
+				return fmt.Errorf("%s: can't be set in a project %s file, only in the user config, MD_PAGER or --pager", key, ProjectFile)
+			}
+			c.Pager = s
+		case KeyLinkMode:
+			c.LinkMode = s
+		case KeyInlineCode:
+			c.InlineCode = s
+		}
+	case KeyWidth:
+		width, ok := value.(int64)
+		if !ok || width < 0 {
+			return fmt.Errorf("%s: expected a non-negative number, got %v", key, value)
+		}
+		c.Width = int(width)
+	case KeyMaxNesting, KeyMaxTableRows, KeyMaxTableColumns, KeyMaxCodeLines:
+		limit, ok := value.(int64)
+		if !ok || limit < 0 {
+			return fmt.Errorf("%s: expected a non-negative number, got %v", key, value)
+		}
+		if !c.setLimit(c.intSetting(key), int(limit), origin) {
+			return nil
+		}
+	case KeyMaxInputSize:
+		size, err := parseSize(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		if !c.setLimit(&c.MaxInputSize, size, origin) {
+			return nil
+		}
+	case KeyHighlightTimeout:
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected a duration such as \"2s\", got %v", key, value)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("%s: expected a duration such as \"2s\", got %q", key, s)
+		}
+		if origin.Source == SourceProject && !stricter(int64(c.HighlightTimeout), int64(timeout)) {
+			return nil
+		}
+		c.HighlightTimeout = timeout
+	case KeyHyperlinks, KeyLineNumbers, KeyCodeFrame, KeyCodeBackground:
+		enabled, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("%s: expected true or false, got %v", key, value)
+		}
+		*c.boolSetting(key) = enabled
+	case KeyExtensions:
+		names, err := parseList(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		c.Extensions = names
+	case KeyAliases:
+		aliases, err := parseAliases(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		// Aliases from later sources are added to earlier ones rather than replacing them
+		for alias, language := range aliases {
+			c.Aliases[alias] = language
+		}
+	default:
+		return fmt.Errorf("%s: unknown setting (want %s)", key, strings.Join(Keys, ", "))
+	}
+
+	c.origins[key] = origin
+	return nil
+}
+
+// boolSetting returns the field holding a boolean setting
+func (c *Config) boolSetting(key string) *bool {
+	switch key {
+	case KeyLineNumbers:
+		return &c.LineNumbers
+	case KeyCodeFrame:
+		return &c.CodeFrame
+	case KeyCodeBackground:
+		return &c.CodeBackground
+	default:
+		return &c.Hyperlinks
+	}
+}
+
+// intSetting returns the field holding an integer limit
+func (c *Config) intSetting(key string) *int {
+	switch key {
+	case KeyMaxNesting:
+		return &c.MaxNesting
+	case KeyMaxTableRows:
+		return &c.MaxTableRows
+	case KeyMaxTableColumns:
+		return &c.MaxTableColumns
+	default:
+		return &c.MaxCodeLines
+	}
+}
+
+// setLimit stores a resource limit and reports whether it was stored.
+// Limits from project files are only stored when they are stricter.
+func (c *Config) setLimit(field *int, limit int, origin Origin) bool {
+	if origin.Source == SourceProject && !stricter(int64(*field), int64(limit)) {
+		return false
+	}
+	*field = limit
+	return true
+}
+
+// stricter reports whether limit is stricter than current, where 0 is no
+// limit at all
+func stricter(current, limit int64) bool {
+	return limit > 0 && (current == 0 || limit < current)
+}
+
+// sizeUnits are the suffixes of sizes, in binary multiples
+var sizeUnits = []struct {
+	suffix string
+	size   int
+}{
+	{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+	{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+}
+
+// parseSize accepts a number of bytes or a string such as "512KB" or "10MB"
+func parseSize(value interface{}) (int, error) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("expected a non-negative size, got %d", v)
+		}
+		if v > math.MaxInt {
+			return 0, fmt.Errorf("size %d is too large", v)
+		}
+		return int(v), nil
+	case string:
+		text := strings.ToUpper(strings.TrimSpace(v))
+		unit := 1
+		for _, u := range sizeUnits {
+			if strings.HasSuffix(text, u.suffix) {
+				text, unit = strings.TrimSpace(strings.TrimSuffix(text, u.suffix)), u.size
+				break
+			}
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("expected a size such as \"10MB\", got %q", v)
+		}
+		// A wrapped negative size would turn the limit off
+		if n > math.MaxInt/unit {
+			return 0, fmt.Errorf("size %q is too large", v)
+		}
+		return n * unit, nil
+	}
+	return 0, fmt.Errorf("expected a size such as \"10MB\", got %v", value)
+}
+
+// formatSize formats a size in bytes in the largest unit that divides it
+func formatSize(size int) string {
+	for _, u := range sizeUnits[:3] {
+		if size > 0 && size%u.size == 0 {
+			return fmt.Sprintf("%d%s", size/u.size, u.suffix)
+		}
+	}
+	return strconv.Itoa(size)
+}
+
+// parseList accepts an array of strings or a comma separated string
+func parseList(value interface{}) ([]string, error) {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a list of strings, got %v", value)
+			}
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("expected a list of strings, got %v", value)
+	}
+
+	names := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			names = append(names, item)
+		}
+	}
+	return names, nil
+}
+
+// parseAliases accepts a table of alias = "language" entries or a comma
+// separated string of alias=language pairs
+func parseAliases(value interface{}) (map[string]string, error) {
+	aliases := map[string]string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for alias, language := range v {
+			s, ok := language.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected a language name, got %v", alias, language)
+			}
+			aliases[alias] = s
+		}
+	case string:
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			alias, language, ok := strings.Cut(pair, "=")
+			if !ok {
+				return nil, fmt.Errorf("expected alias=language, got %q", pair)
+			}
+			aliases[strings.TrimSpace(alias)] = strings.TrimSpace(language)
+		}
+	default:
+		return nil, fmt.Errorf("expected a table of alias = \"language\" entries, got %v", value)
+	}
+	return aliases, nil
+}
+
+// Origin returns where the effective value of a setting came from
+func (c *Config) Origin(key string) Origin {
+	if origin, ok := c.origins[key]; ok {
+		return origin
+	}
+	return Origin{Source: SourceDefault}
+}
+
+// Value returns the effective value of a setting formatted for display
+func (c *Config) Value(key string) string {
+	switch key {
+	case KeyTheme:
+		return c.Theme
+	case KeyCodeTheme:
+		return c.CodeTheme
+	case KeyWidth:
+		return strconv.Itoa(c.Width)
+	case KeyPager:
+		return c.Pager
+	case KeyLinkMode:
+		return c.LinkMode
+	case KeyInlineCode:
+		return c.InlineCode
+	case KeyHyperlinks, KeyLineNumbers, KeyCodeFrame, KeyCodeBackground:
+		return strconv.FormatBool(*c.boolSetting(key))
+	case KeyMaxNesting, KeyMaxTableRows, KeyMaxTableColumns, KeyMaxCodeLines:
+		return strconv.Itoa(*c.intSetting(key))
+	case KeyMaxInputSize:
+		return formatSize(c.MaxInputSize)
+	case KeyHighlightTimeout:
+		return c.HighlightTimeout.String()
+	case KeyExtensions:
+		return strings.Join(c.Extensions, ",")
+	case KeyAliases:
+		pairs := make([]string, 0, len(c.Aliases))
+		for alias, language := range c.Aliases {
+			pairs = append(pairs, alias+"="+language)
+		}
+		sort.Strings(pairs)
+		return strings.Join(pairs, ",")
+	default:
+		return ""
+	}
+}
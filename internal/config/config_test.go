@@ -0,0 +1,286 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	root := t.TempDir()
+	configHome := filepath.Join(root, "config")
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	for _, key := range Keys {
+		t.Setenv(EnvName(key), "")
+	}
+
+	writeFile(t, filepath.Join(configHome, "md", "config.toml"), `
+theme = "dark"
+width = 100
+link-mode = "footnote"
+`)
+	project := filepath.Join(root, "project")
+	writeFile(t, filepath.Join(project, ProjectFile), `
+width = 72
+line-numbers = true
+extensions = ["gfm", "footnote"]
+`)
+	docs := filepath.Join(project, "docs", "guide")
+	if err := os.MkdirAll(docs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MD_LINK_MODE", "hidden")
+
+	cfg, err := Load(docs)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if err := cfg.Set(KeyHyperlinks, "true", Origin{Source: SourceFlag, Detail: "--hyperlinks"}); err != nil {
+		t.Fatalf("Set() returned error: %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+	}{
+		{KeyTheme, "dark", SourceUser},
+		{KeyCodeTheme, "", SourceDefault},
+		{KeyWidth, "72", SourceProject},
+		{KeyLinkMode, "hidden", SourceEnv},
+		{KeyHyperlinks, "true", SourceFlag},
+		{KeyExtensions, "gfm,footnote", SourceProject},
+		{KeyLineNumbers, "true", SourceProject},
+		{KeyCodeFrame, "false", SourceDefault},
+	}
+	for _, tt := range tests {
+		if got := cfg.Value(tt.key); got != tt.value {
+			t.Errorf("Value(%q) = %q, want %q", tt.key, got, tt.value)
+		}
+		if got := cfg.Origin(tt.key).Source; got != tt.source {
+			t.Errorf("Origin(%q) = %v, want %v", tt.key, got, tt.source)
+		}
+	}
+	if got := cfg.Origin(KeyWidth).Detail; got != filepath.Join(project, ProjectFile) {
+		t.Errorf("Origin(width).Detail = %q, want the project file", got)
+	}
+}
+
+func TestFindProjectFile(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, ProjectFile), "")
+	nested := filepath.Join(root, "a", "b")
+	writeFile(t, filepath.Join(nested, "doc.md"), "")
+
+	if got := FindProjectFile(nested); got != filepath.Join(root, ProjectFile) {
+		t.Errorf("FindProjectFile() = %q, want the file in the parent", got)
+	}
+
+	writeFile(t, filepath.Join(root, "a", ProjectFile), "")
+	if got := FindProjectFile(nested); got != filepath.Join(root, "a", ProjectFile) {
+		t.Errorf("FindProjectFile() = %q, want the nearest file", got)
+	}
+}
+
+func TestLoadFileValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"unknown key", "colour = \"red\"\n", "colour: unknown setting"},
+		{"width type", "width = \"wide\"\n", "width: expected a non-negative number"},
+		{"negative width", "width = -1\n", "width: expected a non-negative number"},
+		{"hyperlinks type", "hyperlinks = \"yes\"\n", "hyperlinks: expected true or false"},
+		{"code-frame type", "code-frame = 1\n", "code-frame: expected true or false"},
+		{"extensions type", "extensions = [1, 2]\n", "extensions: expected a list of strings"},
+		{"negative limit", "max-nesting = -1\n", "max-nesting: expected a non-negative number"},
+		{"size unit", "max-input-size = \"10 parsecs\"\n", "max-input-size: expected a size"},
+		{"size overflow", "max-input-size = \"9007199254740992KB\"\n", "max-input-size: size \"9007199254740992KB\" is too large"},
+		{"timeout type", "highlight-timeout = 2\n", "highlight-timeout: expected a duration"},
+		{"invalid toml", "theme = \n", "invalid toml"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "config.toml")
+			writeFile(t, path, tt.data)
+
+			err := Default().LoadFile(path, SourceUser)
+			if err == nil {
+				t.Fatalf("LoadFile() should fail with %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) || !strings.Contains(err.Error(), path) {
+				t.Errorf("LoadFile() error = %q, want it to name %s and contain %q", err, path, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadProjectPager(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ProjectFile)
+	writeFile(t, path, "pager = \"sh -c 'touch pwned; cat'\"\n")
+
+	cfg := Default()
+	err := cfg.LoadFile(path, SourceProject)
+	if err == nil || !strings.Contains(err.Error(), "pager: can't be set in a project") {
+		t.Errorf("LoadFile() error = %v, want the pager to be rejected", err)
+	}
+	if cfg.Pager != "" {
+		t.Errorf("Pager = %q after a rejected project setting", cfg.Pager)
+	}
+
+	// The user config may still choose it
+	if err := cfg.LoadFile(path, SourceUser); err != nil {
+		t.Fatalf("LoadFile() of a user config returned error: %v", err)
+	}
+	if cfg.Pager == "" {
+		t.Error("Pager from the user config was not set")
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	t.Parallel()
+
+	env := map[string]string{
+		"MD_EXTENSIONS": "gfm, typographer",
+		"MD_WIDTH":      "80",
+		"MD_THEME":      "",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cfg := Default()
+	if err := cfg.LoadEnv(lookup); err != nil {
+		t.Fatalf("LoadEnv() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Extensions, []string{"gfm", "typographer"}) {
+		t.Errorf("Extensions = %v, want [gfm typographer]", cfg.Extensions)
+	}
+	if cfg.Width != 80 {
+		t.Errorf("Width = %d, want 80", cfg.Width)
+	}
+	if cfg.Theme != "auto" || cfg.Origin(KeyTheme).Source != SourceDefault {
+		t.Errorf("an empty MD_THEME should keep the default, got %q from %v", cfg.Theme, cfg.Origin(KeyTheme))
+	}
+
+	env["MD_WIDTH"] = "wide"
+	if err := Default().LoadEnv(lookup); err == nil || !strings.Contains(err.Error(), "MD_WIDTH") {
+		t.Errorf("LoadEnv() error = %v, want it to name MD_WIDTH", err)
+	}
+}
+
+func TestAliasesMerge(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	user := filepath.Join(dir, "config.toml")
+	writeFile(t, user, "[aliases]\ngolang-repl = \"go\"\nstarlark = \"python\"\n")
+	project := filepath.Join(dir, ProjectFile)
+	writeFile(t, project, "[aliases]\nstarlark = \"bazel\"\n")
+
+	cfg := Default()
+	if err := cfg.LoadFile(user, SourceUser); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadFile(project, SourceProject); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Set(KeyAliases, "tpl=gotmpl, jsx = javascript", Origin{Source: SourceEnv}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "golang-repl=go,jsx=javascript,starlark=bazel,tpl=gotmpl"
+	if got := cfg.Value(KeyAliases); got != want {
+		t.Errorf("Value(aliases) = %q, want %q", got, want)
+	}
+
+	if err := cfg.Set(KeyAliases, "broken", Origin{Source: SourceEnv}); err == nil {
+		t.Error("Set() with a malformed alias should fail")
+	}
+}
+
+func TestLimits(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	user := filepath.Join(dir, "config.toml")
+	writeFile(t, user, `
+max-input-size = "1MB"
+max-table-rows = 500
+highlight-timeout = "500ms"
+`)
+	project := filepath.Join(dir, ProjectFile)
+	writeFile(t, project, `
+max-input-size = 0
+max-table-rows = 100
+max-code-lines = 1000000
+highlight-timeout = "1m"
+`)
+
+	cfg := Default()
+	if err := cfg.LoadFile(user, SourceUser); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadFile(project, SourceProject); err != nil {
+		t.Fatal(err)
+	}
+
+	// Project files can tighten limits but not lift them
+	tests := []struct {
+		key    string
+		value  string
+		source Source
+	}{
+		{KeyMaxInputSize, "1MB", SourceUser},
+		{KeyMaxTableRows, "100", SourceProject},
+		{KeyMaxCodeLines, "10000", SourceDefault},
+		{KeyHighlightTimeout, "500ms", SourceUser},
+		{KeyMaxNesting, "100", SourceDefault},
+	}
+	for _, tt := range tests {
+		if got := cfg.Value(tt.key); got != tt.value {
+			t.Errorf("Value(%s) = %q, want %q", tt.key, got, tt.value)
+		}
+		if got := cfg.Origin(tt.key).Source; got != tt.source {
+			t.Errorf("Origin(%s) = %v, want %v", tt.key, got, tt.source)
+		}
+	}
+
+	// Other sources can lift them
+	if err := cfg.Set(KeyMaxInputSize, "0", Origin{Source: SourceEnv}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxInputSize != 0 {
+		t.Errorf("MaxInputSize = %d, want 0 after MD_MAX_INPUT_SIZE=0", cfg.MaxInputSize)
+	}
+	if err := cfg.Set(KeyMaxInputSize, "512kb", Origin{Source: SourceEnv}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.MaxInputSize != 512<<10 {
+		t.Errorf("MaxInputSize = %d, want %d", cfg.MaxInputSize, 512<<10)
+	}
+}
@@ -0,0 +1,189 @@
+// Package diff compares texts line by line or word by word, printing unified
+// diffs, and markdown documents block by block
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of an edit
+type Op int
+
+const (
+	// Equal keeps an element of both sequences
+	Equal Op = iota
+	// Delete removes an element of the old sequence
+	Delete
+	// Insert adds an element of the new sequence
+	Insert
+)
+
+// Edit is one step of turning the old sequence into the new one. OldIndex
+// and NewIndex are the positions of the element in the old and new
+// sequences; the index of the side an edit doesn't touch is where it would
+// be.
+type Edit struct {
+	Op       Op
+	OldIndex int
+	NewIndex int
+}
+
+// Compare returns the shortest edit script turning a sequence of n elements
+// into one of m elements, using equal to compare element i of the first
+// with element j of the second. It uses Myers' algorithm, whose cost grows
+// with the number of differences rather than the length of the sequences.
+func Compare(n, m int, equal func(i, j int) bool) []Edit {
+	limit := n + m
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	// trace holds the furthest reaching x of each diagonal k in -d..d before
+	// step d, for backtracking
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int{}, v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && equal(x, y) {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, n, m)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack follows the trace of Compare back from the end of both
+// sequences to build the edit script
+func backtrack(trace [][]int, n, m int) []Edit {
+	var edits []Edit
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		// trace[d] holds diagonals -d..d, so diagonal k is at index k+d
+		v := func(k int) int { return trace[d][k+d] }
+		k := x - y
+		previousK := k - 1
+		if k == -d || (k != d && v(k-1) < v(k+1)) {
+			previousK = k + 1
+		}
+		previousX := 0
+		if d > 0 {
+			previousX = v(previousK)
+		}
+		previousY := previousX - previousK
+
+		for x > previousX && y > previousY {
+			x--
+			y--
+			edits = append(edits, Edit{Equal, x, y})
+		}
+		if d > 0 {
+			if x == previousX {
+				edits = append(edits, Edit{Insert, x, y - 1})
+			} else {
+				edits = append(edits, Edit{Delete, x - 1, y})
+			}
+		}
+		x, y = previousX, previousY
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// Strings returns the edit script turning a into b
+func Strings(a, b []string) []Edit {
+	return Compare(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+}
+
+// Unified returns a unified diff of the lines of a and b with the given
+// number of lines of context, or "" if they are equal
+func Unified(oldName, newName, a, b string, context int) string {
+	oldLines, newLines := splitLines(a), splitLines(b)
+	edits := Strings(oldLines, newLines)
+
+	var out strings.Builder
+	for _, hunk := range hunks(edits, context) {
+		if out.Len() == 0 {
+			fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+		}
+		first := hunk[0]
+		oldCount, newCount := 0, 0
+		for _, e := range hunk {
+			if e.Op != Insert {
+				oldCount++
+			}
+			if e.Op != Delete {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(first.OldIndex, oldCount), hunkRange(first.NewIndex, newCount))
+		for _, e := range hunk {
+			switch e.Op {
+			case Equal:
+				out.WriteString(" " + oldLines[e.OldIndex] + "\n")
+			case Delete:
+				out.WriteString("-" + oldLines[e.OldIndex] + "\n")
+			case Insert:
+				out.WriteString("+" + newLines[e.NewIndex] + "\n")
+			}
+		}
+	}
+	return out.String()
+}
+
+// hunks groups the changes of an edit script with up to context equal edits
+// around them
+func hunks(edits []Edit, context int) [][]Edit {
+	var groups [][]Edit
+	start, end := -1, -1
+	for i, e := range edits {
+		if e.Op == Equal {
+			continue
+		}
+		from, to := max(i-context, 0), min(i+context+1, len(edits))
+		if start >= 0 && from <= end {
+			end = to
+			continue
+		}
+		if start >= 0 {
+			groups = append(groups, edits[start:end])
+		}
+		start, end = from, to
+	}
+	if start >= 0 {
+		groups = append(groups, edits[start:end])
+	}
+	return groups
+}
+
+// hunkRange formats the 1-based start and length of a hunk side
+func hunkRange(index, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", index)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", index+1)
+	}
+	return fmt.Sprintf("%d,%d", index+1, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		a, b      string
+		wantEdits int
+	}{
+		{"equal", "a b c", "a b c", 0},
+		{"empty old", "", "a b", 2},
+		{"empty new", "a b", "", 2},
+		{"insert", "a c", "a b c", 1},
+		{"delete", "a b c", "a c", 1},
+		{"replace", "a b c", "a x c", 2},
+		{"move", "a b c d", "b c d a", 2},
+		{"interleaved", "a b c a b b a", "c b a b a c", 5},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, b := strings.Fields(tt.a), strings.Fields(tt.b)
+			edits := Strings(a, b)
+
+			// Applying the script to a must give b
+			var old, result []string
+			changes := 0
+			for _, e := range edits {
+				switch e.Op {
+				case Equal:
+					if a[e.OldIndex] != b[e.NewIndex] {
+						t.Fatalf("equal edit pairs %q with %q", a[e.OldIndex], b[e.NewIndex])
+					}
+					old = append(old, a[e.OldIndex])
+					result = append(result, b[e.NewIndex])
+				case Delete:
+					old = append(old, a[e.OldIndex])
+					changes++
+				case Insert:
+					result = append(result, b[e.NewIndex])
+					changes++
+				}
+			}
+			if strings.Join(old, " ") != tt.a || strings.Join(result, " ") != tt.b {
+				t.Errorf("edits produce %q -> %q, want %q -> %q", old, result, tt.a, tt.b)
+			}
+			if changes != tt.wantEdits {
+				t.Errorf("got %d changes, want %d", changes, tt.wantEdits)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	t.Parallel()
+
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\n9\nten\n"
+	want := `--- a.md
++++ b.md
+@@ -2,3 +2,3 @@
+ 2
+-3
++three
+ 4
+@@ -9 +9,2 @@
+ 9
++ten
+`
+	if got := Unified("a.md", "b.md", a, b, 1); got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified("a.md", "b.md", a, a, 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q, want empty", got)
+	}
+}
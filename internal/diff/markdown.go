@@ -0,0 +1,238 @@
+package diff
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/codehakase/md/internal/format"
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// ChangeKind is how a block differs between two documents
+type ChangeKind int
+
+const (
+	// Unchanged blocks are the same in both documents
+	Unchanged ChangeKind = iota
+	// Added blocks are only in the new document
+	Added
+	// Removed blocks are only in the old document
+	Removed
+	// Changed blocks were edited: an old block paired with the new block
+	// that replaced it
+	Changed
+)
+
+// Markers delimit changed words in the markdown of changed blocks. They are
+// private use characters, which survive rendering as ordinary text, so they
+// can be replaced with styles in the rendered output.
+const (
+	DeletedStart  = '\uE000'
+	DeletedEnd    = '\uE001'
+	InsertedStart = '\uE002'
+	InsertedEnd   = '\uE003'
+)
+
+// BlockChange is a top-level block of the old or the new document, or a
+// pair of them
+type BlockChange struct {
+	Kind ChangeKind
+	// Old and New are the markdown of the block in each document, empty for
+	// the document it isn't in. The words that differ between the two
+	// blocks of a Changed prose block are delimited by markers.
+	Old string
+	New string
+	// Merged is the new block of a Changed prose block with the deleted words
+	// in place; it is empty for other blocks, whose changes aren't word by
+	// word
+	Merged string
+}
+
+// wordBlocks are the kinds of blocks compared word by word
+var wordBlocks = map[string]bool{
+	"Paragraph": true, "TextBlock": true, "Heading": true, "List": true,
+	"Blockquote": true, "DefinitionList": true,
+}
+
+// pairingThreshold is the share of words a removed and an added block of the
+// same kind must have in common to be shown as one changed block
+const pairingThreshold = 0.5
+
+// Markdown compares two markdown documents block by block. Both are parsed
+// like r does and normalized, so changes to line breaks or markup style that
+// don't change the rendered document are ignored.
+func Markdown(r *renderer.Renderer, old, new []byte) []BlockChange {
+	options := format.DefaultOptions()
+	options.Wrap = format.WrapNever
+	a, b := format.Blocks(r, old, options), format.Blocks(r, new, options)
+
+	var changes []BlockChange
+	var removed, added []format.Block
+	flush := func() {
+		changes = append(changes, pairBlocks(removed, added)...)
+		removed, added = nil, nil
+	}
+	for _, e := range Compare(len(a), len(b), func(i, j int) bool { return a[i] == b[j] }) {
+		switch e.Op {
+		case Equal:
+			flush()
+			changes = append(changes, BlockChange{Kind: Unchanged, Old: a[e.OldIndex].Text, New: b[e.NewIndex].Text})
+		case Delete:
+			removed = append(removed, a[e.OldIndex])
+		case Insert:
+			added = append(added, b[e.NewIndex])
+		}
+	}
+	flush()
+	return changes
+}
+
+// pairBlocks turns a run of removed and added blocks into changes, pairing
+// each removed block with the first following added block of the same kind
+// that is similar enough
+func pairBlocks(removed, added []format.Block) []BlockChange {
+	var changes []BlockChange
+	next := 0
+	for _, old := range removed {
+		match := -1
+		var words WordChanges
+		for j := next; j < len(added); j++ {
+			if added[j].Kind != old.Kind {
+				continue
+			}
+			if w := Words(old.Text, added[j].Text); w.Similarity >= pairingThreshold {
+				match, words = j, w
+				break
+			}
+		}
+		if match < 0 {
+			changes = append(changes, BlockChange{Kind: Removed, Old: old.Text})
+			continue
+		}
+
+		for ; next < match; next++ {
+			changes = append(changes, BlockChange{Kind: Added, New: added[next].Text})
+		}
+		next++
+		if wordBlocks[old.Kind] {
+			changes = append(changes, BlockChange{Kind: Changed, Old: words.Old, New: words.New, Merged: words.Merged})
+		} else {
+			changes = append(changes, BlockChange{Kind: Changed, Old: old.Text, New: added[match].Text})
+		}
+	}
+	for ; next < len(added); next++ {
+		changes = append(changes, BlockChange{Kind: Added, New: added[next].Text})
+	}
+	return changes
+}
+
+// WordChanges is the word by word comparison of two texts
+type WordChanges struct {
+	// Old and New are the texts with their changed words delimited by markers
+	Old string
+	New string
+	// Merged is the new text with the deleted words in place
+	Merged string
+	// Similarity is the share of words the texts have in common, from 0 to 1
+	Similarity float64
+}
+
+var (
+	wordPattern = regexp.MustCompile(`\s+|\S+`)
+	// blockSyntax matches the words that make a line a heading, list item,
+	// quote or table row; they are never marked, so the line keeps its meaning
+	blockSyntax = regexp.MustCompile(`^(#{1,6}|[-+*]|\d{1,9}[.)]|>+|:|\|.*)$`)
+)
+
+// Words compares two texts word by word
+func Words(old, new string) WordChanges {
+	a, b := wordPattern.FindAllString(old, -1), wordPattern.FindAllString(new, -1)
+	oldText, newText, merged := newMarkedText(), newMarkedText(), newMarkedText()
+
+	common, words := 0, 0
+	for _, e := range Strings(a, b) {
+		switch e.Op {
+		case Equal:
+			token := b[e.NewIndex]
+			oldText.write(a[e.OldIndex], 0, 0)
+			newText.write(token, 0, 0)
+			merged.write(token, 0, 0)
+			if !isSpace(token) {
+				common += 2
+				words += 2
+			}
+		case Delete:
+			token := a[e.OldIndex]
+			oldText.write(token, DeletedStart, DeletedEnd)
+			// The new text's spacing and block syntax take precedence
+			if !isSpace(token) && !(merged.lineStart && blockSyntax.MatchString(token)) {
+				merged.write(token, DeletedStart, DeletedEnd)
+			}
+			if !isSpace(token) {
+				words++
+			}
+		case Insert:
+			token := b[e.NewIndex]
+			newText.write(token, InsertedStart, InsertedEnd)
+			merged.write(token, InsertedStart, InsertedEnd)
+			if !isSpace(token) {
+				words++
+			}
+		}
+	}
+
+	similarity := 1.0
+	if words > 0 {
+		similarity = float64(common) / float64(words)
+	}
+	return WordChanges{
+		Old:        oldText.String(),
+		New:        newText.String(),
+		Merged:     merged.String(),
+		Similarity: similarity,
+	}
+}
+
+// markedText builds a text from words and spacing, marking changed words
+type markedText struct {
+	strings.Builder
+	// afterWord is set when the last token written was a word, not spacing
+	afterWord bool
+	// lineStart is set until the first word of a line is written
+	lineStart bool
+}
+
+func newMarkedText() *markedText {
+	return &markedText{lineStart: true}
+}
+
+// write appends a token, delimited by start and end unless they are zero.
+// Words that end up next to each other, because the spacing between them
+// was deleted, are kept apart with a space.
+func (t *markedText) write(token string, start, end rune) {
+	if isSpace(token) {
+		t.WriteString(token)
+		t.afterWord = false
+		if strings.Contains(token, "\n") {
+			t.lineStart = true
+		}
+		return
+	}
+
+	if t.afterWord {
+		t.WriteByte(' ')
+	}
+	if start != 0 && !(t.lineStart && blockSyntax.MatchString(token)) {
+		t.WriteRune(start)
+		t.WriteString(token)
+		t.WriteRune(end)
+	} else {
+		t.WriteString(token)
+	}
+	t.afterWord = true
+	t.lineStart = false
+}
+
+func isSpace(token string) bool {
+	return strings.TrimSpace(token) == ""
+}
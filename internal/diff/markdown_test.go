@@ -0,0 +1,198 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want []ChangeKind
+	}{
+		{
+			name: "equal",
+			old:  "# Title\n\nText.\n",
+			new:  "# Title\n\nText.\n",
+			want: []ChangeKind{Unchanged, Unchanged},
+		},
+		{
+			name: "rewrapped",
+			old:  "Some words\nwrapped here.\n",
+			new:  "Some words wrapped\nhere.\n",
+			want: []ChangeKind{Unchanged},
+		},
+		{
+			name: "markup style",
+			old:  "Title\n=====\n\n* one\n* two\n",
+			new:  "# Title\n\n- one\n- two\n",
+			want: []ChangeKind{Unchanged, Unchanged},
+		},
+		{
+			name: "added",
+			old:  "First.\n\nLast.\n",
+			new:  "First.\n\nMiddle.\n\nLast.\n",
+			want: []ChangeKind{Unchanged, Added, Unchanged},
+		},
+		{
+			name: "removed",
+			old:  "First.\n\nMiddle.\n\nLast.\n",
+			new:  "First.\n\nLast.\n",
+			want: []ChangeKind{Unchanged, Removed, Unchanged},
+		},
+		{
+			name: "changed",
+			old:  "The quick brown fox jumps.\n",
+			new:  "The quick red fox jumps.\n",
+			want: []ChangeKind{Changed},
+		},
+		{
+			name: "replaced",
+			old:  "Entirely different text.\n",
+			new:  "Nothing in common here.\n",
+			want: []ChangeKind{Removed, Added},
+		},
+		{
+			name: "different kinds",
+			old:  "# The quick fox\n",
+			new:  "The quick fox\n",
+			want: []ChangeKind{Removed, Added},
+		},
+		{
+			name: "added before changed",
+			old:  "One two three four.\n",
+			new:  "New paragraph.\n\nOne two three five.\n",
+			want: []ChangeKind{Added, Changed},
+		},
+	}
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []ChangeKind
+			for _, change := range Markdown(r, []byte(tt.old), []byte(tt.new)) {
+				got = append(got, change.Kind)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Markdown() kinds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkdownCodeChange(t *testing.T) {
+	t.Parallel()
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	changes := Markdown(r, []byte("```go\nx := 1\nfmt.Println(x)\n```\n"), []byte("```go\nx := 2\nfmt.Println(x)\n```\n"))
+	if len(changes) != 1 || changes[0].Kind != Changed {
+		t.Fatalf("Markdown() = %+v, want one changed block", changes)
+	}
+	// Code isn't compared word by word
+	if changes[0].Merged != "" || strings.ContainsRune(changes[0].New, InsertedStart) {
+		t.Errorf("code block has word changes: %+v", changes[0])
+	}
+}
+
+// mark replaces the markers with readable delimiters
+var mark = strings.NewReplacer(
+	string(DeletedStart), "[-", string(DeletedEnd), "-]",
+	string(InsertedStart), "{+", string(InsertedEnd), "+}",
+)
+
+func TestWords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		old        string
+		new        string
+		wantOld    string
+		wantNew    string
+		wantMerged string
+	}{
+		{
+			name:       "replaced word",
+			old:        "The quick brown fox",
+			new:        "The quick red fox",
+			wantOld:    "The quick [-brown-] fox",
+			wantNew:    "The quick {+red+} fox",
+			wantMerged: "The quick [-brown-] {+red+} fox",
+		},
+		{
+			name:       "appended words",
+			old:        "Hello",
+			new:        "Hello there world",
+			wantOld:    "Hello",
+			wantNew:    "Hello {+there+} {+world+}",
+			wantMerged: "Hello {+there+} {+world+}",
+		},
+		{
+			name:       "deleted words",
+			old:        "one two three",
+			new:        "one three",
+			wantOld:    "one [-two-] three",
+			wantNew:    "one three",
+			wantMerged: "one [-two-] three",
+		},
+		{
+			name:       "block syntax",
+			old:        "- one\n- two",
+			new:        "- one\n- three",
+			wantOld:    "- one\n- [-two-]",
+			wantNew:    "- one\n- {+three+}",
+			wantMerged: "- one\n- [-two-] {+three+}",
+		},
+		{
+			name:       "item added",
+			old:        "- one",
+			new:        "- one\n- two",
+			wantOld:    "- one",
+			wantNew:    "- one\n- {+two+}",
+			wantMerged: "- one\n- {+two+}",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := Words(tt.old, tt.new)
+			if old := mark.Replace(got.Old); old != tt.wantOld {
+				t.Errorf("Old = %q, want %q", old, tt.wantOld)
+			}
+			if new := mark.Replace(got.New); new != tt.wantNew {
+				t.Errorf("New = %q, want %q", new, tt.wantNew)
+			}
+			if merged := mark.Replace(got.Merged); merged != tt.wantMerged {
+				t.Errorf("Merged = %q, want %q", merged, tt.wantMerged)
+			}
+		})
+	}
+}
+
+func TestWordsSimilarity(t *testing.T) {
+	t.Parallel()
+
+	if got := Words("a b c d", "a b c d").Similarity; got != 1 {
+		t.Errorf("equal texts similarity = %v, want 1", got)
+	}
+	if got := Words("a b", "c d").Similarity; got != 0 {
+		t.Errorf("different texts similarity = %v, want 0", got)
+	}
+	if got := Words("a b c d", "a b x y").Similarity; got != 0.5 {
+		t.Errorf("half equal texts similarity = %v, want 0.5", got)
+	}
+}
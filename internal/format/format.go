@@ -0,0 +1,763 @@
+// Package format re-emits markdown documents as normalized CommonMark/GFM,
+// parsing them with the same configuration as the renderer
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Wrap modes for prose
+const (
+	// WrapPreserve keeps the line breaks of the source
+	WrapPreserve = "preserve"
+	// WrapAlways wraps prose at the configured width
+	WrapAlways = "always"
+	// WrapNever puts each paragraph on a single line
+	WrapNever = "never"
+)
+
+// Heading styles
+const (
+	// HeadingATX writes headings as "# Title"
+	HeadingATX = "atx"
+	// HeadingSetext underlines level 1 and 2 headings with = and -; deeper
+	// levels have no setext form and stay ATX
+	HeadingSetext = "setext"
+)
+
+// Numbering styles of ordered lists
+const (
+	// NumberingAscending numbers items 1, 2, 3 from the list's start number
+	NumberingAscending = "ascending"
+	// NumberingOne gives every item the list's start number, so inserting an
+	// item doesn't renumber the rest
+	NumberingOne = "one"
+)
+
+// Options control the formatting
+type Options struct {
+	Wrap string
+	// Width is the line width for WrapAlways
+	Width        int
+	HeadingStyle string
+	// BulletMarker is the marker of bullet list items: "-", "*" or "+"
+	BulletMarker string
+	Numbering    string
+}
+
+// DefaultOptions returns the formatting used unless configured otherwise
+func DefaultOptions() Options {
+	return Options{
+		Wrap:         WrapPreserve,
+		Width:        80,
+		HeadingStyle: HeadingATX,
+		BulletMarker: "-",
+		Numbering:    NumberingAscending,
+	}
+}
+
+// Validate checks that the options hold known values
+func (o Options) Validate() error {
+	if err := oneOf("wrap mode", o.Wrap, WrapPreserve, WrapAlways, WrapNever); err != nil {
+		return err
+	}
+	if o.Wrap == WrapAlways && o.Width <= 0 {
+		return fmt.Errorf("invalid width %d: must be positive to wrap", o.Width)
+	}
+	if err := oneOf("heading style", o.HeadingStyle, HeadingATX, HeadingSetext); err != nil {
+		return err
+	}
+	if err := oneOf("bullet marker", o.BulletMarker, "-", "*", "+"); err != nil {
+		return err
+	}
+	return oneOf("numbering", o.Numbering, NumberingAscending, NumberingOne)
+}
+
+func oneOf(name, value string, allowed ...string) error {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid %s %q (want %s)", name, value, strings.Join(allowed, ", "))
+}
+
+// Format parses source like r does and returns it as normalized markdown.
+// YAML front matter is kept as it is. Reference links are written inline,
+// since the parser resolves them.
+func Format(r *renderer.Renderer, source []byte, options Options) []byte {
+	frontMatter, body := splitFrontMatter(source)
+
+	f, doc := newFormatter(r, body, options)
+
+	var out bytes.Buffer
+	out.Write(frontMatter)
+	if lines := f.blocks(doc, options.Width); len(lines) > 0 {
+		if len(frontMatter) > 0 {
+			out.WriteByte('\n')
+		}
+		out.WriteString(strings.Join(lines, "\n"))
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// Block is a formatted top-level block of a document
+type Block struct {
+	// Kind is the goldmark node kind of the block, such as Paragraph
+	Kind string
+	Text string
+}
+
+// Blocks parses source like r does and returns each of its top-level blocks
+// formatted on its own, for comparing documents block by block. Front matter
+// is returned as a block of kind FrontMatter.
+func Blocks(r *renderer.Renderer, source []byte, options Options) []Block {
+	frontMatter, body := splitFrontMatter(source)
+	f, doc := newFormatter(r, body, options)
+
+	var blocks []Block
+	if len(frontMatter) > 0 {
+		blocks = append(blocks, Block{Kind: "FrontMatter", Text: strings.TrimSuffix(string(frontMatter), "\n")})
+	}
+	var previous ast.Node
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		if lines := f.block(child, previous, options.Width); len(lines) > 0 {
+			blocks = append(blocks, Block{Kind: child.Kind().String(), Text: strings.Join(lines, "\n")})
+			previous = child
+		}
+	}
+	return blocks
+}
+
+// newFormatter parses source and prepares a formatter for it
+func newFormatter(r *renderer.Renderer, source []byte, options Options) (*formatter, ast.Node) {
+	f := &formatter{
+		source:    source,
+		options:   options,
+		footnotes: map[int]string{},
+		markers:   map[*ast.List]string{},
+	}
+	doc := r.Parse(source)
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if footnote, ok := node.(*extast.Footnote); ok && entering {
+			f.footnotes[footnote.Index] = string(footnote.Ref)
+		}
+		return ast.WalkContinue, nil
+	})
+	return f, doc
+}
+
+// splitFrontMatter separates a leading YAML front matter block, which the
+// parser would otherwise read as a thematic break and a setext heading
+func splitFrontMatter(source []byte) ([]byte, []byte) {
+	if !bytes.HasPrefix(source, []byte("---\n")) && !bytes.HasPrefix(source, []byte("---\r\n")) {
+		return nil, source
+	}
+	offset := bytes.IndexByte(source, '\n') + 1
+	for offset < len(source) {
+		end := bytes.IndexByte(source[offset:], '\n')
+		if end < 0 {
+			end = len(source) - offset
+		}
+		line := strings.TrimRight(string(source[offset:offset+end]), "\r")
+		next := min(offset+end+1, len(source))
+		if line == "---" || line == "..." {
+			frontMatter := append([]byte{}, source[:next]...)
+			if !bytes.HasSuffix(frontMatter, []byte("\n")) {
+				frontMatter = append(frontMatter, '\n')
+			}
+			return frontMatter, bytes.TrimLeft(source[next:], "\r\n")
+		}
+		offset = next
+	}
+	return nil, source
+}
+
+// hardBreak marks hard line breaks in formatted inline content, which is
+// otherwise plain text with newlines at soft line breaks
+const hardBreak = "\x00"
+
+// typographerValues maps the entities the typographer extension substitutes
+// back to the text they replaced
+var typographerValues = map[string]string{
+	"&ldquo;":  `"`,
+	"&rdquo;":  `"`,
+	"&lsquo;":  "'",
+	"&rsquo;":  "'",
+	"&laquo;":  "<<",
+	"&raquo;":  ">>",
+	"&hellip;": "...",
+	"&ndash;":  "--",
+	"&mdash;":  "---",
+}
+
+// unsafeLineStart matches words that would start a different block, such as
+// a list item or a heading, if wrapping moved them to the start of a line
+var unsafeLineStart = regexp.MustCompile("^(#{1,6}$|[-+*]$|\\d{1,9}[.)]$|>|<|\\||=+$|:?-+:?$|\\*{3,}$|_{3,}$|```|~~~)")
+
+// closingSequence matches text an ATX heading would lose as its closing
+// sequence
+var closingSequence = regexp.MustCompile(`(^|[ \t])#+$`)
+
+// backtickRuns matches the delimiters that open and close code spans
+var backtickRuns = regexp.MustCompile("`+")
+
+type formatter struct {
+	source  []byte
+	options Options
+	// footnotes maps footnote indexes to their labels
+	footnotes map[int]string
+	// markers holds the marker each list was written with
+	markers map[*ast.List]string
+}
+
+// blocks formats the block children of parent for the given line width.
+// Blocks are separated by blank lines, except inside the items of tight
+// lists.
+func (f *formatter) blocks(parent ast.Node, width int) []string {
+	separate := true
+	if item, ok := parent.(*ast.ListItem); ok {
+		if list, ok := item.Parent().(*ast.List); ok && list.IsTight {
+			separate = false
+		}
+	}
+
+	var lines []string
+	var previous ast.Node
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		block := f.block(child, previous, width)
+		if len(block) == 0 {
+			continue
+		}
+		if previous != nil && separate {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+		previous = child
+	}
+	return lines
+}
+
+func (f *formatter) block(node, previous ast.Node, width int) []string {
+	switch n := node.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		return f.prose(f.inlines(n), width)
+	case *ast.Heading:
+		return f.heading(n)
+	case *ast.ThematicBreak:
+		return []string{"---"}
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		return f.codeBlock(n)
+	case *ast.HTMLBlock:
+		return f.htmlBlock(n)
+	case *ast.Blockquote:
+		return prefixLines(f.blocks(n, width-2), "> ", "> ")
+	case *ast.List:
+		return f.list(n, previous, width)
+	case *extast.Table:
+		return f.table(n)
+	case *extast.DefinitionList:
+		return f.definitionList(n, width)
+	case *extast.FootnoteList:
+		return f.footnoteList(n, width)
+	}
+	return f.blocks(node, width)
+}
+
+func (f *formatter) heading(n *ast.Heading) []string {
+	text := strings.TrimSpace(joinLines(f.inlines(n)))
+	if f.options.HeadingStyle == HeadingSetext && n.Level <= 2 && text != "" {
+		underline := "="
+		if n.Level == 2 {
+			underline = "-"
+		}
+		return []string{text, strings.Repeat(underline, max(utf8.RuneCountInString(text), 3))}
+	}
+	marker := strings.Repeat("#", n.Level)
+	if text == "" {
+		return []string{marker}
+	}
+	// A trailing run of # would be read as the closing sequence
+	if closingSequence.MatchString(text) {
+		i := strings.LastIndexFunc(text, func(r rune) bool { return r != '#' }) + 1
+		text = text[:i] + `\` + text[i:]
+	}
+	return []string{marker + " " + text}
+}
+
+// codeBlock writes fenced and indented code blocks as fenced blocks, with a
+// fence longer than any run of fence characters in the code
+func (f *formatter) codeBlock(n ast.Node) []string {
+	info := ""
+	if fenced, ok := n.(*ast.FencedCodeBlock); ok && fenced.Info != nil {
+		info = strings.TrimSpace(string(fenced.Info.Text(f.source)))
+	}
+	fenceChar := "`"
+	// Backtick fences can't have backticks in their info string
+	if strings.Contains(info, "`") {
+		fenceChar = "~"
+	}
+
+	code := blockLines(n, f.source)
+	length := 3
+	for _, line := range code {
+		trimmed := strings.TrimLeft(line, " ")
+		run := len(trimmed) - len(strings.TrimLeft(trimmed, fenceChar))
+		length = max(length, run+1)
+	}
+	fence := strings.Repeat(fenceChar, length)
+
+	lines := []string{fence + info}
+	lines = append(lines, code...)
+	return append(lines, fence)
+}
+
+func (f *formatter) htmlBlock(n *ast.HTMLBlock) []string {
+	lines := blockLines(n, f.source)
+	if n.HasClosure() {
+		lines = append(lines, strings.TrimRight(string(n.ClosureLine.Value(f.source)), "\r\n"))
+	}
+	return lines
+}
+
+func (f *formatter) list(n *ast.List, previous ast.Node, width int) []string {
+	marker := f.options.BulletMarker
+	if n.IsOrdered() {
+		marker = "."
+	}
+	// Adjacent lists of the same type only stay apart if their markers differ
+	if p, ok := previous.(*ast.List); ok && p.IsOrdered() == n.IsOrdered() && f.markers[p] == marker {
+		marker = alternateMarker(marker)
+	}
+	f.markers[n] = marker
+
+	var lines []string
+	number := n.Start
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		itemMarker := marker
+		if n.IsOrdered() {
+			itemMarker = strconv.Itoa(number) + marker
+			if f.options.Numbering == NumberingAscending {
+				number++
+			}
+		}
+		if item != n.FirstChild() && !n.IsTight {
+			lines = append(lines, "")
+		}
+
+		indent := strings.Repeat(" ", len(itemMarker)+1)
+		content := f.blocks(item, width-len(indent))
+		if len(content) == 0 {
+			lines = append(lines, itemMarker)
+			continue
+		}
+		lines = append(lines, prefixLines(content, itemMarker+" ", indent)...)
+	}
+	return lines
+}
+
+func alternateMarker(marker string) string {
+	switch marker {
+	case "-":
+		return "*"
+	case ".":
+		return ")"
+	case ")":
+		return "."
+	}
+	return "-"
+}
+
+// table writes a pipe table with its columns padded to a common width
+func (f *formatter) table(n *extast.Table) []string {
+	var rows [][]string
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, escapePipes(strings.TrimSpace(joinLines(f.inlines(cell)))))
+		}
+		rows = append(rows, cells)
+	}
+
+	columns := len(n.Alignments)
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	widths := make([]int, columns)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	alignment := func(i int) extast.Alignment {
+		if i < len(n.Alignments) {
+			return n.Alignments[i]
+		}
+		return extast.AlignNone
+	}
+	formatRow := func(cells []string) string {
+		padded := make([]string, columns)
+		for i := range padded {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			padded[i] = pad(cell, widths[i], alignment(i))
+		}
+		return "| " + strings.Join(padded, " | ") + " |"
+	}
+
+	var lines []string
+	for i, row := range rows {
+		lines = append(lines, formatRow(row))
+		if i == 0 {
+			delimiters := make([]string, columns)
+			for j := range delimiters {
+				delimiters[j] = delimiter(widths[j], alignment(j))
+			}
+			lines = append(lines, "| "+strings.Join(delimiters, " | ")+" |")
+		}
+	}
+	return lines
+}
+
+// escapePipes escapes the pipes in a table cell that would end it. The
+// parser removes the backslashes from escaped pipes inside code spans.
+func escapePipes(cell string) string {
+	var b strings.Builder
+	for i := 0; i < len(cell); i++ {
+		if cell[i] == '\\' && i+1 < len(cell) {
+			b.WriteString(cell[i : i+2])
+			i++
+			continue
+		}
+		if cell[i] == '|' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(cell[i])
+	}
+	return b.String()
+}
+
+func pad(cell string, width int, alignment extast.Alignment) string {
+	space := width - utf8.RuneCountInString(cell)
+	switch alignment {
+	case extast.AlignRight:
+		return strings.Repeat(" ", space) + cell
+	case extast.AlignCenter:
+		return strings.Repeat(" ", space/2) + cell + strings.Repeat(" ", space-space/2)
+	}
+	return cell + strings.Repeat(" ", space)
+}
+
+func delimiter(width int, alignment extast.Alignment) string {
+	switch alignment {
+	case extast.AlignLeft:
+		return ":" + strings.Repeat("-", width-1)
+	case extast.AlignRight:
+		return strings.Repeat("-", width-1) + ":"
+	case extast.AlignCenter:
+		return ":" + strings.Repeat("-", width-2) + ":"
+	}
+	return strings.Repeat("-", width)
+}
+
+func (f *formatter) definitionList(n *extast.DefinitionList, width int) []string {
+	var lines []string
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		switch c := child.(type) {
+		case *extast.DefinitionTerm:
+			if len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, strings.TrimSpace(joinLines(f.inlines(c))))
+		case *extast.DefinitionDescription:
+			if !c.IsTight {
+				lines = append(lines, "")
+			}
+			lines = append(lines, prefixLines(f.blocks(c, width-2), ": ", "  ")...)
+		}
+	}
+	return lines
+}
+
+func (f *formatter) footnoteList(n *extast.FootnoteList, width int) []string {
+	var lines []string
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		footnote, ok := child.(*extast.Footnote)
+		if !ok {
+			continue
+		}
+		content := f.blocks(footnote, width-4)
+		// Footnotes of more than one line are kept apart for readability
+		if len(lines) > 0 && len(content) > 1 {
+			lines = append(lines, "")
+		}
+		label := "[^" + string(footnote.Ref) + "]:"
+		if len(content) == 0 {
+			lines = append(lines, label)
+			continue
+		}
+		lines = append(lines, prefixLines(content, label+" ", "    ")...)
+	}
+	return lines
+}
+
+// inlines formats the inline children of node
+func (f *formatter) inlines(node ast.Node) string {
+	var b strings.Builder
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(f.source))
+			if n.HardLineBreak() {
+				b.WriteString(hardBreak)
+			} else if n.SoftLineBreak() {
+				b.WriteByte('\n')
+			}
+		case *ast.String:
+			if value, ok := typographerValues[string(n.Value)]; ok {
+				b.WriteString(value)
+			} else {
+				b.Write(n.Value)
+			}
+		case *ast.CodeSpan:
+			b.WriteString(f.codeSpan(n))
+		case *ast.Emphasis:
+			marker := strings.Repeat("*", n.Level)
+			b.WriteString(marker + f.inlines(n) + marker)
+		case *extast.Strikethrough:
+			b.WriteString("~~" + f.inlines(n) + "~~")
+		case *ast.Link:
+			b.WriteString("[" + f.inlines(n) + "](" + linkTarget(n.Destination, n.Title) + ")")
+		case *ast.Image:
+			b.WriteString("![" + f.inlines(n) + "](" + linkTarget(n.Destination, n.Title) + ")")
+		case *ast.AutoLink:
+			label := string(n.Label(f.source))
+			// Bare URLs are only links with the linkify extension, which
+			// wrote them without brackets
+			if bytes.Contains(f.source, []byte("<"+label+">")) {
+				label = "<" + label + ">"
+			}
+			b.WriteString(label)
+		case *ast.RawHTML:
+			for i := 0; i < n.Segments.Len(); i++ {
+				segment := n.Segments.At(i)
+				b.Write(segment.Value(f.source))
+			}
+		case *extast.TaskCheckBox:
+			if n.IsChecked {
+				b.WriteString("[x] ")
+			} else {
+				b.WriteString("[ ] ")
+			}
+		case *extast.FootnoteLink:
+			b.WriteString("[^" + f.footnotes[n.Index] + "]")
+		case *extast.FootnoteBacklink:
+			// Generated by the parser, not part of the source
+		default:
+			b.WriteString(f.inlines(child))
+		}
+	}
+	return b.String()
+}
+
+// codeSpan writes inline code with a delimiter longer than any run of
+// backticks in the code, padded with spaces where the code needs it
+func (f *formatter) codeSpan(n *ast.CodeSpan) string {
+	var code strings.Builder
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if text, ok := child.(*ast.Text); ok {
+			code.Write(text.Segment.Value(f.source))
+		} else if s, ok := child.(*ast.String); ok {
+			code.Write(s.Value)
+		}
+	}
+	content := strings.ReplaceAll(code.String(), "\n", " ")
+
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	delimiter := strings.Repeat("`", longest+1)
+
+	if strings.HasPrefix(content, "`") || strings.HasSuffix(content, "`") ||
+		(strings.HasPrefix(content, " ") && strings.HasSuffix(content, " ") && strings.TrimSpace(content) != "") {
+		content = " " + content + " "
+	}
+	return delimiter + content + delimiter
+}
+
+// linkTarget writes a link destination and title as they appear between the
+// parentheses of an inline link
+func linkTarget(destination, title []byte) string {
+	target := string(destination)
+	if strings.ContainsAny(target, " <>\t") || !balancedParens(target) {
+		target = "<" + strings.NewReplacer("<", `\<`, ">", `\>`).Replace(target) + ">"
+	}
+	if len(title) == 0 {
+		return target
+	}
+
+	// Titles are kept as written, so pick quotes that don't occur in them
+	t := string(title)
+	switch {
+	case !hasUnescaped(t, '"'):
+		return target + ` "` + t + `"`
+	case !hasUnescaped(t, '\''):
+		return target + " '" + t + "'"
+	}
+	return target + " (" + t + ")"
+}
+
+func balancedParens(s string) bool {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+func hasUnescaped(s string, c byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == c {
+			return true
+		}
+	}
+	return false
+}
+
+// prose breaks formatted inline content into lines according to the wrap
+// mode, ending lines at hard breaks with a backslash
+func (f *formatter) prose(text string, width int) []string {
+	if strings.TrimSpace(strings.ReplaceAll(text, hardBreak, "")) == "" {
+		return nil
+	}
+
+	parts := strings.Split(text, hardBreak)
+	var lines []string
+	for i, part := range parts {
+		var partLines []string
+		switch f.options.Wrap {
+		case WrapAlways:
+			partLines = wrap(joinLines(part), max(width, 1))
+		case WrapNever:
+			partLines = []string{joinLines(part)}
+		default:
+			for _, line := range strings.Split(part, "\n") {
+				partLines = append(partLines, strings.TrimSpace(line))
+			}
+		}
+		if i < len(parts)-1 {
+			partLines[len(partLines)-1] += `\`
+		}
+		lines = append(lines, partLines...)
+	}
+	return lines
+}
+
+// wrap breaks text into lines of at most width characters where it can.
+// Words that would change the meaning of a line they start stay on the
+// previous line.
+func wrap(text string, width int) []string {
+	var words []string
+	inCode := false
+	for _, word := range strings.Split(strings.TrimSpace(text), " ") {
+		// Runs of spaces are collapsed, except inside code spans where they
+		// are part of the code
+		if word == "" {
+			if inCode && len(words) > 0 {
+				words[len(words)-1] += " "
+			}
+			continue
+		}
+		words = append(words, word)
+		if len(backtickRuns.FindAllString(word, -1))%2 == 1 {
+			inCode = !inCode
+		}
+	}
+
+	var lines []string
+	line := ""
+	for _, word := range words {
+		if line == "" {
+			line = word
+			continue
+		}
+		if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) > width && !unsafeLineStart.MatchString(word) {
+			lines = append(lines, strings.TrimRight(line, " "))
+			line = word
+			continue
+		}
+		line += " " + word
+	}
+	return append(lines, line)
+}
+
+// joinLines replaces soft line breaks with spaces
+func joinLines(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, hardBreak, "\n"), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, " ")
+}
+
+// blockLines returns the source lines of a block, without line endings
+func blockLines(n ast.Node, source []byte) []string {
+	var lines []string
+	segments := n.Lines()
+	for i := 0; i < segments.Len(); i++ {
+		segment := segments.At(i)
+		lines = append(lines, strings.TrimRight(string(segment.Value(source)), "\r\n"))
+	}
+	return lines
+}
+
+// prefixLines prefixes the first line with first and the others with rest,
+// leaving blank lines without trailing spaces
+func prefixLines(lines []string, first, rest string) []string {
+	prefixed := make([]string, len(lines))
+	for i, line := range lines {
+		prefix := rest
+		if i == 0 {
+			prefix = first
+		}
+		if line == "" {
+			prefix = strings.TrimRight(prefix, " ")
+		}
+		prefixed[i] = prefix + line
+	}
+	return prefixed
+}
@@ -0,0 +1,175 @@
+package format
+
+import (
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	defaults := DefaultOptions()
+	with := func(change func(*Options)) Options {
+		options := defaults
+		change(&options)
+		return options
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		options Options
+		want    string
+	}{
+		{
+			name:    "atx headings",
+			source:  "Title\n=====\n\nSub #\n---\n\n###   Deep ###\n",
+			options: defaults,
+			want:    "# Title\n\n## Sub \\#\n\n### Deep\n",
+		},
+		{
+			name:    "setext headings",
+			source:  "# Title\n\n## Sub\n\n### Deep\n",
+			options: with(func(o *Options) { o.HeadingStyle = HeadingSetext }),
+			want:    "Title\n=====\n\nSub\n---\n\n### Deep\n",
+		},
+		{
+			name:    "bullet markers",
+			source:  "* one\n* two\n\n+ three\n",
+			options: defaults,
+			want:    "- one\n- two\n\n* three\n",
+		},
+		{
+			name:    "ascending numbering",
+			source:  "3. a\n3. b\n3. c\n",
+			options: defaults,
+			want:    "3. a\n4. b\n5. c\n",
+		},
+		{
+			name:    "numbering one",
+			source:  "1. a\n2. b\n",
+			options: with(func(o *Options) { o.Numbering = NumberingOne }),
+			want:    "1. a\n1. b\n",
+		},
+		{
+			name:    "loose and nested lists",
+			source:  "1. a\n\n   more\n2. b\n   * c\n",
+			options: defaults,
+			want:    "1. a\n\n   more\n\n2. b\n\n   - c\n",
+		},
+		{
+			name:    "emphasis and thematic breaks",
+			source:  "_a_ __b__\n\n***\n",
+			options: defaults,
+			want:    "*a* **b**\n\n---\n",
+		},
+		{
+			name:    "fenced code",
+			source:  "~~~ go\nfunc main() {}\n~~~\n\n    indented\n\n````\n```\n````\n",
+			options: defaults,
+			want:    "```go\nfunc main() {}\n```\n\n```\nindented\n```\n\n````\n```\n````\n",
+		},
+		{
+			name:    "aligned table",
+			source:  "|a|long header|c|\n|:-|:-:|-:|\n|`x\\|y`|b|1|\n",
+			options: defaults,
+			want:    "| a      | long header |   c |\n| :----- | :---------: | --: |\n| `x\\|y` |      b      |   1 |\n",
+		},
+		{
+			name:    "preserve line breaks",
+			source:  "one\n   two  \nthree\\\nfour\n",
+			options: defaults,
+			want:    "one\ntwo\\\nthree\\\nfour\n",
+		},
+		{
+			name:    "unwrap",
+			source:  "one\ntwo\nthree\n\n> quoted\n> text\n",
+			options: with(func(o *Options) { o.Wrap = WrapNever }),
+			want:    "one two three\n\n> quoted text\n",
+		},
+		{
+			name:    "wrap",
+			source:  "aaa bbb ccc ddd eee fff\n\n- ggg hhh iii jjj\n",
+			options: with(func(o *Options) { o.Wrap, o.Width = WrapAlways, 10 }),
+			want:    "aaa bbb\nccc ddd\neee fff\n\n- ggg hhh\n  iii jjj\n",
+		},
+		{
+			name:    "wrap keeps block markers off line starts",
+			source:  "aaaa - bbbb 1. cccc # dddd\n",
+			options: with(func(o *Options) { o.Wrap, o.Width = WrapAlways, 5 }),
+			want:    "aaaa -\nbbbb 1.\ncccc #\ndddd\n",
+		},
+		{
+			name:    "links",
+			source:  "[a](<b c>) [d](e 'f') ![g](h.png) <https://x.y> [r]\n\n[r]: /ref \"T\"\n",
+			options: defaults,
+			want:    "[a](<b c>) [d](e \"f\") ![g](h.png) <https://x.y> [r](/ref \"T\")\n",
+		},
+		{
+			name:    "code spans",
+			source:  "`` a`b `` ` `` `\n",
+			options: defaults,
+			want:    "``a`b`` ``` `` ```\n",
+		},
+		{
+			name:    "front matter",
+			source:  "---\ntitle: x\n---\n\nText\n",
+			options: defaults,
+			want:    "---\ntitle: x\n---\n\nText\n",
+		},
+		{
+			name:    "task list and html",
+			source:  "* [x] done\n* [ ] todo\n\n<div>\nhi\n</div>\n",
+			options: defaults,
+			want:    "- [x] done\n- [ ] todo\n\n<div>\nhi\n</div>\n",
+		},
+	}
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := string(Format(r, []byte(tt.source), tt.options))
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if again := string(Format(r, []byte(got), tt.options)); again != got {
+				t.Errorf("Format() is not idempotent, second pass gives\n%s", again)
+			}
+		})
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		change  func(*Options)
+		wantErr bool
+	}{
+		{"defaults", func(*Options) {}, false},
+		{"unknown wrap", func(o *Options) { o.Wrap = "sometimes" }, true},
+		{"wrap without width", func(o *Options) { o.Wrap, o.Width = WrapAlways, 0 }, true},
+		{"unknown heading style", func(o *Options) { o.HeadingStyle = "html" }, true},
+		{"unknown bullet", func(o *Options) { o.BulletMarker = "•" }, true},
+		{"unknown numbering", func(o *Options) { o.Numbering = "roman" }, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			options := DefaultOptions()
+			tt.change(&options)
+			if err := options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,24 +2,36 @@ package highlighter
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/chroma"
 	"github.com/alecthomas/chroma/formatters"
 	"github.com/alecthomas/chroma/lexers"
 	"github.com/alecthomas/chroma/styles"
+
+	"github.com/codehakase/md/internal/theme"
 )
 
 // ChromaHelper handles the low-level Chroma integration for syntax highlighting
 type ChromaHelper struct {
 	formatter chroma.Formatter
 	style     *chroma.Style
+	languages *Languages
+	// timeout bounds how long tokenising a piece of code may take; 0 is unlimited
+	timeout time.Duration
 }
 
 // NewChromaHelper creates a new ChromaHelper with optimal terminal settings
 func NewChromaHelper() *ChromaHelper {
-	formatter := formatters.Get("terminal256")
+	return NewChromaHelperForProfile(theme.ProfileANSI256)
+}
+
+// NewChromaHelperForProfile creates a new ChromaHelper whose output matches the given color profile
+func NewChromaHelperForProfile(profile theme.ColorProfile) *ChromaHelper {
+	formatter := formatters.Get(formatterForProfile(profile))
 	if formatter == nil {
 		formatter = formatters.Get("terminal")
 		if formatter == nil {
@@ -41,25 +53,103 @@ func NewChromaHelper() *ChromaHelper {
 	return &ChromaHelper{
 		formatter: formatter,
 		style:     style,
+		languages: NewLanguages(),
+	}
+}
+
+// formatterForProfile returns the name of the Chroma formatter for a color profile
+func formatterForProfile(profile theme.ColorProfile) string {
+	switch profile {
+	case theme.ProfileNone:
+		return "noop"
+	case theme.ProfileANSI:
+		return "terminal16"
+	case theme.ProfileTrueColor:
+		return "terminal16m"
+	default:
+		return "terminal256"
 	}
 }
 
 // Highlight performs syntax highlighting using Chroma
 func (ch *ChromaHelper) Highlight(code, language string) (string, error) {
-	lexer := ch.getLexer(language, code)
+	// Guessing the language runs every lexer's analyser over the code, so it
+	// is bounded by the timeout too
+	return ch.withTimeout(func(ctx context.Context) (string, error) {
+		lexer := ch.getLexer(language, code)
+		if lexer == nil {
+			return "", fmt.Errorf("no suitable lexer found for language: %s", language)
+		}
+		return ch.formatContext(ctx, lexer, code)
+	})
+}
+
+// HighlightInline highlights a snippet of inline code as the given language.
+// Unlike Highlight it never guesses: unknown languages are an error.
+func (ch *ChromaHelper) HighlightInline(code, language string) (string, error) {
+	lexer := ch.languages.Lexer(language)
 	if lexer == nil {
-		return "", fmt.Errorf("no suitable lexer found for language: %s", language)
+		return "", fmt.Errorf("no lexer found for language: %s", language)
+	}
+
+	result, err := ch.withTimeout(func(ctx context.Context) (string, error) {
+		return ch.formatContext(ctx, lexer, code)
+	})
+	if err != nil {
+		return "", err
+	}
+	// Inline code has no newlines of its own; lexers may add a final one
+	return strings.ReplaceAll(result, "\n", ""), nil
+}
+
+// withTimeout runs highlight, giving up when it takes longer than the timeout
+func (ch *ChromaHelper) withTimeout(highlight func(ctx context.Context) (string, error)) (string, error) {
+	if ch.timeout <= 0 {
+		return highlight(context.Background())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ch.timeout)
+	defer cancel()
+
+	type result struct {
+		text string
+		err  error
 	}
+	done := make(chan result, 1)
+	go func() {
+		text, err := highlight(ctx)
+		done <- result{text, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err == nil && ctx.Err() != nil {
+			// The iterator stopped early, so the output is incomplete
+			r.err = fmt.Errorf("tokenizing timed out after %s", ch.timeout)
+		}
+		return r.text, r.err
+	case <-ctx.Done():
+		return "", fmt.Errorf("tokenizing timed out after %s", ch.timeout)
+	}
+}
 
+// formatContext tokenises and formats code until ctx is done
+func (ch *ChromaHelper) formatContext(ctx context.Context, lexer chroma.Lexer, code string) (string, error) {
 	lexer = chroma.Coalesce(lexer)
 
 	iterator, err := lexer.Tokenise(nil, code)
 	if err != nil {
 		return "", fmt.Errorf("failed to tokenize code: %w", err)
 	}
+	tokens := func() chroma.Token {
+		if ctx.Err() != nil {
+			return chroma.EOF
+		}
+		return iterator()
+	}
 
 	var buf bytes.Buffer
-	err = ch.formatter.Format(&buf, ch.style, iterator)
+	err = ch.formatter.Format(&buf, ch.style, tokens)
 	if err != nil {
 		return "", fmt.Errorf("failed to format highlighted code: %w", err)
 	}
@@ -70,69 +160,30 @@ func (ch *ChromaHelper) Highlight(code, language string) (string, error) {
 	return result, nil
 }
 
+// SetTimeout bounds how long highlighting a piece of code may take; 0 removes
+// the bound. Highlighting that times out is abandoned: it stops at the next
+// token, while a single slow match is still bounded by Chroma's own timeout
+// for each rule.
+func (ch *ChromaHelper) SetTimeout(timeout time.Duration) {
+	ch.timeout = timeout
+}
+
 // getLexer returns the most appropriate lexer for the given language and code
 func (ch *ChromaHelper) getLexer(language, code string) chroma.Lexer {
-	var lexer chroma.Lexer
-
-	if language != "" {
-		lexer = lexers.Get(language)
-		if lexer != nil {
-			return lexer
-		}
-
-		if aliasLexer := ch.getLexerByAlias(language); aliasLexer != nil {
-			return aliasLexer
-		}
+	if lexer := ch.languages.Lexer(language); lexer != nil {
+		return lexer
 	}
 
-	lexer = lexers.Analyse(code)
-	if lexer != nil {
+	if lexer := lexers.Analyse(code); lexer != nil {
 		return lexer
 	}
 
 	return lexers.Fallback
 }
 
-// getLexerByAlias handles language aliases that might not be directly supported
-func (ch *ChromaHelper) getLexerByAlias(language string) chroma.Lexer {
-	switch strings.ToLower(language) {
-	case "jsonc", "json5":
-		return lexers.Get("json")
-	case "tsx":
-		return lexers.Get("typescript")
-	case "jsx":
-		return lexers.Get("javascript")
-	case "sh", "shell", "zsh", "fish":
-		return lexers.Get("bash")
-	case "yml":
-		return lexers.Get("yaml")
-	case "ps1", "powershell":
-		return lexers.Get("powershell")
-	case "cmd", "batch", "bat":
-		return lexers.Get("batch")
-	case "asm", "assembly":
-		return lexers.Get("nasm")
-	case "tex", "latex":
-		return lexers.Get("latex")
-	case "md", "markdown":
-		return lexers.Get("markdown")
-	case "ini", "cfg", "conf", "config":
-		return lexers.Get("ini")
-	case "toml":
-		return lexers.Get("toml")
-	case "proto", "protobuf":
-		return lexers.Get("protobuf")
-	case "graphql", "gql":
-		return lexers.Get("graphql")
-	case "hcl", "terraform":
-		return lexers.Get("hcl")
-	case "nginx":
-		return lexers.Get("nginx")
-	case "apache":
-		return lexers.Get("apacheconf")
-	default:
-		return nil
-	}
+// Languages returns the registry used to resolve code block languages
+func (ch *ChromaHelper) Languages() *Languages {
+	return ch.languages
 }
 
 // GetAvailableStyles returns a list of available Chroma styles suitable for terminals
@@ -163,10 +214,12 @@ func (ch *ChromaHelper) GetAvailableStyles() []string {
 	return terminalFriendly
 }
 
-// SetStyle changes the current highlighting style
-// Note: chroma always returns a style (fallback if not found), so this never errors
+// SetStyle changes the current highlighting style, rejecting names that are not registered with Chroma
 func (ch *ChromaHelper) SetStyle(styleName string) error {
-	style := styles.Get(styleName)
+	style, ok := styles.Registry[styleName]
+	if !ok {
+		return fmt.Errorf("unknown style: %s", styleName)
+	}
 	ch.style = style
 	return nil
 }
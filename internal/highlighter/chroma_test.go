@@ -1,7 +1,13 @@
 package highlighter
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
 )
 
 func TestNewChromaHelper(t *testing.T) {
@@ -135,11 +141,11 @@ func TestGetLexerByAlias(t *testing.T) {
 		t.Run(tt.alias, func(t *testing.T) {
 			t.Parallel()
 
-			lexer := ch.getLexerByAlias(tt.alias)
+			lexer := ch.Languages().Lexer(tt.alias)
 			found := lexer != nil
 
 			if found != tt.expected {
-				t.Errorf("getLexerByAlias(%q) found=%v, expected=%v", tt.alias, found, tt.expected)
+				t.Errorf("Lexer(%q) found=%v, expected=%v", tt.alias, found, tt.expected)
 			}
 		})
 	}
@@ -188,9 +194,9 @@ func TestSetStyle(t *testing.T) {
 			wantErr:   false,
 		},
 		{
-			name:      "unknown style should not error",
+			name:      "unknown style",
 			styleName: "somerarestyle",
-			wantErr:   false,
+			wantErr:   true,
 		},
 	}
 
@@ -231,3 +237,64 @@ func TestGetCurrentStyle(t *testing.T) {
 	}
 }
 
+func TestChromaHelperTimeout(t *testing.T) {
+	t.Parallel()
+
+	// A rule that backtracks exponentially on a run of a's with no b
+	lexer := chroma.MustNewLexer(&chroma.Config{Name: "backtrack"}, chroma.Rules{
+		"root": {
+			{Pattern: `(a+)+b`, Type: chroma.Keyword},
+			{Pattern: `.`, Type: chroma.Text},
+		},
+	})
+	format := func(ch *ChromaHelper, code string) (string, error) {
+		return ch.withTimeout(func(ctx context.Context) (string, error) {
+			return ch.formatContext(ctx, lexer, code)
+		})
+	}
+
+	ch := NewChromaHelper()
+	ch.SetTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := format(ch, strings.Repeat("a", 40))
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("format() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("format() took %s, want it to give up after the timeout", elapsed)
+	}
+
+	// Code that tokenises in time is highlighted as usual
+	if _, err := format(ch, "ab"); err != nil {
+		t.Errorf("format() of short code error = %v", err)
+	}
+}
+
+func TestChromaHelperTimeoutCoversAnalysis(t *testing.T) {
+	t.Parallel()
+
+	// An analyser that is slow only for this test's code, so other tests
+	// guessing languages are unaffected
+	const marker = "slow-analysis-marker"
+	lexers.Register(chroma.MustNewLexer(&chroma.Config{Name: "slow-analysis"}, chroma.Rules{
+		"root": {{Pattern: `.`, Type: chroma.Text}},
+	}).SetAnalyser(func(text string) float32 {
+		if strings.Contains(text, marker) {
+			time.Sleep(2 * time.Second)
+		}
+		return 0
+	}))
+
+	ch := NewChromaHelper()
+	ch.SetTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	_, err := ch.Highlight(marker, "")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Highlight() error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Highlight() took %s, want it to give up after the timeout", elapsed)
+	}
+}
@@ -2,6 +2,7 @@ package highlighter
 
 import (
 	"strings"
+	"time"
 
 	"github.com/codehakase/md/internal/theme"
 )
@@ -10,13 +11,25 @@ import (
 type Highlighter struct {
 	themeManager *theme.ThemeManager
 	chromaHelper *ChromaHelper
+	// inlineMode is InlineAuto, a language name, or "" for plain inline code
+	inlineMode string
 }
 
 // New creates a new code highlighter with the given theme manager
 func New(themeManager *theme.ThemeManager) *Highlighter {
-	return &Highlighter{
+	h := &Highlighter{
 		themeManager: themeManager,
-		chromaHelper: NewChromaHelper(),
+		chromaHelper: NewChromaHelperForProfile(themeManager.ColorProfile()),
+	}
+	h.syncStyle()
+	return h
+}
+
+// syncStyle makes the Chroma style follow the theme manager's code theme
+func (h *Highlighter) syncStyle() {
+	if name := h.themeManager.GetChromaTheme(); name != h.chromaHelper.GetCurrentStyle() {
+		// Unknown names keep the current style; they are rejected when the theme is chosen
+		_ = h.chromaHelper.SetStyle(name)
 	}
 }
 
@@ -27,7 +40,7 @@ func (h *Highlighter) Highlight(code, language string) (string, error) {
 		return code, nil
 	}
 
-	language = h.normalizeLanguage(language)
+	h.syncStyle()
 
 	highlighted, err := h.chromaHelper.Highlight(code, language)
 	if err != nil {
@@ -42,77 +55,19 @@ func (h *Highlighter) HighlightCode(code, language string) (string, error) {
 	return h.Highlight(code, language)
 }
 
-// HighlightInlineCode highlights inline code snippets using theme manager
-func (h *Highlighter) HighlightInlineCode(code string) string {
-	return h.themeManager.Style(code, theme.Code)
+// SetTimeout bounds how long highlighting a code block may take; code that
+// takes longer is shown without highlighting. 0 removes the bound.
+func (h *Highlighter) SetTimeout(timeout time.Duration) {
+	h.chromaHelper.SetTimeout(timeout)
 }
 
-// normalizeLanguage normalizes language hints from markdown fenced code blocks
-func (h *Highlighter) normalizeLanguage(language string) string {
-	if language == "" {
-		return language
-	}
-
-	lang := strings.ToLower(strings.TrimSpace(language))
+// Languages returns the registry used to resolve code block languages, so
+// user-defined aliases can be added
+func (h *Highlighter) Languages() *Languages {
+	return h.chromaHelper.Languages()
+}
 
-	switch lang {
-	case "js", "javascript":
-		return "javascript"
-	case "ts", "typescript":
-		return "typescript"
-	case "py", "python":
-		return "python"
-	case "rb", "ruby":
-		return "ruby"
-	case "sh", "bash", "shell":
-		return "bash"
-	case "yml", "yaml":
-		return "yaml"
-	case "json":
-		return "json"
-	case "xml", "html":
-		return lang
-	case "css":
-		return "css"
-	case "sql":
-		return "sql"
-	case "go", "golang":
-		return "go"
-	case "rust", "rs":
-		return "rust"
-	case "c":
-		return "c"
-	case "cpp", "c++", "cxx":
-		return "cpp"
-	case "java":
-		return "java"
-	case "php":
-		return "php"
-	case "swift":
-		return "swift"
-	case "kotlin", "kt":
-		return "kotlin"
-	case "scala":
-		return "scala"
-	case "r":
-		return "r"
-	case "matlab":
-		return "matlab"
-	case "perl":
-		return "perl"
-	case "lua":
-		return "lua"
-	case "vim":
-		return "vim"
-	case "dockerfile", "docker":
-		return "dockerfile"
-	case "makefile", "make":
-		return "makefile"
-	case "diff":
-		return "diff"
-	case "plain", "text", "txt":
-		return "text"
-	default:
-		return lang
-	}
-}
\ No newline at end of file
+// HighlightInlineCode highlights inline code snippets, see HighlightInlineCodeIn
+func (h *Highlighter) HighlightInlineCode(code string) string {
+	return h.HighlightInlineCodeIn(code, "")
+}
@@ -1,6 +1,7 @@
 package highlighter
 
 import (
+	"regexp"
 	"strings"
 	"testing"
 
@@ -164,11 +165,93 @@ func TestNormalizeLanguage(t *testing.T) {
 		t.Run(tt.input, func(t *testing.T) {
 			t.Parallel()
 
-			result := h.normalizeLanguage(tt.input)
+			result := h.Languages().Normalize(tt.input)
 			if result != tt.expected {
-				t.Errorf("normalizeLanguage(%q) = %q, want %q", tt.input, result, tt.expected)
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
 		})
 	}
 }
 
+func TestHighlighterFollowsChromaTheme(t *testing.T) {
+	t.Parallel()
+
+	light := New(theme.NewWithBackground(theme.BackgroundLight))
+	if got := light.chromaHelper.GetCurrentStyle(); got != "github" {
+		t.Errorf("light background style = %q, want github", got)
+	}
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	h := New(tm)
+	if got := h.chromaHelper.GetCurrentStyle(); got != "monokai" {
+		t.Errorf("dark background style = %q, want monokai", got)
+	}
+
+	if err := tm.SetChromaTheme("dracula"); err != nil {
+		t.Fatalf("SetChromaTheme() returned error: %v", err)
+	}
+	if _, err := h.Highlight("package main", "go"); err != nil {
+		t.Fatalf("Highlight() returned error: %v", err)
+	}
+	if got := h.chromaHelper.GetCurrentStyle(); got != "dracula" {
+		t.Errorf("style after SetChromaTheme = %q, want dracula", got)
+	}
+
+	if err := tm.SetChromaTheme("not-a-style"); err == nil {
+		t.Error("SetChromaTheme() should reject unknown styles")
+	}
+}
+
+func TestHighlightInlineCodeIn(t *testing.T) {
+	t.Parallel()
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	plain := func(code string) string { return tm.Style(code, theme.Code) }
+
+	tests := []struct {
+		name    string
+		mode    string
+		code    string
+		context string
+		plain   bool
+		visible string
+	}{
+		{"hint", InlinePlain, "fmt.Println(){:go}", "", false, "fmt.Println()"},
+		{"unknown hint", InlinePlain, "x(){:nonexistentlang}", "", true, "x()"},
+		{"plain mode", InlinePlain, "fmt.Println()", "go", true, "fmt.Println()"},
+		{"auto uses context", InlineAuto, "fmt.Println()", "go", false, "fmt.Println()"},
+		{"auto skips names", InlineAuto, "README.md", "go", true, "README.md"},
+		{"auto without context", InlineAuto, "x := 1", "", true, "x := 1"},
+		{"language mode", "python", "print(1)", "", false, "print(1)"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := New(tm)
+			if err := h.SetInlineMode(tt.mode); err != nil {
+				t.Fatalf("SetInlineMode(%q) returned error: %v", tt.mode, err)
+			}
+
+			result := h.HighlightInlineCodeIn(tt.code, tt.context)
+			if got := stripANSI(result); got != tt.visible {
+				t.Errorf("visible text = %q, want %q", got, tt.visible)
+			}
+			if (result == plain(tt.visible)) != tt.plain {
+				t.Errorf("HighlightInlineCodeIn(%q) = %q, plain = %v", tt.code, result, tt.plain)
+			}
+		})
+	}
+
+	if err := New(tm).SetInlineMode("nonexistentlang"); err == nil {
+		t.Error("SetInlineMode() with an unknown language should fail")
+	}
+}
+
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+func stripANSI(s string) string {
+	return ansiSequence.ReplaceAllString(s, "")
+}
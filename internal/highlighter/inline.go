@@ -0,0 +1,91 @@
+package highlighter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/lexers"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+// Inline code modes for SetInlineMode; any other value is a language name
+const (
+	// InlinePlain styles inline code with the theme's code color
+	InlinePlain = "plain"
+	// InlineAuto highlights inline code that looks like code as the language
+	// of the surrounding code blocks
+	InlineAuto = "auto"
+)
+
+// inlineHint matches inline code ending in a language hint, as in
+// `fmt.Println(){:go}`
+var inlineHint = regexp.MustCompile(`^(.+?)\{:([\w+#.-]+)\}$`)
+
+// codeLike matches constructs that rarely appear in inline code that is just
+// a name, a path or a command: calls, scope and arrow operators,
+// assignments, brackets and statement terminators
+var codeLike = regexp.MustCompile(`\w\(|::|->|=>|:=|\s=\s|[{}\[\];]`)
+
+// SetInlineMode sets how inline code without a language hint is highlighted:
+// InlinePlain, InlineAuto or the name of a language to highlight all of it as
+func (h *Highlighter) SetInlineMode(mode string) error {
+	switch mode = strings.TrimSpace(mode); mode {
+	case "", InlinePlain:
+		h.inlineMode = ""
+	case InlineAuto:
+		h.inlineMode = InlineAuto
+	default:
+		if h.Languages().Lexer(mode) == nil {
+			return fmt.Errorf("invalid inline code mode %q (want plain, auto or a language from 'md languages')", mode)
+		}
+		h.inlineMode = mode
+	}
+	return nil
+}
+
+// HighlightInlineCodeIn highlights an inline code snippet. A trailing {:lang}
+// hint selects the language and is removed; otherwise the inline mode
+// decides, with context being the language of the surrounding code blocks.
+// Snippets that can't be highlighted get the theme's code color.
+func (h *Highlighter) HighlightInlineCodeIn(code, context string) string {
+	language := ""
+	if match := inlineHint.FindStringSubmatch(code); match != nil {
+		code, language = match[1], match[2]
+	} else {
+		switch h.inlineMode {
+		case "":
+		case InlineAuto:
+			language = guessInlineLanguage(code, context)
+		default:
+			language = h.inlineMode
+		}
+	}
+
+	if language == "" || strings.TrimSpace(code) == "" {
+		return h.themeManager.Style(code, theme.Code)
+	}
+
+	h.syncStyle()
+	highlighted, err := h.chromaHelper.HighlightInline(code, language)
+	if err != nil {
+		return h.themeManager.Style(code, theme.Code)
+	}
+	return highlighted
+}
+
+// guessInlineLanguage returns the language to highlight a snippet as in
+// InlineAuto mode, or "" to leave it plain
+func guessInlineLanguage(code, context string) string {
+	if !codeLike.MatchString(code) {
+		return ""
+	}
+	if context != "" {
+		return context
+	}
+	if lexer := lexers.Analyse(code); lexer != nil {
+		return lexer.Config().Name
+	}
+	return ""
+}
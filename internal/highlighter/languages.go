@@ -0,0 +1,193 @@
+package highlighter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+)
+
+// builtinAliases maps language hints that Chroma doesn't know, or resolves
+// differently than markdown authors expect, to a Chroma lexer name or alias
+var builtinAliases = map[string]string{
+	// Common abbreviations
+	"js":     "javascript",
+	"ts":     "typescript",
+	"py":     "python",
+	"rb":     "ruby",
+	"rs":     "rust",
+	"kt":     "kotlin",
+	"golang": "go",
+	"c++":    "cpp",
+	"cxx":    "cpp",
+	"yml":    "yaml",
+	"sh":     "bash",
+	"shell":  "bash",
+	"docker": "dockerfile",
+	"make":   "makefile",
+
+	// Formats with a close relative
+	"jsonc":      "json",
+	"json5":      "json",
+	"asm":        "nasm",
+	"assembly":   "nasm",
+	"conf":       "ini",
+	"config":     "ini",
+	"properties": "ini",
+	"dotenv":     "bash",
+	"env":        "bash",
+	"j2":         "jinja",
+
+	// Interactive sessions
+	"sh-session":   "console",
+	"shellsession": "console",
+	"terminal":     "console",
+	"golang-repl":  "go",
+
+	// Not highlighted, but never guessed from the content either
+	"plain":    "text",
+	"txt":      "text",
+	"csv":      "text",
+	"mermaid":  "text",
+	"plantuml": "text",
+}
+
+// Languages resolves the info string of fenced code blocks to Chroma lexers.
+// Hints are matched against the alias table, then as file names such as
+// "main.go", then against Chroma's lexer names and aliases, and finally as
+// file names without an extension such as "Dockerfile".
+type Languages struct {
+	aliases map[string]string
+}
+
+// Language describes a supported language for listing
+type Language struct {
+	Name      string
+	Aliases   []string
+	Filenames []string
+}
+
+// NewLanguages creates a registry with the built-in aliases
+func NewLanguages() *Languages {
+	aliases := make(map[string]string, len(builtinAliases))
+	for alias, name := range builtinAliases {
+		aliases[alias] = name
+	}
+	return &Languages{aliases: aliases}
+}
+
+// AddAlias makes alias resolve to the same lexer as language
+func (l *Languages) AddAlias(alias, language string) error {
+	alias = strings.ToLower(strings.TrimSpace(alias))
+	if alias == "" {
+		return fmt.Errorf("empty language alias")
+	}
+	if lexers.Get(l.Normalize(language)) == nil {
+		return fmt.Errorf("alias %s: unknown language %q (see 'md languages')", alias, language)
+	}
+	l.aliases[alias] = strings.ToLower(strings.TrimSpace(language))
+	return nil
+}
+
+// AddAliases adds every alias in the map, in sorted order so errors are deterministic
+func (l *Languages) AddAliases(aliases map[string]string) error {
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+
+	for _, alias := range names {
+		if err := l.AddAlias(alias, aliases[alias]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Normalize lower-cases a language hint and resolves aliases
+func (l *Languages) Normalize(language string) string {
+	lang := strings.ToLower(strings.TrimSpace(language))
+	// Aliases may point at other aliases; the bound guards against cycles
+	for i := 0; i < 4; i++ {
+		target, ok := l.aliases[lang]
+		if !ok || target == lang {
+			break
+		}
+		lang = target
+	}
+	return lang
+}
+
+// Lexer returns the lexer for a language hint, or nil if nothing matches
+func (l *Languages) Lexer(language string) chroma.Lexer {
+	hint := strings.TrimSpace(language)
+	if hint == "" {
+		return nil
+	}
+
+	name := l.Normalize(hint)
+	if _, ok := l.aliases[strings.ToLower(hint)]; ok {
+		return lexers.Get(name)
+	}
+
+	// File name hints keep their case, which matters for names like
+	// "CMakeLists.txt" that Chroma would otherwise match by extension only
+	if strings.ContainsAny(hint, "./") {
+		if lexer := lexers.Match(filepath.Base(hint)); lexer != nil {
+			return lexer
+		}
+	}
+
+	if lexer := lexers.Get(name); lexer != nil {
+		return lexer
+	}
+	return lexers.Match(filepath.Base(hint))
+}
+
+// List returns every Chroma language with its aliases, including the
+// registry's own, sorted by name
+func (l *Languages) List() []Language {
+	extra := map[string][]string{}
+	for alias := range l.aliases {
+		if lexer := lexers.Get(l.Normalize(alias)); lexer != nil {
+			name := lexer.Config().Name
+			extra[name] = append(extra[name], alias)
+		}
+	}
+
+	var languages []Language
+	// lexers.Registry is a copy taken at start-up, so it misses lexers
+	// registered from definition files; Names reads the live registry
+	for _, name := range lexers.Names(false) {
+		lexer := lexers.Get(name)
+		if lexer == nil {
+			continue
+		}
+		config := lexer.Config()
+
+		seen := map[string]bool{}
+		var aliases []string
+		for _, alias := range append(append([]string{}, config.Aliases...), extra[config.Name]...) {
+			if !seen[alias] {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+		sort.Strings(aliases)
+
+		languages = append(languages, Language{
+			Name:      config.Name,
+			Aliases:   aliases,
+			Filenames: config.Filenames,
+		})
+	}
+
+	sort.Slice(languages, func(i, j int) bool {
+		return strings.ToLower(languages[i].Name) < strings.ToLower(languages[j].Name)
+	})
+	return languages
+}
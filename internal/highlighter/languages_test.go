@@ -0,0 +1,101 @@
+package highlighter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLanguagesLexer(t *testing.T) {
+	t.Parallel()
+
+	l := NewLanguages()
+
+	tests := []struct {
+		hint     string
+		expected string
+	}{
+		{"go", "Go"},
+		{"golang", "Go"},
+		{"golang-repl", "Go"},
+		{"console", "BashSession"},
+		{"sh-session", "BashSession"},
+		{"jsonc", "JSON"},
+		{"Dockerfile", "Docker"},
+		{"Makefile", "Base Makefile"},
+		{"main.go", "Go"},
+		{"cmd/server/main.rs", "Rust"},
+		{"CMakeLists.txt", "CMake"},
+		{"mermaid", "plaintext"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.hint, func(t *testing.T) {
+			t.Parallel()
+
+			lexer := l.Lexer(tt.hint)
+			if lexer == nil {
+				t.Fatalf("Lexer(%q) = nil, want %s", tt.hint, tt.expected)
+			}
+			if got := lexer.Config().Name; got != tt.expected {
+				t.Errorf("Lexer(%q) = %s, want %s", tt.hint, got, tt.expected)
+			}
+		})
+	}
+
+	if lexer := l.Lexer("nonexistentlang"); lexer != nil {
+		t.Errorf("Lexer(nonexistentlang) = %s, want nil", lexer.Config().Name)
+	}
+}
+
+func TestLanguagesAddAlias(t *testing.T) {
+	t.Parallel()
+
+	l := NewLanguages()
+	if err := l.AddAliases(map[string]string{"Snek": "python", "snakey": "snek"}); err != nil {
+		t.Fatalf("AddAliases() returned error: %v", err)
+	}
+	if lexer := l.Lexer("snakey"); lexer == nil || lexer.Config().Name != "Python" {
+		t.Errorf("alias of an alias should resolve to Python, got %v", lexer)
+	}
+
+	err := l.AddAlias("foo", "nonexistentlang")
+	if err == nil || !strings.Contains(err.Error(), "unknown language") {
+		t.Errorf("AddAlias() to an unknown language error = %v", err)
+	}
+
+	if NewLanguages().Lexer("snakey") != nil {
+		t.Error("aliases should not leak between registries")
+	}
+}
+
+func TestLanguagesList(t *testing.T) {
+	t.Parallel()
+
+	l := NewLanguages()
+	if err := l.AddAlias("gomod", "go"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, lang := range l.List() {
+		if lang.Name != "Go" {
+			continue
+		}
+		for _, want := range []string{"go", "golang", "gomod"} {
+			if !contains(lang.Aliases, want) {
+				t.Errorf("Go aliases = %v, want %s included", lang.Aliases, want)
+			}
+		}
+		return
+	}
+	t.Error("List() should include Go")
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,419 @@
+package highlighter
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/dlclark/regexp2"
+	"gopkg.in/yaml.v3"
+
+	"github.com/codehakase/md/internal/xdg"
+)
+
+// lexerExtensions are the file formats accepted for custom lexers
+var lexerExtensions = []string{".xml", ".yaml", ".yml"}
+
+// lexerDefinition is a custom lexer decoded from a rule file
+type lexerDefinition struct {
+	config chroma.Config
+	states []stateDefinition
+}
+
+// stateDefinition is a named state of a lexer with its rules in order
+type stateDefinition struct {
+	name  string
+	rules []ruleDefinition
+}
+
+// ruleDefinition is a single lexer rule. A rule either includes another
+// state or matches a pattern; a match emits one token type, or one per
+// capture group when groups is set, then optionally pushes or pops states.
+type ruleDefinition struct {
+	pattern string
+	include string
+	tokens  []string
+	groups  bool
+	push    []string
+	pop     int
+}
+
+// LexerDir returns the directory custom lexers are loaded from
+func LexerDir() string {
+	return xdg.ConfigPath("lexers")
+}
+
+// RegisterLexers loads every lexer definition in dir and registers the
+// lexers with Chroma under their names and aliases. A missing directory is
+// not an error. Definitions that fail to load are skipped and reported
+// together in the error, while the valid ones are still registered. It
+// returns the names of the registered lexers.
+func RegisterLexers(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexer directory %s: %w", dir, err)
+	}
+
+	var names []string
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || !isLexerExtension(filepath.Ext(entry.Name())) {
+			continue
+		}
+		lexer, err := LoadLexerFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		lexers.Register(lexer)
+		names = append(names, lexer.Config().Name)
+	}
+	return names, errors.Join(errs...)
+}
+
+func isLexerExtension(ext string) bool {
+	for _, e := range lexerExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadLexerFile parses and validates a lexer definition in XML or YAML
+// format without registering it
+func LoadLexerFile(path string) (chroma.Lexer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lexer %s: %w", path, err)
+	}
+
+	lexer, err := ParseLexer(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("lexer %s: %w", path, err)
+	}
+	return lexer, nil
+}
+
+// ParseLexer parses a lexer definition in the format given by its file
+// extension (.xml, .yaml or .yml), checking every pattern and state reference
+func ParseLexer(data []byte, ext string) (chroma.Lexer, error) {
+	var (
+		def *lexerDefinition
+		err error
+	)
+	switch strings.ToLower(ext) {
+	case ".xml":
+		def, err = parseXMLLexer(data)
+	case ".yaml", ".yml":
+		def, err = parseYAMLLexer(data)
+	default:
+		return nil, fmt.Errorf("unsupported lexer format %q", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return def.build()
+}
+
+// build validates the definition and creates the lexer
+func (def *lexerDefinition) build() (chroma.Lexer, error) {
+	if def.config.Name == "" {
+		return nil, fmt.Errorf("missing lexer name")
+	}
+
+	known := map[string]bool{}
+	for _, state := range def.states {
+		if known[state.name] {
+			return nil, fmt.Errorf("state %s: defined twice", state.name)
+		}
+		known[state.name] = true
+	}
+	if !known["root"] {
+		return nil, fmt.Errorf("missing root state")
+	}
+
+	flags := regexp2.RegexOptions(regexp2.RE2)
+	if def.config.CaseInsensitive {
+		flags |= regexp2.IgnoreCase
+	}
+	if def.config.DotAll {
+		flags |= regexp2.Singleline
+	}
+	if !def.config.NotMultiline {
+		flags |= regexp2.Multiline
+	}
+
+	rules := chroma.Rules{}
+	for _, state := range def.states {
+		for i, rd := range state.rules {
+			rule, err := rd.build(known, flags)
+			if err != nil {
+				return nil, fmt.Errorf("state %s, rule %d: %w", state.name, i+1, err)
+			}
+			rules[state.name] = append(rules[state.name], rule)
+		}
+	}
+
+	config := def.config
+	return chroma.NewLazyLexer(&config, func() chroma.Rules { return rules })
+}
+
+// build validates a rule and converts it to a Chroma rule
+func (rd ruleDefinition) build(states map[string]bool, flags regexp2.RegexOptions) (chroma.Rule, error) {
+	if rd.include != "" {
+		if rd.pattern != "" || len(rd.tokens) > 0 {
+			return chroma.Rule{}, fmt.Errorf("include cannot be combined with a pattern or tokens")
+		}
+		if !states[rd.include] {
+			return chroma.Rule{}, fmt.Errorf("include of unknown state %q", rd.include)
+		}
+		return chroma.Include(rd.include), nil
+	}
+
+	if rd.pattern == "" {
+		return chroma.Rule{}, fmt.Errorf("missing pattern")
+	}
+	re, err := regexp2.Compile(rd.pattern, flags)
+	if err != nil {
+		return chroma.Rule{}, fmt.Errorf("invalid pattern %q: %v", rd.pattern, err)
+	}
+
+	types := make([]chroma.Emitter, 0, len(rd.tokens))
+	for _, name := range rd.tokens {
+		tokenType, err := parseTokenType(name)
+		if err != nil {
+			return chroma.Rule{}, err
+		}
+		types = append(types, tokenType)
+	}
+
+	rule := chroma.Rule{Pattern: rd.pattern}
+	switch {
+	case rd.groups:
+		// Capture group 0 is the whole match
+		if groups := len(re.GetGroupNumbers()) - 1; groups != len(types) {
+			return chroma.Rule{}, fmt.Errorf("pattern has %d groups but %d group tokens", groups, len(types))
+		}
+		rule.Type = chroma.ByGroups(types...)
+	case len(types) > 1:
+		return chroma.Rule{}, fmt.Errorf("%d tokens given; use groups to emit one per capture group", len(types))
+	case len(types) == 1:
+		rule.Type = types[0]
+	}
+
+	switch {
+	case len(rd.push) > 0 && rd.pop > 0:
+		return chroma.Rule{}, fmt.Errorf("push cannot be combined with pop")
+	case len(rd.push) > 0:
+		for _, state := range rd.push {
+			if !states[state] {
+				return chroma.Rule{}, fmt.Errorf("push of unknown state %q", state)
+			}
+		}
+		rule.Mutator = chroma.Push(rd.push...)
+	case rd.pop > 0:
+		rule.Mutator = chroma.Pop(rd.pop)
+	}
+	return rule, nil
+}
+
+// parseTokenType looks up a token type by the name Chroma prints for it,
+// such as "Keyword" or "LiteralStringDouble"
+func parseTokenType(name string) (chroma.TokenType, error) {
+	var tokenType chroma.TokenType
+	quoted, _ := json.Marshal(name)
+	if err := tokenType.UnmarshalJSON(quoted); err != nil {
+		return tokenType, fmt.Errorf("unknown token type %q", name)
+	}
+	return tokenType, nil
+}
+
+// xmlLexer is the XML lexer format, the same layout Chroma uses for its own
+// lexer definitions
+type xmlLexer struct {
+	XMLName xml.Name `xml:"lexer"`
+	Config  struct {
+		Name            string   `xml:"name"`
+		Aliases         []string `xml:"alias"`
+		Filenames       []string `xml:"filename"`
+		MimeTypes       []string `xml:"mime_type"`
+		CaseInsensitive bool     `xml:"case_insensitive"`
+		DotAll          bool     `xml:"dot_all"`
+		NotMultiline    bool     `xml:"not_multiline"`
+		EnsureNL        bool     `xml:"ensure_nl"`
+	} `xml:"config"`
+	States []struct {
+		Name  string `xml:"name,attr"`
+		Rules []struct {
+			Pattern string `xml:"pattern,attr"`
+			Token   *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"token"`
+			ByGroups *struct {
+				Tokens []struct {
+					Type string `xml:"type,attr"`
+				} `xml:"token"`
+			} `xml:"bygroups"`
+			Push []struct {
+				State string `xml:"state,attr"`
+			} `xml:"push"`
+			Pop *struct {
+				Depth int `xml:"depth,attr"`
+			} `xml:"pop"`
+			Include *struct {
+				State string `xml:"state,attr"`
+			} `xml:"include"`
+		} `xml:"rule"`
+	} `xml:"rules>state"`
+}
+
+func parseXMLLexer(data []byte) (*lexerDefinition, error) {
+	var doc xmlLexer
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid xml: %w", err)
+	}
+
+	def := &lexerDefinition{config: chroma.Config{
+		Name:            doc.Config.Name,
+		Aliases:         doc.Config.Aliases,
+		Filenames:       doc.Config.Filenames,
+		MimeTypes:       doc.Config.MimeTypes,
+		CaseInsensitive: doc.Config.CaseInsensitive,
+		DotAll:          doc.Config.DotAll,
+		NotMultiline:    doc.Config.NotMultiline,
+		EnsureNL:        doc.Config.EnsureNL,
+	}}
+
+	for _, s := range doc.States {
+		state := stateDefinition{name: s.Name}
+		for _, r := range s.Rules {
+			rule := ruleDefinition{pattern: r.Pattern}
+			if r.Token != nil {
+				rule.tokens = []string{r.Token.Type}
+			}
+			if r.ByGroups != nil {
+				rule.groups = true
+				for _, token := range r.ByGroups.Tokens {
+					rule.tokens = append(rule.tokens, token.Type)
+				}
+			}
+			for _, push := range r.Push {
+				rule.push = append(rule.push, push.State)
+			}
+			if r.Pop != nil {
+				rule.pop = r.Pop.Depth
+				if rule.pop == 0 {
+					rule.pop = 1
+				}
+			}
+			if r.Include != nil {
+				rule.include = r.Include.State
+			}
+			state.rules = append(state.rules, rule)
+		}
+		def.states = append(def.states, state)
+	}
+	return def, nil
+}
+
+// yamlLexer is the YAML lexer format
+type yamlLexer struct {
+	Name            string                `yaml:"name"`
+	Aliases         []string              `yaml:"aliases"`
+	Filenames       []string              `yaml:"filenames"`
+	MimeTypes       []string              `yaml:"mime_types"`
+	CaseInsensitive bool                  `yaml:"case_insensitive"`
+	DotAll          bool                  `yaml:"dot_all"`
+	NotMultiline    bool                  `yaml:"not_multiline"`
+	EnsureNL        bool                  `yaml:"ensure_nl"`
+	Rules           map[string][]yamlRule `yaml:"rules"`
+}
+
+type yamlRule struct {
+	Pattern string      `yaml:"pattern"`
+	Token   string      `yaml:"token"`
+	Groups  []string    `yaml:"groups"`
+	Push    stringOrSeq `yaml:"push"`
+	Pop     int         `yaml:"pop"`
+	Include string      `yaml:"include"`
+}
+
+// stringOrSeq accepts either a single string or a list of strings
+type stringOrSeq []string
+
+func (s *stringOrSeq) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*s = []string{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+func parseYAMLLexer(data []byte) (*lexerDefinition, error) {
+	var doc yamlLexer
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid yaml: %w", err)
+	}
+
+	def := &lexerDefinition{config: chroma.Config{
+		Name:            doc.Name,
+		Aliases:         doc.Aliases,
+		Filenames:       doc.Filenames,
+		MimeTypes:       doc.MimeTypes,
+		CaseInsensitive: doc.CaseInsensitive,
+		DotAll:          doc.DotAll,
+		NotMultiline:    doc.NotMultiline,
+		EnsureNL:        doc.EnsureNL,
+	}}
+
+	// YAML maps are unordered; sort states so errors are deterministic
+	names := make([]string, 0, len(doc.Rules))
+	for name := range doc.Rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		state := stateDefinition{name: name}
+		for _, r := range doc.Rules[name] {
+			rule := ruleDefinition{
+				pattern: r.Pattern,
+				include: r.Include,
+				push:    r.Push,
+				pop:     r.Pop,
+			}
+			if r.Token != "" {
+				rule.tokens = []string{r.Token}
+			}
+			if len(r.Groups) > 0 {
+				if r.Token != "" {
+					return nil, fmt.Errorf("state %s: token and groups cannot be combined", name)
+				}
+				rule.groups = true
+				rule.tokens = r.Groups
+			}
+			state.rules = append(state.rules, rule)
+		}
+		def.states = append(def.states, state)
+	}
+	return def, nil
+}
@@ -0,0 +1,205 @@
+package highlighter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/lexers"
+)
+
+const xmlLexerDefinition = `<lexer>
+  <config>
+    <name>Widget</name>
+    <alias>widget</alias>
+    <filename>*.widget</filename>
+  </config>
+  <rules>
+    <state name="root">
+      <rule pattern="#.*$"><token type="Comment"/></rule>
+      <rule pattern="\b(widget)(\s+)(\w+)"><bygroups><token type="Keyword"/><token type="Text"/><token type="NameClass"/></bygroups></rule>
+      <rule pattern="&quot;"><token type="LiteralString"/><push state="string"/></rule>
+      <rule><include state="common"/></rule>
+    </state>
+    <state name="string">
+      <rule pattern="[^&quot;]+"><token type="LiteralString"/></rule>
+      <rule pattern="&quot;"><token type="LiteralString"/><pop depth="1"/></rule>
+    </state>
+    <state name="common">
+      <rule pattern="\s+"><token type="Text"/></rule>
+      <rule pattern="\w+"><token type="Name"/></rule>
+    </state>
+  </rules>
+</lexer>
+`
+
+const yamlLexerDefinition = `
+name: Widget
+aliases: [widget]
+filenames: ["*.widget"]
+rules:
+  root:
+    - pattern: '#.*$'
+      token: Comment
+    - pattern: '\b(widget)(\s+)(\w+)'
+      groups: [Keyword, Text, NameClass]
+    - pattern: '"'
+      token: LiteralString
+      push: string
+    - include: common
+  string:
+    - pattern: '[^"]+'
+      token: LiteralString
+    - pattern: '"'
+      token: LiteralString
+      pop: 1
+  common:
+    - pattern: '\s+'
+      token: Text
+    - pattern: '\w+'
+      token: Name
+`
+
+func TestParseLexerFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ext  string
+		data string
+	}{
+		{".xml", xmlLexerDefinition},
+		{".yaml", yamlLexerDefinition},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.ext, func(t *testing.T) {
+			t.Parallel()
+
+			lexer, err := ParseLexer([]byte(tt.data), tt.ext)
+			if err != nil {
+				t.Fatalf("ParseLexer() returned error: %v", err)
+			}
+			if name := lexer.Config().Name; name != "Widget" {
+				t.Errorf("Name = %q, want Widget", name)
+			}
+
+			iterator, err := lexer.Tokenise(nil, "widget Button \"label\" # note\n")
+			if err != nil {
+				t.Fatalf("Tokenise() returned error: %v", err)
+			}
+
+			types := map[string]chroma.TokenType{}
+			for _, token := range iterator.Tokens() {
+				types[strings.TrimSpace(token.Value)] = token.Type
+			}
+			want := map[string]chroma.TokenType{
+				"widget": chroma.Keyword,
+				"Button": chroma.NameClass,
+				"label":  chroma.LiteralString,
+				"# note": chroma.Comment,
+			}
+			for value, tokenType := range want {
+				if types[value] != tokenType {
+					t.Errorf("token %q = %v, want %v", value, types[value], tokenType)
+				}
+			}
+		})
+	}
+}
+
+func TestParseLexerValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"invalid regex", "name: X\nrules:\n  root:\n    - pattern: '(unclosed'\n      token: Text\n", `state root, rule 1: invalid pattern "(unclosed"`},
+		{"unknown token", "name: X\nrules:\n  root:\n    - pattern: 'a'\n      token: Keywrd\n", `unknown token type "Keywrd"`},
+		{"unknown push", "name: X\nrules:\n  root:\n    - pattern: 'a'\n      push: nowhere\n", `push of unknown state "nowhere"`},
+		{"unknown include", "name: X\nrules:\n  root:\n    - include: nowhere\n", `include of unknown state "nowhere"`},
+		{"group count", "name: X\nrules:\n  root:\n    - pattern: '(a)(b)'\n      groups: [Text]\n", "pattern has 2 groups but 1 group tokens"},
+		{"missing root", "name: X\nrules:\n  main:\n    - pattern: 'a'\n", "missing root state"},
+		{"missing name", "rules:\n  root:\n    - pattern: 'a'\n", "missing lexer name"},
+		{"unknown field", "name: X\nrulez: {}\n", "field rulez not found"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseLexer([]byte(tt.data), ".yaml")
+			if err == nil {
+				t.Fatalf("ParseLexer() should fail with %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseLexer() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	_, err := ParseLexer([]byte(`<lexer><config><name>X</name></config><rules><state name="root"><rule pattern="[a-"><token type="Text"/></rule></state></rules></lexer>`), ".xml")
+	if err == nil || !strings.Contains(err.Error(), `invalid pattern "[a-"`) {
+		t.Errorf("ParseLexer() xml error = %v, want an invalid pattern error", err)
+	}
+}
+
+func TestRegisterLexers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	definition := strings.Replace(yamlLexerDefinition, "name: Widget\naliases: [widget]", "name: RegisteredWidget\naliases: [registered-widget]", 1)
+	if err := os.WriteFile(filepath.Join(dir, "widget.yaml"), []byte(definition), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := RegisterLexers(dir)
+	if err != nil {
+		t.Fatalf("RegisterLexers() returned error: %v", err)
+	}
+	if len(names) != 1 || names[0] != "RegisteredWidget" {
+		t.Errorf("RegisterLexers() = %v, want [RegisteredWidget]", names)
+	}
+	if lexer := lexers.Get("registered-widget"); lexer == nil || lexer.Config().Name != "RegisteredWidget" {
+		t.Error("registered lexer should be found by its alias")
+	}
+	if lexer := NewLanguages().Lexer("registered-widget"); lexer == nil {
+		t.Error("registered lexer should be resolved by the language registry")
+	}
+	listed := false
+	for _, lang := range NewLanguages().List() {
+		listed = listed || lang.Name == "RegisteredWidget"
+	}
+	if !listed {
+		t.Error("registered lexer should be listed")
+	}
+
+	if names, err := RegisterLexers(filepath.Join(dir, "missing")); err != nil || names != nil {
+		t.Errorf("RegisterLexers() on a missing directory = %v, %v; want nothing", names, err)
+	}
+
+	bad := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bad, "bad.xml"), []byte("<lexer>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	valid := strings.Replace(yamlLexerDefinition, "name: Widget\naliases: [widget]", "name: SurvivingWidget\naliases: [surviving-widget]", 1)
+	if err := os.WriteFile(filepath.Join(bad, "good.yaml"), []byte(valid), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	names, err = RegisterLexers(bad)
+	if err == nil || !strings.Contains(err.Error(), "bad.xml") {
+		t.Errorf("RegisterLexers() error = %v, want it to name bad.xml", err)
+	}
+	// The broken definition doesn't keep the others from loading
+	if len(names) != 1 || names[0] != "SurvivingWidget" || lexers.Get("surviving-widget") == nil {
+		t.Errorf("RegisterLexers() = %v, want the valid lexer registered alongside the error", names)
+	}
+}
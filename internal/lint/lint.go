@@ -0,0 +1,324 @@
+// Package lint checks markdown documents for structural problems and broken
+// links, using the same parser configuration as the renderer
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Rule names, as shown in reports
+const (
+	RuleHeadingIncrement = "heading-increment"
+	RuleDuplicateAnchor  = "duplicate-anchor"
+	RuleEmptyLink        = "empty-link"
+	RuleImageAlt         = "image-alt"
+	RuleMissingFile      = "missing-file"
+	RuleMissingAnchor    = "missing-anchor"
+)
+
+// Issue is a problem found in a document
+type Issue struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// String formats the issue as file:line:col: message (rule)
+func (i Issue) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", i.File, i.Line, i.Column, i.Message, i.Rule)
+}
+
+// markdownExtensions are the file extensions whose anchors are checked
+var markdownExtensions = map[string]bool{".md": true, ".markdown": true, ".mdown": true, ".mkd": true}
+
+// Linter checks documents. It caches the anchors of every file it parses, so
+// links between the documents of a directory are only parsed once.
+type Linter struct {
+	renderer *renderer.Renderer
+	anchors  map[string]map[string]bool
+}
+
+// New creates a linter that parses documents like r does
+func New(r *renderer.Renderer) *Linter {
+	return &Linter{
+		renderer: r,
+		anchors:  map[string]map[string]bool{},
+	}
+}
+
+// LintFile reads and checks a markdown file
+func (l *Linter) LintFile(path string) ([]Issue, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return l.Lint(path, source), nil
+}
+
+// Lint checks source, the content of the file at path. Relative links are
+// resolved against the directory of path.
+func (l *Linter) Lint(path string, source []byte) []Issue {
+	c := &checker{
+		linter: l,
+		path:   path,
+		source: source,
+	}
+	doc := l.renderer.Parse(source)
+	l.anchors[absPath(path)] = collectAnchors(doc)
+
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := node.(type) {
+		case *ast.Heading:
+			c.checkHeading(n)
+		case *ast.Link:
+			c.checkLink(n, string(n.Destination))
+		case *ast.Image:
+			c.checkImage(n)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		if c.issues[i].Line != c.issues[j].Line {
+			return c.issues[i].Line < c.issues[j].Line
+		}
+		return c.issues[i].Column < c.issues[j].Column
+	})
+	return c.issues
+}
+
+// anchorsOf returns the heading anchors of a markdown file, or nil if it
+// can't be read
+func (l *Linter) anchorsOf(path string) map[string]bool {
+	path = absPath(path)
+	if anchors, ok := l.anchors[path]; ok {
+		return anchors
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	anchors := collectAnchors(l.renderer.Parse(source))
+	l.anchors[path] = anchors
+	return anchors
+}
+
+// collectAnchors returns the ids of every heading in doc
+func collectAnchors(doc ast.Node) map[string]bool {
+	anchors := map[string]bool{}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if heading, ok := node.(*ast.Heading); ok && entering {
+			if anchor := headingAnchor(heading); anchor != "" {
+				anchors[anchor] = true
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return anchors
+}
+
+// headingAnchor returns the id the parser gave a heading
+func headingAnchor(heading *ast.Heading) string {
+	if id, ok := heading.AttributeString("id"); ok {
+		if b, ok := id.([]byte); ok {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// checker holds the state of checking one document
+type checker struct {
+	linter *Linter
+	path   string
+	source []byte
+	issues []Issue
+
+	lastLevel int
+	// anchors maps the anchor each heading's text produces on its own to the
+	// line of the first heading producing it
+	anchors map[string]int
+}
+
+func (c *checker) report(offset int, rule, format string, args ...interface{}) {
+	line, column := c.lineColumn(offset)
+	c.issues = append(c.issues, Issue{
+		File:    c.path,
+		Line:    line,
+		Column:  column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// lineColumn converts a byte offset to a 1-based line and column
+func (c *checker) lineColumn(offset int) (int, int) {
+	offset = min(max(offset, 0), len(c.source))
+	start := bytes.LastIndexByte(c.source[:offset], '\n') + 1
+	return bytes.Count(c.source[:start], []byte("\n")) + 1, utf8.RuneCount(c.source[start:offset]) + 1
+}
+
+func (c *checker) checkHeading(n *ast.Heading) {
+	if n.Lines().Len() == 0 {
+		return
+	}
+	last := n.Lines().At(n.Lines().Len() - 1)
+	offset := lineStartOffset(c.source, n.Lines().At(0).Start)
+
+	if c.lastLevel > 0 && n.Level > c.lastLevel+1 {
+		c.report(offset, RuleHeadingIncrement, "heading level jumps from h%d to h%d", c.lastLevel, n.Level)
+	}
+	c.lastLevel = n.Level
+
+	// Auto heading IDs make repeated anchors unique with a numeric suffix,
+	// so compare the anchor each heading would get on its own
+	base := string(parser.NewContext().IDs().Generate(last.Value(c.source), ast.KindHeading))
+	if c.anchors == nil {
+		c.anchors = map[string]int{}
+	}
+	if line, ok := c.anchors[base]; ok {
+		c.report(offset, RuleDuplicateAnchor, "duplicate heading anchor #%s (first used on line %d); this heading is #%s", base, line, headingAnchor(n))
+		return
+	}
+	c.anchors[base], _ = c.lineColumn(offset)
+}
+
+func (c *checker) checkLink(n ast.Node, destination string) {
+	offset := c.offset(n, destination)
+	if strings.TrimSpace(destination) == "" {
+		c.report(offset, RuleEmptyLink, "link has no destination")
+		return
+	}
+	if _, isImage := n.(*ast.Image); !isImage && strings.TrimSpace(string(n.Text(c.source))) == "" && !hasImage(n) {
+		c.report(offset, RuleEmptyLink, "link to %s has no text", destination)
+	}
+	c.checkTarget(offset, destination)
+}
+
+func (c *checker) checkImage(n *ast.Image) {
+	destination := string(n.Destination)
+	if strings.TrimSpace(string(n.Text(c.source))) == "" {
+		c.report(c.offset(n, destination), RuleImageAlt, "image %s has no alt text", destination)
+	}
+	c.checkLink(n, destination)
+}
+
+// checkTarget checks that a relative link points to an existing file and,
+// for markdown files, that its fragment names a heading
+func (c *checker) checkTarget(offset int, destination string) {
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.HasPrefix(destination, "/") {
+		// External links, and site-absolute paths whose root is unknown
+		return
+	}
+
+	target := c.path
+	if u.Path != "" {
+		target = filepath.Join(filepath.Dir(c.path), filepath.FromSlash(u.Path))
+		info, err := os.Stat(target)
+		if err != nil {
+			c.report(offset, RuleMissingFile, "link to missing file %s", u.Path)
+			return
+		}
+		if info.IsDir() {
+			return
+		}
+	}
+
+	if u.Fragment == "" || !markdownExtensions[strings.ToLower(filepath.Ext(target))] {
+		return
+	}
+	if anchors := c.linter.anchorsOf(target); anchors != nil && !anchors[u.Fragment] {
+		if u.Path == "" {
+			c.report(offset, RuleMissingAnchor, "no heading with anchor #%s", u.Fragment)
+		} else {
+			c.report(offset, RuleMissingAnchor, "no heading with anchor #%s in %s", u.Fragment, u.Path)
+		}
+	}
+}
+
+// offset returns the source offset of an inline link or image. Inline nodes
+// carry no position of their own, so it is taken from their text, or found
+// by searching the enclosing block for the destination.
+func (c *checker) offset(n ast.Node, destination string) int {
+	opening := 1
+	if _, ok := n.(*ast.Image); ok {
+		opening = 2
+	}
+	if start := textStart(n); start >= 0 {
+		return start - opening
+	}
+
+	block := n.Parent()
+	for block != nil && block.Type() != ast.TypeBlock {
+		block = block.Parent()
+	}
+	if block == nil || block.Lines().Len() == 0 {
+		return 0
+	}
+	start := block.Lines().At(0).Start
+	end := block.Lines().At(block.Lines().Len() - 1).Stop
+	if i := bytes.Index(c.source[start:end], []byte("]("+destination)); i >= 0 {
+		if i > 0 && c.source[start+i-1] == '[' {
+			i--
+			if i > 0 && c.source[start+i-1] == '!' {
+				i--
+			}
+		}
+		return start + i
+	}
+	return start
+}
+
+// textStart returns the offset of the first text inside n, or -1
+func textStart(n ast.Node) int {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if text, ok := child.(*ast.Text); ok {
+			return text.Segment.Start
+		}
+		if start := textStart(child); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
+
+// hasImage reports whether a link wraps an image, as in [![badge](...)](...)
+func hasImage(n ast.Node) bool {
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if _, ok := child.(*ast.Image); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lineStartOffset returns the offset of the beginning of the line containing offset
+func lineStartOffset(source []byte, offset int) int {
+	return bytes.LastIndexByte(source[:min(offset, len(source))], '\n') + 1
+}
+
+// absPath returns the absolute form of path, or path itself on failure
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
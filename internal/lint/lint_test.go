@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newLinter() *Linter {
+	return New(renderer.New(theme.NewWithBackground(theme.BackgroundDark)))
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "guide", "install.md"), "# Install\n\n## Linux\n")
+	writeFile(t, filepath.Join(dir, "logo.png"), "")
+	doc := filepath.Join(dir, "README.md")
+	writeFile(t, doc, `# Title
+
+### Skipped
+
+## Usage
+
+## Usage
+
+See [usage](#usage), [linux](guide/install.md#linux) and [site](https://example.com/#x).
+Broken: [here](#nowhere), [there](guide/install.md#windows), [gone](missing.md).
+Empty: [](logo.png) and [text]().
+
+![](logo.png) ![logo](logo.png) [![badge](logo.png)](https://example.com)
+`)
+
+	issues, err := newLinter().LintFile(doc)
+	if err != nil {
+		t.Fatalf("LintFile() returned error: %v", err)
+	}
+
+	want := []string{
+		"3:1 heading-increment",
+		"7:1 duplicate-anchor",
+		"10:9 missing-anchor",
+		"10:27 missing-anchor",
+		"10:62 missing-file",
+		"11:8 empty-link",
+		"11:25 empty-link",
+		"13:1 image-alt",
+	}
+	var got []string
+	for _, issue := range issues {
+		if issue.File != doc {
+			t.Errorf("issue %v reported for the wrong file", issue)
+		}
+		got = append(got, fmt.Sprintf("%d:%d %s", issue.Line, issue.Column, issue.Rule))
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintClean(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "a.md")
+	writeFile(t, doc, "# A\n\n## B\n\n### C\n\n## D\n\nSee [C](#c) and [b](b.md#top).\n")
+	writeFile(t, filepath.Join(dir, "b.md"), "# Top\n")
+
+	issues, err := newLinter().LintFile(doc)
+	if err != nil {
+		t.Fatalf("LintFile() returned error: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Errorf("Lint() = %v, want no issues", issues)
+	}
+}
+
+func TestIssueString(t *testing.T) {
+	t.Parallel()
+
+	issue := Issue{File: "docs/a.md", Line: 3, Column: 7, Rule: RuleEmptyLink, Message: "link has no destination"}
+	if got, want := issue.String(), "docs/a.md:3:7: link has no destination (empty-link)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
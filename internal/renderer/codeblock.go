@@ -0,0 +1,372 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+// codeAttributes are the decorations of a single code block
+type codeAttributes struct {
+	lineNumbers bool
+	lineStart   int
+	frame       bool
+	background  bool
+	// highlight holds the emphasized lines, counted from 1 within the block
+	highlight map[int]bool
+	title     string
+}
+
+// parseCodeInfo splits the info string of a fenced code block into the
+// language and its attributes. It understands the forms used by common
+// documentation generators:
+//
+//	go {linenos=true}
+//	go {3,5-7} title="main.go"
+//	js hl_lines="2 4"
+//	js{4}
+//
+// Bare line numbers and ranges are collected under "hl_lines".
+func parseCodeInfo(info string) (string, map[string]string) {
+	attrs := map[string]string{}
+	fields := splitAttributes(strings.TrimSpace(info), unicode.IsSpace)
+	if len(fields) == 0 {
+		return "", attrs
+	}
+
+	language := ""
+	if first := fields[0]; !strings.HasPrefix(first, "{") && !strings.Contains(first, "=") {
+		language = first
+		fields = fields[1:]
+		if open := strings.Index(language, "{"); open > 0 {
+			fields = append([]string{language[open:]}, fields...)
+			language = language[:open]
+		}
+	}
+
+	var lines []string
+	add := func(field string) {
+		key, value, ok := strings.Cut(field, "=")
+		switch {
+		case ok:
+			attrs[strings.ToLower(key)] = unquote(value)
+		case lineRangePattern.MatchString(field):
+			lines = append(lines, field)
+		case field != "":
+			// Flags such as showLineNumbers
+			attrs[strings.ToLower(field)] = "true"
+		}
+	}
+	for _, field := range fields {
+		if strings.HasPrefix(field, "{") {
+			body := strings.TrimSuffix(strings.TrimPrefix(field, "{"), "}")
+			for _, item := range splitAttributes(body, func(r rune) bool { return r == ',' || unicode.IsSpace(r) }) {
+				add(item)
+			}
+			continue
+		}
+		add(field)
+	}
+
+	if len(lines) > 0 {
+		attrs["hl_lines"] = strings.TrimSpace(attrs["hl_lines"] + " " + strings.Join(lines, " "))
+	}
+	return language, attrs
+}
+
+// lineRangePattern matches a line number or a range of lines such as "5-7"
+var lineRangePattern = regexp.MustCompile(`^\d+(-\d+)?$`)
+
+// splitAttributes splits s at runes matched by sep, except inside quotes,
+// braces or brackets
+func splitAttributes(s string, sep func(rune) bool) []string {
+	var fields []string
+	var field strings.Builder
+	var quote rune
+	depth := 0
+
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '{' || r == '[':
+			depth++
+		case (r == '}' || r == ']') && depth > 0:
+			depth--
+		case depth == 0 && sep(r):
+			if field.Len() > 0 {
+				fields = append(fields, field.String())
+				field.Reset()
+			}
+			continue
+		}
+		field.WriteRune(r)
+	}
+	if field.Len() > 0 {
+		fields = append(fields, field.String())
+	}
+	return fields
+}
+
+// unquote removes the quotes around an attribute value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// parseLineRanges parses line numbers and ranges separated by spaces or
+// commas, as in "2 4-6" or Hugo's [2, "4-6"]
+func parseLineRanges(value string) map[int]bool {
+	lines := map[int]bool{}
+	items := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == '[' || r == ']' || r == '"' || r == '\'' || unicode.IsSpace(r)
+	})
+	for _, item := range items {
+		from, to, isRange := strings.Cut(item, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			continue
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				continue
+			}
+		}
+		// Guard against ranges such as 1-999999999 making a huge map
+		for line := first; line <= last && line-first < maxHighlightedLines; line++ {
+			lines[line] = true
+		}
+	}
+	return lines
+}
+
+// maxHighlightedLines bounds the length of a single highlighted range
+const maxHighlightedLines = 10000
+
+// codeAttributes returns the decorations configured in the options,
+// overridden by the attributes of a fenced code block
+func (tr *terminalRenderer) codeAttributes(attrs map[string]string) codeAttributes {
+	a := codeAttributes{
+		lineNumbers: tr.options.LineNumbers,
+		lineStart:   1,
+		frame:       tr.options.CodeFrame,
+		background:  tr.options.CodeBackground,
+		highlight:   parseLineRanges(attrs["hl_lines"]),
+		title:       attrs["title"],
+	}
+
+	// Invalid values are ignored rather than failing the whole document
+	if value, ok := attrs["linenos"]; ok {
+		a.lineNumbers = parseAttributeBool(value, a.lineNumbers)
+	}
+	if value, ok := attrs["showlinenumbers"]; ok {
+		a.lineNumbers = parseAttributeBool(value, a.lineNumbers)
+	}
+	if value, ok := attrs["linenostart"]; ok {
+		if start, err := strconv.Atoi(value); err == nil && start >= 0 {
+			a.lineStart = start
+		}
+	}
+	if value, ok := attrs["frame"]; ok {
+		a.frame = parseAttributeBool(value, a.frame)
+	}
+	if value, ok := attrs["background"]; ok {
+		a.background = parseAttributeBool(value, a.background)
+	}
+	return a
+}
+
+// parseAttributeBool parses a boolean attribute, accepting Hugo's "table"
+// and "inline" line number styles as true
+func parseAttributeBool(value string, fallback bool) bool {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1", "table", "inline":
+		return true
+	case "false", "no", "off", "0":
+		return false
+	default:
+		return fallback
+	}
+}
+
+// writeCodeBlock writes highlighted code indented under the current block,
+// with the line numbers, frame, background and emphasized lines selected by
+// attrs. The frame's top border is labelled with the title, or with label if
+// there is none; without a frame the title is shown as a caption.
+func (tr *terminalRenderer) writeCodeBlock(w io.Writer, highlighted, label string, attrs codeAttributes) {
+	if attrs.title != "" {
+		label = attrs.title
+		if !attrs.frame {
+			fmt.Fprint(w, Indent(tr.themeManager.Style(attrs.title, theme.TableHeader), 1)+"\n")
+		}
+	}
+
+	code := strings.TrimRight(highlighted, "\n")
+	if !attrs.lineNumbers && !attrs.frame && !attrs.background && len(attrs.highlight) == 0 {
+		fmt.Fprint(w, Indent(code, 1))
+		return
+	}
+
+	lines := splitStyledLines(code)
+	// Highlighters may leave the final newline inside a styled token
+	for len(lines) > 1 && StripANSI(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	width := 0
+	for i, line := range lines {
+		// Tabs would make the visible width of a line unpredictable
+		lines[i] = strings.ReplaceAll(line, "\t", "    ")
+		width = max(width, VisibleWidth(lines[i]))
+	}
+
+	background := ""
+	if attrs.background {
+		background = tr.themeManager.CodeBackground()
+	}
+	padding := ""
+	if attrs.frame || background != "" {
+		padding = " "
+	}
+
+	// Without colors emphasized lines can't be shaded, so they are marked
+	markLines := len(attrs.highlight) > 0 && tr.themeManager.CodeHighlight() == ""
+	digits := len(strconv.Itoa(attrs.lineStart + len(lines) - 1))
+	gutter := func(i int) string {
+		var g string
+		if markLines {
+			g = "  "
+			if attrs.highlight[i+1] {
+				g = "▶ "
+			}
+		}
+		if attrs.lineNumbers {
+			number := fmt.Sprintf("%*d │", digits, attrs.lineStart+i)
+			if attrs.frame && !markLines {
+				number = " " + number
+			}
+			if padding == "" {
+				number += " "
+			}
+			g += number
+		}
+		return g
+	}
+	gutterWidth := VisibleWidth(gutter(0))
+
+	// The inner width of the frame, between its borders
+	inner := func() int { return gutterWidth + 2*len(padding) + width }
+	if padding != "" && tr.options.Width > 0 {
+		borders := 0
+		if attrs.frame {
+			borders = 2
+		}
+		width = max(width, tr.options.Width-2-borders-(inner()-width))
+	}
+	if attrs.frame && label != "" {
+		width = max(width, VisibleWidth(label)+4-(inner()-width))
+	}
+
+	border := func(text string) string {
+		return tr.themeManager.Style(text, theme.TableBorder)
+	}
+
+	var rows []string
+	if attrs.frame {
+		top := border("╭" + strings.Repeat("─", inner()) + "╮")
+		if label != "" {
+			rest := inner() - VisibleWidth(label) - 3
+			top = border("╭─ ") + tr.themeManager.Style(label, theme.TableHeader) + border(" "+strings.Repeat("─", rest)+"╮")
+		}
+		rows = append(rows, top)
+	}
+
+	for i, line := range lines {
+		var row strings.Builder
+		if attrs.frame {
+			row.WriteString(border("│"))
+		}
+		if gutterWidth > 0 {
+			row.WriteString(border(gutter(i)))
+		}
+
+		fill := strings.Repeat(" ", width-VisibleWidth(line))
+		rowBackground := background
+		if attrs.highlight[i+1] {
+			rowBackground = tr.themeManager.CodeHighlight()
+		}
+		if rowBackground != "" {
+			// Highlighted tokens reset all styles when they end
+			line = strings.ReplaceAll(line, Reset, Reset+rowBackground)
+			row.WriteString(rowBackground + padding + line + fill + padding + Reset)
+		} else {
+			row.WriteString(padding + line + fill + padding)
+		}
+
+		if attrs.frame {
+			row.WriteString(border("│"))
+		}
+		rows = append(rows, row.String())
+	}
+
+	if attrs.frame {
+		rows = append(rows, border("╰"+strings.Repeat("─", inner())+"╯"))
+	}
+
+	fmt.Fprint(w, Indent(strings.Join(rows, "\n"), 1))
+}
+
+// splitStyledLines splits highlighted text into lines that each end with
+// their styles reset, reapplying the styles still active at the start of the
+// next line so that decorations added around a line are never colored by it
+func splitStyledLines(text string) []string {
+	var lines []string
+	var line strings.Builder
+	active := ""
+
+	plain := func(segment string) {
+		for {
+			i := strings.IndexByte(segment, '\n')
+			if i < 0 {
+				line.WriteString(segment)
+				return
+			}
+			line.WriteString(segment[:i])
+			if active != "" {
+				line.WriteString(Reset)
+			}
+			lines = append(lines, line.String())
+			line.Reset()
+			line.WriteString(active)
+			segment = segment[i+1:]
+		}
+	}
+
+	last := 0
+	for _, loc := range ansiPattern.FindAllStringIndex(text, -1) {
+		plain(text[last:loc[0]])
+		sequence := text[loc[0]:loc[1]]
+		switch {
+		case sequence == Reset || sequence == "\033[m":
+			active = ""
+		case strings.HasSuffix(sequence, "m"):
+			active += sequence
+		}
+		line.WriteString(sequence)
+		last = loc[1]
+	}
+	plain(text[last:])
+
+	return append(lines, line.String())
+}
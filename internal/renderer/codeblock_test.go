@@ -0,0 +1,216 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestRenderCodeBlock(t *testing.T) {
+	t.Parallel()
+
+	const program = "```go\nfunc main() {\n\treturn\n}\n```\n"
+	tests := []struct {
+		name    string
+		options Options
+		source  string
+		want    string
+	}{
+		{
+			name:   "plain",
+			source: program,
+			want:   "\n  func main() {\n  \treturn\n  }\n",
+		},
+		{
+			name:    "line numbers expand tabs",
+			options: Options{LineNumbers: true},
+			source:  program,
+			want:    "\n  1 │ func main() {\n  2 │     return\n  3 │ }\n",
+		},
+		{
+			name:   "linenostart widens the gutter",
+			source: "```go {linenos=true linenostart=98}\na\nb\nc\n```\n",
+			want:   "\n   98 │ a\n   99 │ b\n  100 │ c\n",
+		},
+		{
+			name:    "linenos=false overrides the option",
+			options: Options{LineNumbers: true},
+			source:  "```go {linenos=false}\na\n```\n",
+			want:    "\n  a\n",
+		},
+		{
+			name:    "frame",
+			options: Options{CodeFrame: true},
+			source:  program,
+			want:    "\n  ╭─ go ──────────╮\n  │ func main() { │\n  │     return    │\n  │ }             │\n  ╰───────────────╯\n",
+		},
+		{
+			name:    "frame fills the width",
+			options: Options{CodeFrame: true, Width: 30},
+			source:  "```go\nx\n```\n",
+			want:    "\n  ╭─ go ─────────────────────╮\n  │ x                        │\n  ╰──────────────────────────╯\n",
+		},
+		{
+			name:    "frame with line numbers and a title",
+			options: Options{CodeFrame: true, LineNumbers: true},
+			source:  "```go title=\"main.go\"\nx\n```\n",
+			want:    "\n  ╭─ main.go ─╮\n  │ 1 │ x     │\n  ╰───────────╯\n",
+		},
+		{
+			name:   "title without a frame is a caption",
+			source: "```go title=\"main.go\"\nx\n```\n",
+			want:   "\n  main.go\n  x\n",
+		},
+		{
+			name:   "emphasized lines are marked without colors",
+			source: "```go {hl_lines=[2,\"4-5\"]}\na\nb\nc\nd\ne\n```\n",
+			want:   "\n    a\n  ▶ b\n    c\n  ▶ d\n  ▶ e\n",
+		},
+		{
+			name:    "marks go before line numbers",
+			options: Options{CodeFrame: true, LineNumbers: true},
+			source:  "```go {2}\na\nb\n```\n",
+			want:    "\n  ╭─ go ───╮\n  │  1 │ a │\n  │▶ 2 │ b │\n  ╰────────╯\n",
+		},
+		{
+			name:    "wide title",
+			options: Options{CodeFrame: true},
+			source:  "```go title=\"日本語タイトル\"\nx\n```\n",
+			want:    "\n  ╭─ 日本語タイトル ─╮\n  │ x                │\n  ╰──────────────────╯\n",
+		},
+		{
+			// Without colors there is no background to fill
+			name:    "background",
+			options: Options{CodeBackground: true},
+			source:  "```go\nx := 1\nlonger line\n```\n",
+			want:    "\n  x := 1\n  longer line\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := render(t, tt.options, tt.source); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCodeBackground(t *testing.T) {
+	t.Parallel()
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileANSI256)
+	r := NewWithOptions(tm, Options{CodeBackground: true, Width: 24})
+	out, err := r.RenderContent([]byte("```\nx := 1\n\tlonger line\n```\n"), plainHighlighter{})
+	if err != nil {
+		t.Fatalf("RenderContent() returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.Trim(out, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("render() = %q, want two lines", out)
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, tm.CodeBackground()) {
+			t.Errorf("line %q is not filled with the code background", line)
+		}
+		// The background fills the block out to the width, less the indent
+		if got := VisibleWidth(line); got != 24 {
+			t.Errorf("line %q is %d columns wide, want 24", StripANSI(line), got)
+		}
+	}
+}
+
+func TestCodeAttributes(t *testing.T) {
+	t.Parallel()
+
+	tr := &terminalRenderer{options: Options{LineNumbers: true, CodeFrame: true}}
+	tests := []struct {
+		name  string
+		attrs map[string]string
+		want  codeAttributes
+	}{
+		{"options", nil, codeAttributes{lineNumbers: true, lineStart: 1, frame: true, highlight: map[int]bool{}}},
+		{
+			name:  "overrides",
+			attrs: map[string]string{"linenos": "false", "frame": "off", "background": "yes", "linenostart": "5", "title": "x.go"},
+			want:  codeAttributes{lineStart: 5, background: true, highlight: map[int]bool{}, title: "x.go"},
+		},
+		{
+			name:  "invalid values are ignored",
+			attrs: map[string]string{"linenos": "maybe", "linenostart": "-3"},
+			want:  codeAttributes{lineNumbers: true, lineStart: 1, frame: true, highlight: map[int]bool{}},
+		},
+		{
+			name:  "hugo line number styles",
+			attrs: map[string]string{"linenos": "table", "hl_lines": "1 3-4"},
+			want:  codeAttributes{lineNumbers: true, lineStart: 1, frame: true, highlight: map[int]bool{1: true, 3: true, 4: true}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := tr.codeAttributes(tt.attrs)
+			if got.lineNumbers != tt.want.lineNumbers || got.lineStart != tt.want.lineStart || got.frame != tt.want.frame ||
+				got.background != tt.want.background || got.title != tt.want.title || len(got.highlight) != len(tt.want.highlight) {
+				t.Errorf("codeAttributes(%v) = %+v, want %+v", tt.attrs, got, tt.want)
+			}
+			for line := range tt.want.highlight {
+				if !got.highlight[line] {
+					t.Errorf("codeAttributes(%v) doesn't highlight line %d", tt.attrs, line)
+				}
+			}
+		})
+	}
+}
+
+func TestParseCodeInfo(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		info     string
+		language string
+		attrs    map[string]string
+	}{
+		{"", "", map[string]string{}},
+		{"go", "go", map[string]string{}},
+		{"go {linenos=true}", "go", map[string]string{"linenos": "true"}},
+		{`go {3,5-7} title="main.go"`, "go", map[string]string{"hl_lines": "3 5-7", "title": "main.go"}},
+		{`js hl_lines="2 4"`, "js", map[string]string{"hl_lines": "2 4"}},
+		{"js{4}", "js", map[string]string{"hl_lines": "4"}},
+		{`go {hl_lines=[2,"4-5"] linenos=table}`, "go", map[string]string{"hl_lines": `[2,"4-5"]`, "linenos": "table"}},
+		{`go {hl_lines=[2, 4] showLineNumbers}`, "go", map[string]string{"hl_lines": "[2, 4]", "showlinenumbers": "true"}},
+		{`{title="a b" frame}`, "", map[string]string{"title": "a b", "frame": "true"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.info, func(t *testing.T) {
+			t.Parallel()
+
+			language, attrs := parseCodeInfo(tt.info)
+			if language != tt.language || !reflect.DeepEqual(attrs, tt.attrs) {
+				t.Errorf("parseCodeInfo(%q) = %q, %q, want %q, %q", tt.info, language, attrs, tt.language, tt.attrs)
+			}
+		})
+	}
+}
+
+func TestParseLineRanges(t *testing.T) {
+	t.Parallel()
+
+	got := parseLineRanges(`[2,"4-5"] 7 x 9-8`)
+	want := map[int]bool{2: true, 4: true, 5: true, 7: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLineRanges() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package renderer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// regionMarker matches the comment lines that delimit a named region of a
+// source file, such as "// region:setup" and "// endregion:setup", in any
+// comment syntax
+var regionMarker = regexp.MustCompile(`\b(end)?region:\s*([\w.-]*)`)
+
+// embedSnippet returns the code a fenced block's file attribute refers to,
+// narrowed by its lines or region attribute. Relative paths are resolved
+// against the directory of the markdown file.
+func (tr *terminalRenderer) embedSnippet(attrs map[string]string) (string, error) {
+	name := attrs["file"]
+	path, err := tr.embedPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	data, err := readEmbedded(path, tr.options.Limits.MaxInputSize)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), "\n")
+
+	if region := attrs["region"]; region != "" {
+		if lines, err = extractRegion(lines, region); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+	} else if spec := attrs["lines"]; spec != "" {
+		if lines, err = extractLines(lines, spec); err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+		// Number the lines as they are numbered in the file
+		if _, ok := attrs["linenostart"]; !ok {
+			from, _, _ := strings.Cut(spec, "-")
+			attrs["linenostart"] = strings.TrimSpace(from)
+		}
+	}
+
+	return tr.sanitize(strings.Join(dedent(lines), "\n") + "\n"), nil
+}
+
+// embedPath resolves the path of an embedded file, following symbolic links.
+// The file must be inside the embed root, so a document can only show files
+// of its own project and not, say, ../../.ssh/id_rsa.
+func (tr *terminalRenderer) embedPath(name string) (string, error) {
+	root := tr.options.EmbedRoot
+	if root == "" {
+		root = tr.baseDir
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(tr.baseDir, path)
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Whether files outside the root exist is none of the document's business
+	if !within(root, path) {
+		return "", fmt.Errorf("outside of %s, not embedded", root)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("file not found")
+	}
+	if err != nil {
+		return "", err
+	}
+	if resolvedRoot, err := filepath.EvalSymlinks(root); err != nil || !within(resolvedRoot, resolved) {
+		return "", fmt.Errorf("outside of %s, not embedded", root)
+	}
+	return resolved, nil
+}
+
+// within reports whether path is dir or inside it
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// readEmbedded reads a regular file of at most limit bytes; 0 is unlimited.
+// Devices and pipes are refused, since reading them may never end.
+func readEmbedded(path string, limit int) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("not a regular file")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if limit > 0 {
+		r = io.LimitReader(f, int64(limit)+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(data) > limit {
+		return nil, fmt.Errorf("%w: the file is larger than %d bytes (max-input-size)", ErrLimitExceeded, limit)
+	}
+	return data, nil
+}
+
+// extractRegion returns the lines between the markers of a named region,
+// leaving out the markers of any regions nested inside it
+func extractRegion(lines []string, region string) ([]string, error) {
+	start := -1
+	for i, line := range lines {
+		match := regionMarker.FindStringSubmatch(line)
+		if match == nil || match[2] != region {
+			continue
+		}
+		if match[1] == "" && start < 0 {
+			start = i + 1
+		} else if match[1] != "" && start >= 0 {
+			return withoutMarkers(lines[start:i]), nil
+		}
+	}
+
+	if start < 0 {
+		return nil, fmt.Errorf("region %q not found", region)
+	}
+	return nil, fmt.Errorf("region %q has no endregion:%s marker", region, region)
+}
+
+// withoutMarkers drops region marker lines
+func withoutMarkers(lines []string) []string {
+	var kept []string
+	for _, line := range lines {
+		if !regionMarker.MatchString(line) {
+			kept = append(kept, line)
+		}
+	}
+	return kept
+}
+
+// extractLines returns the lines selected by a 1-based inclusive range such
+// as "10-40", "10-" (to the end of the file) or "10"
+func extractLines(lines []string, spec string) ([]string, error) {
+	from, to, isRange := strings.Cut(spec, "-")
+	first, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || first < 1 {
+		return nil, fmt.Errorf("invalid line range %q", spec)
+	}
+
+	last := first
+	if isRange {
+		last = len(lines)
+		if to = strings.TrimSpace(to); to != "" {
+			if last, err = strconv.Atoi(to); err != nil || last < first {
+				return nil, fmt.Errorf("invalid line range %q", spec)
+			}
+		}
+	}
+
+	if first > len(lines) {
+		return nil, fmt.Errorf("lines %s out of range, the file has %d lines", spec, len(lines))
+	}
+	return lines[first-1 : min(last, len(lines))], nil
+}
+
+// dedent removes the indentation shared by all non-blank lines
+func dedent(lines []string) []string {
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	if prefix == "" {
+		return lines
+	}
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		dedented[i] = strings.TrimPrefix(line, prefix)
+	}
+	return dedented
+}
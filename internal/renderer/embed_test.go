@@ -0,0 +1,182 @@
+package renderer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractLines(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"one", "two", "three", "four"}
+	tests := []struct {
+		spec    string
+		want    []string
+		wantErr string
+	}{
+		{spec: "2", want: []string{"two"}},
+		{spec: "2-3", want: []string{"two", "three"}},
+		{spec: "3-", want: []string{"three", "four"}},
+		{spec: "3-10", want: []string{"three", "four"}},
+		{spec: " 1 - 2 ", want: []string{"one", "two"}},
+		{spec: "5", wantErr: "lines 5 out of range, the file has 4 lines"},
+		{spec: "0", wantErr: `invalid line range "0"`},
+		{spec: "3-2", wantErr: `invalid line range "3-2"`},
+		{spec: "a-b", wantErr: `invalid line range "a-b"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.spec, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := extractLines(lines, tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("extractLines(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractLines(%q) = %q, %v, want %q", tt.spec, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractRegion(t *testing.T) {
+	t.Parallel()
+
+	lines := strings.Split(`func main() {
+	// region:setup
+	cfg := load()
+	# region:inner
+	cfg.apply()
+	# endregion:inner
+	// endregion:setup
+	/* region:open */
+	run()
+}`, "\n")
+
+	tests := []struct {
+		region  string
+		want    []string
+		wantErr string
+	}{
+		{region: "setup", want: []string{"\tcfg := load()", "\tcfg.apply()"}},
+		{region: "inner", want: []string{"\tcfg.apply()"}},
+		{region: "missing", wantErr: `region "missing" not found`},
+		{region: "open", wantErr: `region "open" has no endregion:open marker`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.region, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := extractRegion(lines, tt.region)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("extractRegion(%q) error = %v, want %q", tt.region, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractRegion(%q) = %q, %v, want %q", tt.region, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedent(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{"none", []string{"a", "  b"}, []string{"a", "  b"}},
+		{"shared", []string{"    a", "      b", "    c"}, []string{"a", "  b", "c"}},
+		{"blank lines don't count", []string{"\t\ta", "", "\t\t\tb"}, []string{"a", "", "\tb"}},
+		{"mixed indentation", []string{"\t  a", "\tb"}, []string{"  a", "b"}},
+	}
+
+	for _, tt := range tests {
+		if got := dedent(tt.lines); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dedent(%q) = %q, want %q", tt.name, tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestRenderEmbeddedSnippet(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	docs := filepath.Join(root, "docs")
+	for path, content := range map[string]string{
+		"main.go":      "package main\n\nfunc main() {\n\t// region:body\n\tprintln()\n\t// endregion:body\n}\n",
+		"big.txt":      strings.Repeat("x", 200) + "\n",
+		"docs/dir/.gk": "",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := filepath.Join(t.TempDir(), "outside.txt")
+	if err := os.WriteFile(outside, []byte("leaked\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	relative, err := filepath.Rel(docs, outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	options := Options{BaseDir: docs, EmbedRoot: root, Limits: Limits{MaxInputSize: 100}}
+	tests := []struct {
+		name string
+		info string
+		want string
+	}{
+		{"lines", "go file=../main.go lines=3", "\n  func main() {\n"},
+		{"region", "go file=../main.go region=body", "\n  println()\n"},
+		{"line numbers follow the file", "go {linenos=true} file=../main.go lines=3-4", "\n  3 │ func main() {\n  4 │     // region:body\n"},
+		{"missing file", "go file=missing.go", "\n  ⚠ embed: missing.go: file not found\n"},
+		{"missing region", "go file=../main.go region=nope", "\n  ⚠ embed: ../main.go: region \"nope\" not found\n"},
+		{"bad lines", "go file=../main.go lines=99", "\n  ⚠ embed: ../main.go: lines 99 out of range, the file has 7 lines\n"},
+		{"directory", "file=dir", "\n  ⚠ embed: dir: not a regular file\n"},
+		{"too large", "file=../big.txt", "\n  ⚠ embed: ../big.txt: document exceeds a resource limit: the file is larger than 100 bytes (max-input-size)\n"},
+		{"outside the root", "file=" + relative, "outside of"},
+		{"missing file outside the root", "file=../../missing.txt", "outside of"},
+		{"absolute path outside the root", "file=" + outside, "outside of"},
+		{"symbolic link out of the root", "file=../link.txt", "outside of"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := render(t, options, "```"+tt.info+"\n```\n")
+			if strings.HasPrefix(tt.want, "\n") && got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("render() = %q, want it to contain %q", got, tt.want)
+			}
+			if strings.Contains(got, "leaked") {
+				t.Errorf("render() = %q shows a file outside the embed root", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package renderer
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FoldKind identifies the markdown construct a fold was created from
+type FoldKind int
+
+const (
+	// FoldSection is a heading together with everything up to the next heading of the same or higher level
+	FoldSection FoldKind = iota
+	// FoldCodeBlock is a fenced code block
+	FoldCodeBlock
+	// FoldDetails is an HTML <details> block
+	FoldDetails
+)
+
+// String returns a string representation of the fold kind
+func (fk FoldKind) String() string {
+	switch fk {
+	case FoldSection:
+		return "section"
+	case FoldCodeBlock:
+		return "code"
+	case FoldDetails:
+		return "details"
+	default:
+		return "unknown"
+	}
+}
+
+// Fold describes a collapsible range of rendered output lines.
+// Start is the line that stays visible when the fold is closed; End is inclusive.
+type Fold struct {
+	Kind  FoldKind
+	Level int
+	Start int
+	End   int
+	Title string
+}
+
+// Lines returns the number of lines hidden when the fold is closed
+func (f Fold) Lines() int {
+	return f.End - f.Start
+}
+
+// Contains reports whether the given output line lies within the fold
+func (f Fold) Contains(line int) bool {
+	return line >= f.Start && line <= f.End
+}
+
+// Document is rendered markdown together with its structural metadata
+type Document struct {
+	Content string
+	Folds   []Fold
+}
+
+// lineWriter counts the newlines written through it so renderers can
+// record which output line a node starts on. Output can be captured instead
+// of written so a block can be post-processed, for example wrapped, before
+// it reaches the real writer.
+type lineWriter struct {
+	w       io.Writer
+	lines   int
+	tail    []byte
+	capture []*bytes.Buffer
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	if n := len(lw.capture); n > 0 {
+		return lw.capture[n-1].Write(p)
+	}
+
+	n, err := lw.w.Write(p)
+	written := p[:n]
+	for _, b := range written {
+		if b == '\n' {
+			lw.lines++
+		}
+	}
+	if i := bytes.LastIndexByte(written, '\n'); i >= 0 {
+		lw.tail = append(lw.tail[:0], written[i+1:]...)
+	} else {
+		lw.tail = append(lw.tail, written...)
+	}
+	return n, err
+}
+
+// column returns the visible width of the current output line
+func (lw *lineWriter) column() int {
+	return VisibleWidth(string(lw.tail))
+}
+
+// beginCapture buffers subsequent writes until the matching endCapture
+func (lw *lineWriter) beginCapture() {
+	lw.capture = append(lw.capture, &bytes.Buffer{})
+}
+
+// endCapture stops the innermost capture and returns what was written
+func (lw *lineWriter) endCapture() string {
+	n := len(lw.capture)
+	if n == 0 {
+		return ""
+	}
+	buf := lw.capture[n-1]
+	lw.capture = lw.capture[:n-1]
+	return buf.String()
+}
+
+var summaryPattern = regexp.MustCompile(`(?is)<summary[^>]*>(.*?)</summary>`)
+
+// detailsSummary extracts the <summary> text of an HTML <details> block
+func detailsSummary(html string) string {
+	if m := summaryPattern.FindStringSubmatch(html); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return "Details"
+}
+
+// finalizeFolds closes open section folds, trims trailing blank lines from
+// every fold and drops folds that would hide nothing
+func finalizeFolds(folds []Fold, content string) []Fold {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	last := len(lines) - 1
+
+	for i := range folds {
+		if folds[i].Kind != FoldSection {
+			continue
+		}
+		folds[i].End = last
+		for j := i + 1; j < len(folds); j++ {
+			if folds[j].Kind == FoldSection && folds[j].Level <= folds[i].Level {
+				folds[i].End = folds[j].Start - 1
+				break
+			}
+		}
+	}
+
+	result := folds[:0]
+	for _, f := range folds {
+		if f.Kind == FoldDetails && f.End < f.Start {
+			f.End = last
+		}
+		if f.End > last {
+			f.End = last
+		}
+		for f.End > f.Start && strings.TrimSpace(StripANSI(lines[f.End])) == "" {
+			f.End--
+		}
+		if f.End > f.Start {
+			result = append(result, f)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
@@ -0,0 +1,42 @@
+package renderer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestRenderDocumentFolds(t *testing.T) {
+	t.Parallel()
+
+	source := "# Title\n\nIntro.\n\n## Code\n\n```go\na\nb\n```\n\n## More\n\n" +
+		"<details>\n<summary>Hidden</summary>\n\nSecret text.\n\n</details>\n\nEnd.\n"
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileNone)
+	doc, err := NewWithOptions(tm, Options{}).RenderDocument([]byte(source), plainHighlighter{})
+	if err != nil {
+		t.Fatalf("RenderDocument() returned error: %v", err)
+	}
+
+	// The lines the folds below refer to
+	lines := strings.Split(doc.Content, "\n")
+	for line, want := range map[int]string{0: "# Title", 4: "## Code", 6: "  a", 7: "  b", 10: "## More", 12: "▸ Hidden", 14: "Secret text.", 16: "End."} {
+		if line >= len(lines) || lines[line] != want {
+			t.Fatalf("RenderDocument() content = %q, want line %d to be %q", doc.Content, line, want)
+		}
+	}
+
+	want := []Fold{
+		{Kind: FoldSection, Level: 1, Start: 0, End: 16, Title: "Title"},
+		{Kind: FoldSection, Level: 2, Start: 4, End: 7, Title: "Code"},
+		{Kind: FoldCodeBlock, Start: 6, End: 7, Title: "go"},
+		{Kind: FoldSection, Level: 2, Start: 10, End: 16, Title: "More"},
+		{Kind: FoldDetails, Start: 12, End: 14, Title: "Hidden"},
+	}
+	if !reflect.DeepEqual(doc.Folds, want) {
+		t.Errorf("RenderDocument() folds = %+v, want %+v", doc.Folds, want)
+	}
+}
@@ -0,0 +1,200 @@
+package renderer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// ErrLimitExceeded is returned, wrapped, when a document is larger or more
+// deeply nested than the renderer's limits allow
+var ErrLimitExceeded = errors.New("document exceeds a resource limit")
+
+// errSkipChildren is returned by node renderers that have rendered the whole
+// node themselves, so its children are skipped
+var errSkipChildren = errors.New("skip children")
+
+// Limits bound the work done for a document, so an untrusted one can't make
+// rendering hang. Zero means unlimited.
+type Limits struct {
+	// MaxInputSize is the largest document, in bytes, that is rendered
+	MaxInputSize int
+	// MaxNesting is the deepest nesting of blocks and inlines that is rendered
+	MaxNesting int
+	// MaxTableRows and MaxTableColumns bound the tables drawn with borders;
+	// larger tables are shown as plain text
+	MaxTableRows    int
+	MaxTableColumns int
+	// MaxCodeLines is the longest code block that is syntax highlighted;
+	// longer blocks are shown without highlighting
+	MaxCodeLines int
+}
+
+// checkSize fails documents larger than MaxInputSize
+func (l Limits) checkSize(content []byte) error {
+	if l.MaxInputSize > 0 && len(content) > l.MaxInputSize {
+		return fmt.Errorf("%w: the document is %d bytes, the limit is %d (max-input-size)", ErrLimitExceeded, len(content), l.MaxInputSize)
+	}
+	return nil
+}
+
+// checkSourceNesting fails documents whose lists and block quotes nest
+// deeper than MaxNesting, judged from the source before it is parsed, since
+// parsing such documents is what takes the time. A line is as deep as the
+// quote and list markers it starts with, plus the list levels its indentation
+// can reach.
+func (l Limits) checkSourceNesting(content []byte) error {
+	if l.MaxNesting <= 0 {
+		return nil
+	}
+
+	for len(content) > 0 {
+		line := content
+		if i := bytes.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i], content[i+1:]
+		} else {
+			content = nil
+		}
+		if sourceDepth(line) > l.MaxNesting {
+			return l.nestingError()
+		}
+	}
+	return nil
+}
+
+// sourceDepth estimates how deeply the blocks started on a line nest
+func sourceDepth(line []byte) int {
+	if isThematicBreak(line) {
+		return 0
+	}
+
+	indent := 0
+	for len(line) > 0 && (line[0] == ' ' || line[0] == '\t') {
+		if line[0] == '\t' {
+			indent += 4
+		} else {
+			indent++
+		}
+		line = line[1:]
+	}
+
+	depth := 0
+	for {
+		n := markerLength(line)
+		if n == 0 {
+			break
+		}
+		depth++
+		line = bytes.TrimLeft(line[n:], " \t")
+	}
+	if depth == 0 {
+		// Indented text continues the blocks above it rather than nesting
+		return 0
+	}
+	// A nested list item is indented at least two columns past its parent
+	return depth + indent/2
+}
+
+// markerLength returns the length of the block quote or list marker at the
+// start of line, or 0 if there is none
+func markerLength(line []byte) int {
+	if len(line) == 0 {
+		return 0
+	}
+	if line[0] == '>' {
+		return 1
+	}
+
+	n := 0
+	switch {
+	case line[0] == '-' || line[0] == '*' || line[0] == '+':
+		n = 1
+	case line[0] >= '0' && line[0] <= '9':
+		for n < len(line) && n < 9 && line[n] >= '0' && line[n] <= '9' {
+			n++
+		}
+		if n == len(line) || (line[n] != '.' && line[n] != ')') {
+			return 0
+		}
+		n++
+	default:
+		return 0
+	}
+	// List markers are followed by white space or end the line
+	if n < len(line) && line[n] != ' ' && line[n] != '\t' {
+		return 0
+	}
+	return n
+}
+
+// isThematicBreak reports whether line is a rule such as "- - -", which
+// looks like nested list markers but isn't
+func isThematicBreak(line []byte) bool {
+	var mark byte
+	count := 0
+	for _, c := range line {
+		switch {
+		case c == ' ' || c == '\t':
+		case mark == 0 && (c == '-' || c == '*' || c == '_'):
+			mark = c
+			count++
+		case c == mark:
+			count++
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// checkNesting fails documents whose tree is deeper than MaxNesting
+func (l Limits) checkNesting(doc ast.Node) error {
+	if l.MaxNesting <= 0 {
+		return nil
+	}
+
+	// The document node itself is at depth 0
+	depth, deepest := -1, 0
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			depth--
+			return ast.WalkContinue, nil
+		}
+		depth++
+		deepest = max(deepest, depth)
+		if deepest > l.MaxNesting {
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if deepest > l.MaxNesting {
+		return l.nestingError()
+	}
+	return nil
+}
+
+func (l Limits) nestingError() error {
+	return fmt.Errorf("%w: the document is nested more than %d levels deep (max-nesting)", ErrLimitExceeded, l.MaxNesting)
+}
+
+// tableTooLarge reports whether a table has more rows or columns than the
+// limits allow, with a description of the table if so
+func (l Limits) tableTooLarge(table ast.Node) (string, bool) {
+	rows, columns := 0, 0
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		rows++
+		columns = max(columns, row.ChildCount())
+	}
+	if (l.MaxTableRows > 0 && rows > l.MaxTableRows) || (l.MaxTableColumns > 0 && columns > l.MaxTableColumns) {
+		return fmt.Sprintf("%d rows by %d columns", rows, columns), true
+	}
+	return "", false
+}
+
+// codeTooLong reports whether a code block is too long to highlight
+func (l Limits) codeTooLong(code string) bool {
+	return l.MaxCodeLines > 0 && strings.Count(strings.TrimSuffix(code, "\n"), "\n")+1 > l.MaxCodeLines
+}
@@ -0,0 +1,173 @@
+package renderer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+// markingHighlighter wraps highlighted code in markers, so tests can tell
+// whether a code block was highlighted
+type markingHighlighter struct{}
+
+func (markingHighlighter) Highlight(code, language string) (string, error) {
+	return "<hl>" + code + "</hl>", nil
+}
+func (markingHighlighter) HighlightInlineCode(code string) string { return code }
+
+// renderLimited renders source with colors disabled and returns the error
+func renderLimited(limits Limits, source string, highlighter CodeHighlighter) (string, error) {
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileNone)
+	return NewWithOptions(tm, Options{Limits: limits}).RenderContent([]byte(source), highlighter)
+}
+
+func TestLimitsRefuseDocuments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		limits  Limits
+		source  string
+		wantErr string
+	}{
+		{"size", Limits{MaxInputSize: 10}, "# A heading longer than ten bytes\n", "max-input-size"},
+		{"nested lists", Limits{MaxNesting: 10}, strings.Repeat("- ", 20) + "x\n", "max-nesting"},
+		{"nested quotes", Limits{MaxNesting: 10}, strings.Repeat(">", 20) + " x\n", "max-nesting"},
+		{"spaced quotes", Limits{MaxNesting: 10}, strings.Repeat("> ", 20) + "x\n", "max-nesting"},
+		{"ordered lists", Limits{MaxNesting: 10}, strings.Repeat("1. ", 20) + "x\n", "max-nesting"},
+		{"indented lists", Limits{MaxNesting: 10}, "- a\n" + strings.Repeat(" ", 40) + "- b\n", "max-nesting"},
+		// Emphasis nests inlines without any block markers, so it is only
+		// caught once the document is parsed
+		{"nested inlines", Limits{MaxNesting: 10}, strings.Repeat("*a ", 20) + strings.Repeat("b*", 20) + "\n", "max-nesting"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := renderLimited(tt.limits, tt.source, plainHighlighter{})
+			if !errors.Is(err, ErrLimitExceeded) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("RenderContent() error = %v, want %s exceeded", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimitsAllowDocuments(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		limits Limits
+		source string
+	}{
+		{"zero is unlimited", Limits{}, strings.Repeat("- ", 20) + "x\n"},
+		{"within limits", Limits{MaxInputSize: 100, MaxNesting: 10}, "- a\n  - b\n    > c\n"},
+		{"thematic break", Limits{MaxNesting: 2}, strings.Repeat("- ", 20) + "\n\n" + strings.Repeat("* ", 20) + "\n"},
+		{"indented text", Limits{MaxNesting: 5}, "- a\n\n" + strings.Repeat(" ", 40) + "code\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := renderLimited(tt.limits, tt.source, plainHighlighter{}); err != nil {
+				t.Errorf("RenderContent() error = %v", err)
+			}
+		})
+	}
+}
+
+// Parsing deeply nested documents is slow, so they must be refused unparsed
+func TestLimitsRefuseNestingBeforeParsing(t *testing.T) {
+	t.Parallel()
+
+	for name, source := range map[string]string{
+		"lists":  strings.Repeat("- ", 50000) + "x\n",
+		"quotes": strings.Repeat(">", 200000) + "\n",
+	} {
+		start := time.Now()
+		_, err := renderLimited(Limits{MaxNesting: 100}, source, plainHighlighter{})
+		if !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: RenderContent() error = %v, want the nesting limit exceeded", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: RenderContent() took %s, want the document refused before parsing", name, elapsed)
+		}
+	}
+}
+
+func TestLimitsPlainTable(t *testing.T) {
+	t.Parallel()
+
+	table := "| a | b |\n|---|---|\n| 1 | 2 |\n| 3 | 4 |\n"
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"rows", Limits{MaxTableRows: 2}, "3 rows by 2 columns"},
+		{"columns", Limits{MaxTableColumns: 1}, "3 rows by 2 columns"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := renderLimited(tt.limits, table, plainHighlighter{})
+			if err != nil {
+				t.Fatalf("RenderContent() error = %v", err)
+			}
+			want := "\n  ⚠ table: " + tt.want + " is over the limit, shown as plain text\na | b\n1 | 2\n3 | 4\n"
+			if got != want {
+				t.Errorf("RenderContent() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	// Tables within the limits are drawn with borders
+	got, err := renderLimited(Limits{MaxTableRows: 3, MaxTableColumns: 2}, table, plainHighlighter{})
+	if err != nil {
+		t.Fatalf("RenderContent() error = %v", err)
+	}
+	if strings.Contains(got, "⚠") || !strings.Contains(got, "│") {
+		t.Errorf("RenderContent() = %q, want a table with borders", got)
+	}
+}
+
+func TestLimitsCodeLines(t *testing.T) {
+	t.Parallel()
+
+	source := "```go\nx\ny\nz\n```\n"
+	tests := []struct {
+		name   string
+		limits Limits
+		want   string
+	}{
+		{"highlighted", Limits{MaxCodeLines: 3}, "\n  <hl>x\n  y\n  z\n  </hl>\n"},
+		{"unlimited", Limits{}, "\n  <hl>x\n  y\n  z\n  </hl>\n"},
+		{"too long", Limits{MaxCodeLines: 2}, "\n  x\n  y\n  z\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := renderLimited(tt.limits, source, markingHighlighter{})
+			if err != nil {
+				t.Fatalf("RenderContent() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
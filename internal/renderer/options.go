@@ -0,0 +1,135 @@
+package renderer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// LinkMode controls how link destinations are shown
+type LinkMode int
+
+const (
+	// LinkInline prints the destination in parentheses after the link text
+	LinkInline LinkMode = iota
+	// LinkFootnote numbers links and lists their destinations at the end of the document
+	LinkFootnote
+	// LinkHidden prints only the link text
+	LinkHidden
+)
+
+// String returns a string representation of the link mode
+func (lm LinkMode) String() string {
+	switch lm {
+	case LinkFootnote:
+		return "footnote"
+	case LinkHidden:
+		return "hidden"
+	default:
+		return "inline"
+	}
+}
+
+// ParseLinkMode parses a link mode name
+func ParseLinkMode(value string) (LinkMode, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "inline":
+		return LinkInline, nil
+	case "footnote", "footnotes", "reference":
+		return LinkFootnote, nil
+	case "hidden", "none", "off":
+		return LinkHidden, nil
+	default:
+		return LinkInline, fmt.Errorf("invalid link mode %q (want inline, footnote or hidden)", value)
+	}
+}
+
+// Options configures how markdown is parsed and rendered
+type Options struct {
+	// Width wraps paragraphs and sizes rules to this many columns; 0 disables wrapping
+	Width int
+	// LinkMode controls how link destinations are shown
+	LinkMode LinkMode
+	// Hyperlinks makes links clickable with OSC 8 escape sequences
+	Hyperlinks bool
+	// Extensions names the goldmark extensions to enable; nil means DefaultExtensions
+	Extensions []string
+
+	// LineNumbers numbers the lines of code blocks
+	LineNumbers bool
+	// CodeFrame draws a box labelled with the language around code blocks
+	CodeFrame bool
+	// CodeBackground fills code blocks with the code theme's background color
+	CodeBackground bool
+
+	// BaseDir resolves relative paths in the document, such as the files
+	// embedded in code blocks; empty means the working directory
+	BaseDir string
+	// EmbedRoot is the directory files embedded in code blocks must be
+	// inside, such as the root of the document's project; empty means the
+	// directory of the document
+	EmbedRoot string
+
+	// AllowRawEscapes writes control characters in the document, such as
+	// escape sequences, to the terminal as they are instead of showing them
+	// as symbols
+	AllowRawEscapes bool
+
+	// Limits bound the size of the documents and elements that are rendered
+	Limits Limits
+}
+
+// DefaultExtensions are the markdown extensions enabled unless configured otherwise
+var DefaultExtensions = []string{"gfm"}
+
+// extensions maps extension names to goldmark extenders
+var extensions = map[string]goldmark.Extender{
+	"gfm":             extension.GFM,
+	"table":           extension.Table,
+	"strikethrough":   extension.Strikethrough,
+	"tasklist":        extension.TaskList,
+	"linkify":         extension.Linkify,
+	"footnote":        extension.Footnote,
+	"definition-list": extension.DefinitionList,
+	"typographer":     extension.Typographer,
+}
+
+// Extensions returns the names of the supported markdown extensions
+func Extensions() []string {
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateExtensions checks that every name is a supported extension
+func ValidateExtensions(names []string) error {
+	for _, name := range names {
+		if _, ok := extensions[name]; !ok {
+			return fmt.Errorf("unknown extension %q (want %s)", name, strings.Join(Extensions(), ", "))
+		}
+	}
+	return nil
+}
+
+// extenders returns the goldmark extenders for the configured extensions,
+// skipping unknown names
+func (o Options) extenders() []goldmark.Extender {
+	names := o.Extensions
+	if names == nil {
+		names = DefaultExtensions
+	}
+
+	var result []goldmark.Extender
+	for _, name := range names {
+		if ext, ok := extensions[name]; ok {
+			result = append(result, ext)
+		}
+	}
+	return result
+}
@@ -2,15 +2,18 @@ package renderer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/codehakase/md/internal/theme"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
-	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 )
@@ -21,21 +24,28 @@ type CodeHighlighter interface {
 	HighlightInlineCode(code string) string
 }
 
+// ContextHighlighter is implemented by highlighters that can take the
+// language of the preceding code block into account for inline code
+type ContextHighlighter interface {
+	HighlightInlineCodeIn(code, context string) string
+}
+
 // Renderer renders markdown to styled terminal output
 type Renderer struct {
 	themeManager *theme.ThemeManager
 	goldmark     goldmark.Markdown
+	options      Options
 }
 
-// New creates a new markdown renderer
+// New creates a new markdown renderer with the default options
 func New(themeManager *theme.ThemeManager) *Renderer {
+	return NewWithOptions(themeManager, Options{})
+}
+
+// NewWithOptions creates a new markdown renderer
+func NewWithOptions(themeManager *theme.ThemeManager, options Options) *Renderer {
 	md := goldmark.New(
-		goldmark.WithExtensions(
-			extension.GFM,
-			extension.Table,
-			extension.Strikethrough,
-			extension.TaskList,
-		),
+		goldmark.WithExtensions(options.extenders()...),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
@@ -44,51 +54,113 @@ func New(themeManager *theme.ThemeManager) *Renderer {
 	return &Renderer{
 		themeManager: themeManager,
 		goldmark:     md,
+		options:      options,
 	}
 }
 
 // RenderFile renders a markdown file to styled terminal output
 func (r *Renderer) RenderFile(filename string, highlighter CodeHighlighter) (string, error) {
+	doc, err := r.RenderFileDocument(filename, highlighter)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// RenderFileDocument renders a markdown file and returns the output with its fold metadata
+func (r *Renderer) RenderFileDocument(filename string, highlighter CodeHighlighter) (*Document, error) {
 	content, err := os.ReadFile(filename)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", filename, err)
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
 	}
 
-	return r.RenderContent(content, highlighter)
+	baseDir := r.options.BaseDir
+	if baseDir == "" {
+		baseDir = filepath.Dir(filename)
+	}
+	return r.renderDocument(content, highlighter, baseDir)
 }
 
 // RenderContent renders markdown content to styled terminal output
 func (r *Renderer) RenderContent(content []byte, highlighter CodeHighlighter) (string, error) {
+	doc, err := r.RenderDocument(content, highlighter)
+	if err != nil {
+		return "", err
+	}
+	return doc.Content, nil
+}
+
+// RenderDocument renders markdown content and records the foldable regions of the output
+func (r *Renderer) RenderDocument(content []byte, highlighter CodeHighlighter) (*Document, error) {
+	return r.renderDocument(content, highlighter, r.options.BaseDir)
+}
+
+// renderDocument renders markdown content, resolving relative paths in it against baseDir
+func (r *Renderer) renderDocument(content []byte, highlighter CodeHighlighter, baseDir string) (*Document, error) {
+	if err := r.options.Limits.checkSize(content); err != nil {
+		return nil, err
+	}
+	content = normalizeLineEndings(content)
+	// Only escape sequences md writes itself may reach the terminal
+	if !r.options.AllowRawEscapes {
+		content = []byte(SanitizeText(string(content)))
+	}
+	if err := r.options.Limits.checkSourceNesting(content); err != nil {
+		return nil, err
+	}
 	doc := r.goldmark.Parser().Parse(text.NewReader(content))
+	if err := r.options.Limits.checkNesting(doc); err != nil {
+		return nil, err
+	}
 
 	termRenderer := &terminalRenderer{
 		themeManager: r.themeManager,
 		highlighter:  highlighter,
+		options:      r.options,
+		baseDir:      baseDir,
 	}
 
 	var buf bytes.Buffer
 	err := termRenderer.render(&buf, content, doc)
 	if err != nil {
-		return "", fmt.Errorf("failed to render markdown: %w", err)
+		return nil, fmt.Errorf("failed to render markdown: %w", err)
 	}
 
 	result := buf.String()
 	result = TrimTrailingWhitespace(result)
 	result = EnsureTrailingNewline(result)
 
-	return result, nil
+	return &Document{
+		Content: result,
+		Folds:   finalizeFolds(termRenderer.folds, result),
+	}, nil
 }
 
 // terminalRenderer handles the actual rendering to terminal format
 type terminalRenderer struct {
 	themeManager *theme.ThemeManager
 	highlighter  CodeHighlighter
+	options      Options
+	baseDir      string
+
+	// codeLanguage is the language of the last fenced code block
+	codeLanguage string
+
+	out     *lineWriter
+	links   []string
+	folds   []Fold
+	details []int
 }
 
 // render renders the AST node to the writer
 func (tr *terminalRenderer) render(w io.Writer, source []byte, node ast.Node) error {
+	tr.out = &lineWriter{w: w}
+	w = tr.out
 	return ast.Walk(node, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
 		err := tr.renderNode(w, source, node, entering)
+		if errors.Is(err, errSkipChildren) {
+			return ast.WalkSkipChildren, nil
+		}
 		if err != nil {
 			return ast.WalkStop, err
 		}
@@ -107,6 +179,10 @@ func (tr *terminalRenderer) renderNode(w io.Writer, source []byte, node ast.Node
 		return tr.renderParagraph(w, source, n, entering)
 	case *ast.Text:
 		return tr.renderText(w, source, n, entering)
+	case *ast.String:
+		return tr.renderString(w, source, n, entering)
+	case *ast.TextBlock:
+		return tr.renderTextBlock(w, source, n, entering)
 	case *ast.Emphasis:
 		return tr.renderEmphasis(w, source, n, entering)
 	case *ast.CodeSpan:
@@ -146,12 +222,25 @@ func (tr *terminalRenderer) renderNode(w io.Writer, source []byte, node ast.Node
 			return tr.renderStrikethrough(w, source, node, entering)
 		case "TaskCheckBox":
 			return tr.renderTaskCheckBox(w, source, node, entering)
+		case "FootnoteLink", "FootnoteList", "Footnote":
+			return tr.renderFootnote(w, source, node, entering)
+		case "DefinitionTerm", "DefinitionDescription":
+			return tr.renderDefinition(w, source, node, entering)
 		}
 		return nil
 	}
 }
 
 func (tr *terminalRenderer) renderDocument(w io.Writer, source []byte, n *ast.Document, entering bool) error {
+	if entering || len(tr.links) == 0 {
+		return nil
+	}
+
+	fmt.Fprint(w, "\n")
+	for i, url := range tr.links {
+		label := tr.themeManager.Style(fmt.Sprintf("[%d]", i+1), theme.OrderedList)
+		fmt.Fprintf(w, "%s %s\n", label, tr.themeManager.Style(tr.hyperlink(url, url), theme.Link))
+	}
 	return nil
 }
 
@@ -185,6 +274,13 @@ func (tr *terminalRenderer) renderHeading(w io.Writer, source []byte, n *ast.Hea
 			fmt.Fprint(w, "\n")
 		}
 
+		tr.folds = append(tr.folds, Fold{
+			Kind:  FoldSection,
+			Level: n.Level,
+			Start: tr.out.lines,
+			Title: string(n.Text(source)),
+		})
+
 		fmt.Fprint(w, tr.themeManager.StyleNoReset(prefix, headerTheme))
 	} else {
 		fmt.Fprint(w, tr.themeManager.Reset())
@@ -194,7 +290,10 @@ func (tr *terminalRenderer) renderHeading(w io.Writer, source []byte, n *ast.Hea
 }
 
 func (tr *terminalRenderer) renderParagraph(w io.Writer, source []byte, n *ast.Paragraph, entering bool) error {
-	if !entering {
+	if entering {
+		tr.beginWrap()
+	} else {
+		tr.endWrap(w)
 		fmt.Fprint(w, "\n")
 		if n.NextSibling() != nil {
 			fmt.Fprint(w, "\n")
@@ -216,22 +315,60 @@ func (tr *terminalRenderer) renderText(w io.Writer, source []byte, n *ast.Text,
 		if n.IsRaw() {
 			fmt.Fprint(w, string(value))
 		} else {
-			lines := strings.Split(string(value), "\n")
-			for i, line := range lines {
-				if i > 0 {
-					if n.HardLineBreak() || (i == len(lines)-1 && n.SoftLineBreak()) {
-						fmt.Fprint(w, "\n")
-					} else {
-						fmt.Fprint(w, " ")
-					}
-				}
-				fmt.Fprint(w, line)
-			}
+			fmt.Fprint(w, strings.ReplaceAll(string(value), "\n", " "))
+		}
+
+		// Segments end before the line break, which only the flags record.
+		// Soft breaks are spaces so paragraphs flow into the available width.
+		if n.HardLineBreak() {
+			fmt.Fprint(w, "\n")
+		} else if n.SoftLineBreak() {
+			fmt.Fprint(w, " ")
 		}
 	}
 	return nil
 }
 
+// renderTextBlock renders the text of tight list items, which has no paragraph
+func (tr *terminalRenderer) renderTextBlock(w io.Writer, source []byte, n *ast.TextBlock, entering bool) error {
+	if entering {
+		tr.beginWrap()
+	} else {
+		tr.endWrap(w)
+	}
+	return nil
+}
+
+// beginWrap starts buffering a block of inline content so it can be wrapped
+func (tr *terminalRenderer) beginWrap() {
+	if tr.options.Width > 0 {
+		tr.out.beginCapture()
+	}
+}
+
+// endWrap writes the buffered block wrapped to the configured width, with
+// continuation lines aligned to the column the block started at
+func (tr *terminalRenderer) endWrap(w io.Writer) {
+	if tr.options.Width <= 0 {
+		return
+	}
+	text := tr.out.endCapture()
+	column := tr.out.column()
+	width := tr.options.Width - column
+	if width < 20 {
+		width = 20
+	}
+	fmt.Fprint(w, WrapANSI(text, width, strings.Repeat(" ", column)))
+}
+
+func (tr *terminalRenderer) renderString(w io.Writer, source []byte, n *ast.String, entering bool) error {
+	if entering {
+		// The typographer extension substitutes HTML entities for quotes and dashes
+		fmt.Fprint(w, tr.sanitize(html.UnescapeString(string(n.Value))))
+	}
+	return nil
+}
+
 func (tr *terminalRenderer) renderEmphasis(w io.Writer, source []byte, n *ast.Emphasis, entering bool) error {
 	if entering {
 		if n.Level == 2 {
@@ -248,7 +385,12 @@ func (tr *terminalRenderer) renderEmphasis(w io.Writer, source []byte, n *ast.Em
 func (tr *terminalRenderer) renderCodeSpan(w io.Writer, source []byte, n *ast.CodeSpan, entering bool) error {
 	if entering {
 		value := string(n.Text(source))
-		highlighted := tr.highlighter.HighlightInlineCode(value)
+		highlighted := ""
+		if ch, ok := tr.highlighter.(ContextHighlighter); ok {
+			highlighted = ch.HighlightInlineCodeIn(value, tr.codeLanguage)
+		} else {
+			highlighted = tr.highlighter.HighlightInlineCode(value)
+		}
 		fmt.Fprint(w, highlighted)
 	}
 	return nil
@@ -256,10 +398,11 @@ func (tr *terminalRenderer) renderCodeSpan(w io.Writer, source []byte, n *ast.Co
 
 func (tr *terminalRenderer) renderFencedCodeBlock(w io.Writer, source []byte, n *ast.FencedCodeBlock, entering bool) error {
 	if entering {
-		language := ""
+		info := ""
 		if n.Info != nil {
-			language = string(n.Info.Text(source))
+			info = string(n.Info.Text(source))
 		}
+		language, attrs := parseCodeInfo(info)
 
 		var code strings.Builder
 		for i := 0; i < n.Lines().Len(); i++ {
@@ -267,13 +410,52 @@ func (tr *terminalRenderer) renderFencedCodeBlock(w io.Writer, source []byte, n
 			code.Write(line.Value(source))
 		}
 
-		highlighted, err := tr.highlighter.Highlight(code.String(), language)
-		if err != nil {
-			highlighted = tr.themeManager.Style(code.String(), theme.Code)
+		fmt.Fprint(w, "\n")
+
+		// Blocks with a file attribute show a snippet of that file; the
+		// block's own content is kept when the snippet can't be read
+		hint, label := language, language
+		if file := attrs["file"]; file != "" {
+			snippet, err := tr.embedSnippet(attrs)
+			if err != nil {
+				fmt.Fprint(w, Indent(tr.themeManager.Style("⚠ embed: "+err.Error(), theme.Warning), 1)+"\n")
+				if strings.TrimSpace(code.String()) == "" {
+					fmt.Fprint(w, "\n")
+					return nil
+				}
+			} else {
+				code.Reset()
+				code.WriteString(snippet)
+			}
+			if language == "" {
+				hint, label = file, filepath.Base(file)
+			}
 		}
 
-		fmt.Fprint(w, "\n")
-		fmt.Fprint(w, Indent(strings.TrimRight(highlighted, "\n"), 1))
+		if hint != "" {
+			tr.codeLanguage = hint
+		}
+		// Very long blocks aren't worth the time highlighting them takes
+		highlighted := tr.themeManager.Style(code.String(), theme.Code)
+		if !tr.options.Limits.codeTooLong(code.String()) {
+			if h, err := tr.highlighter.Highlight(code.String(), hint); err == nil {
+				highlighted = h
+			}
+		}
+
+		start := tr.out.lines
+		decorations := tr.codeAttributes(attrs)
+		tr.writeCodeBlock(w, highlighted, label, decorations)
+		title := label
+		if decorations.title != "" {
+			title = decorations.title
+		}
+		tr.folds = append(tr.folds, Fold{
+			Kind:  FoldCodeBlock,
+			Start: start,
+			End:   tr.out.lines,
+			Title: title,
+		})
 		fmt.Fprint(w, "\n\n")
 	}
 	return nil
@@ -290,7 +472,7 @@ func (tr *terminalRenderer) renderCodeBlock(w io.Writer, source []byte, n *ast.C
 		styled := tr.themeManager.Style(code.String(), theme.Code)
 
 		fmt.Fprint(w, "\n")
-		fmt.Fprint(w, Indent(strings.TrimRight(styled, "\n"), 1))
+		tr.writeCodeBlock(w, styled, "", tr.codeAttributes(nil))
 		fmt.Fprint(w, "\n\n")
 	}
 	return nil
@@ -355,20 +537,80 @@ func (tr *terminalRenderer) renderListItem(w io.Writer, source []byte, n *ast.Li
 }
 
 func (tr *terminalRenderer) renderLink(w io.Writer, source []byte, n *ast.Link, entering bool) error {
+	// Destinations have their entities decoded, which can produce control
+	// characters the source didn't have
+	url := tr.sanitize(string(n.Destination))
 	if entering {
+		if tr.hyperlinks() {
+			fmt.Fprint(w, hyperlinkStart(url))
+		}
 		fmt.Fprint(w, tr.themeManager.GetColor(theme.Link))
 	} else {
-		url := string(n.Destination)
-		fmt.Fprintf(w, " (%s)", url)
+		switch tr.options.LinkMode {
+		case LinkInline:
+			fmt.Fprintf(w, " (%s)", url)
+		case LinkFootnote:
+			fmt.Fprintf(w, "[%d]", tr.linkNumber(url))
+		}
 		fmt.Fprint(w, tr.themeManager.Reset())
+		if tr.hyperlinks() {
+			fmt.Fprint(w, hyperlinkEnd)
+		}
 	}
 	return nil
 }
 
+// sanitize makes text that was decoded from the document, rather than
+// copied from its already sanitized source, safe to write to the terminal
+func (tr *terminalRenderer) sanitize(text string) string {
+	if tr.options.AllowRawEscapes {
+		return text
+	}
+	return SanitizeText(text)
+}
+
+// linkNumber returns the footnote number of a link destination, numbering
+// each distinct destination once
+func (tr *terminalRenderer) linkNumber(url string) int {
+	for i, link := range tr.links {
+		if link == url {
+			return i + 1
+		}
+	}
+	tr.links = append(tr.links, url)
+	return len(tr.links)
+}
+
+// hyperlinkEnd closes an OSC 8 hyperlink
+const hyperlinkEnd = "\033]8;;\033\\"
+
+// hyperlinkStart opens an OSC 8 hyperlink to url
+func hyperlinkStart(url string) string {
+	return "\033]8;;" + url + "\033\\"
+}
+
+// hyperlinks reports whether links are made clickable. Like colors, they are
+// escape sequences, so they are left out when the profile has none.
+func (tr *terminalRenderer) hyperlinks() bool {
+	return tr.options.Hyperlinks && tr.themeManager.SupportsColor()
+}
+
+// hyperlink makes text a clickable link to url when hyperlinks are enabled
+func (tr *terminalRenderer) hyperlink(text, url string) string {
+	if !tr.hyperlinks() {
+		return text
+	}
+	return hyperlinkStart(url) + text + hyperlinkEnd
+}
+
 func (tr *terminalRenderer) renderAutoLink(w io.Writer, source []byte, n *ast.AutoLink, entering bool) error {
 	if entering {
 		url := string(n.URL(source))
-		fmt.Fprint(w, tr.themeManager.Style(url, theme.Link))
+		target := url
+		if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(url), "mailto:") {
+			target = "mailto:" + url
+		}
+		fmt.Fprint(w, tr.hyperlink(tr.themeManager.Style(url, theme.Link), target))
 	}
 	return nil
 }
@@ -378,13 +620,51 @@ func (tr *terminalRenderer) renderRawHTML(w io.Writer, source []byte, n *ast.Raw
 }
 
 func (tr *terminalRenderer) renderHTMLBlock(w io.Writer, source []byte, n *ast.HTMLBlock, entering bool) error {
+	if !entering {
+		return nil
+	}
+
+	var html strings.Builder
+	for i := 0; i < n.Lines().Len(); i++ {
+		line := n.Lines().At(i)
+		html.Write(line.Value(source))
+	}
+	if n.HasClosure() {
+		html.Write(n.ClosureLine.Value(source))
+	}
+	block := strings.ToLower(html.String())
+
+	opens := strings.Contains(block, "<details")
+	closes := strings.Contains(block, "</details>")
+
+	if opens {
+		fmt.Fprint(w, "\n")
+		if !closes {
+			tr.details = append(tr.details, len(tr.folds))
+			tr.folds = append(tr.folds, Fold{
+				Kind:  FoldDetails,
+				Start: tr.out.lines,
+				Title: detailsSummary(html.String()),
+			})
+		}
+		fmt.Fprint(w, tr.themeManager.Style("▸", theme.BulletPoint))
+		fmt.Fprintf(w, " %s\n\n", detailsSummary(html.String()))
+	} else if closes && len(tr.details) > 0 {
+		open := tr.details[len(tr.details)-1]
+		tr.details = tr.details[:len(tr.details)-1]
+		tr.folds[open].End = tr.out.lines - 1
+	}
 	return nil
 }
 
 func (tr *terminalRenderer) renderThematicBreak(w io.Writer, source []byte, n *ast.ThematicBreak, entering bool) error {
 	if entering {
 		fmt.Fprint(w, "\n")
-		rule := strings.Repeat("─", 50)
+		width := 50
+		if tr.options.Width > 0 {
+			width = tr.options.Width
+		}
+		rule := strings.Repeat("─", width)
 		fmt.Fprint(w, tr.themeManager.Style(rule, theme.TableBorder))
 		fmt.Fprint(w, "\n\n")
 	}
@@ -395,12 +675,29 @@ func (tr *terminalRenderer) renderThematicBreak(w io.Writer, source []byte, n *a
 func (tr *terminalRenderer) renderTable(w io.Writer, source []byte, node ast.Node, entering bool) error {
 	if entering {
 		fmt.Fprint(w, "\n")
+		if size, tooLarge := tr.options.Limits.tableTooLarge(node); tooLarge {
+			tr.renderPlainTable(w, source, node, size)
+			return errSkipChildren
+		}
 	} else {
 		fmt.Fprint(w, "\n")
 	}
 	return nil
 }
 
+// renderPlainTable writes the cells of a table too large to lay out, one row
+// per line
+func (tr *terminalRenderer) renderPlainTable(w io.Writer, source []byte, node ast.Node, size string) {
+	fmt.Fprint(w, Indent(tr.themeManager.Style("⚠ table: "+size+" is over the limit, shown as plain text", theme.Warning), 1)+"\n")
+	for row := node.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, string(cell.Text(source)))
+		}
+		fmt.Fprint(w, strings.Join(cells, " | ")+"\n")
+	}
+}
+
 func (tr *terminalRenderer) renderTableHeader(w io.Writer, source []byte, node ast.Node, entering bool) error {
 	return nil
 }
@@ -471,3 +768,43 @@ func (tr *terminalRenderer) renderTaskCheckBox(w io.Writer, source []byte, node
 	return nil
 }
 
+func (tr *terminalRenderer) renderFootnote(w io.Writer, source []byte, node ast.Node, entering bool) error {
+	if !entering {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *extast.FootnoteLink:
+		fmt.Fprint(w, tr.themeManager.Style(fmt.Sprintf("[^%d]", n.Index), theme.Link))
+	case *extast.FootnoteList:
+		fmt.Fprint(w, "\n")
+		fmt.Fprint(w, tr.themeManager.Style(strings.Repeat("─", 20), theme.TableBorder))
+		fmt.Fprint(w, "\n\n")
+	case *extast.Footnote:
+		fmt.Fprint(w, tr.themeManager.Style(fmt.Sprintf("[^%d]", n.Index), theme.OrderedList))
+		fmt.Fprint(w, " ")
+	}
+	return nil
+}
+
+func (tr *terminalRenderer) renderDefinition(w io.Writer, source []byte, node ast.Node, entering bool) error {
+	switch node.(type) {
+	case *extast.DefinitionTerm:
+		if entering {
+			if node.PreviousSibling() != nil {
+				fmt.Fprint(w, "\n")
+			}
+			fmt.Fprint(w, tr.themeManager.GetColor(theme.Bold))
+		} else {
+			fmt.Fprint(w, tr.themeManager.Reset())
+			fmt.Fprint(w, "\n")
+		}
+	case *extast.DefinitionDescription:
+		if entering {
+			fmt.Fprint(w, "  ")
+		} else if _, ok := node.LastChild().(*ast.TextBlock); ok {
+			fmt.Fprint(w, "\n")
+		}
+	}
+	return nil
+}
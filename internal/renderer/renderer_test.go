@@ -0,0 +1,195 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+// plainHighlighter leaves code as it is, so tests don't depend on Chroma
+type plainHighlighter struct{}
+
+func (plainHighlighter) Highlight(code, language string) (string, error) { return code, nil }
+func (plainHighlighter) HighlightInlineCode(code string) string          { return code }
+
+// render renders source with colors disabled
+func render(t *testing.T, options Options, source string) string {
+	t.Helper()
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileNone)
+	out, err := NewWithOptions(tm, options).RenderContent([]byte(source), plainHighlighter{})
+	if err != nil {
+		t.Fatalf("RenderContent() returned error: %v", err)
+	}
+	return out
+}
+
+func TestRenderWidth(t *testing.T) {
+	t.Parallel()
+
+	source := "The quick brown fox\njumps over the lazy dog.\n\n- a list item long enough to wrap\n"
+	tests := []struct {
+		name  string
+		width int
+		want  string
+	}{
+		{
+			name: "unwrapped",
+			want: "The quick brown fox jumps over the lazy dog.\n\n\n\u00a0• a list item long enough to wrap\n",
+		},
+		{
+			name:  "wrapped",
+			width: 20,
+			want:  "The quick brown fox\njumps over the lazy\ndog.\n\n\n\u00a0• a list item long\n   enough to wrap\n",
+		},
+		{
+			// Narrow widths still leave 20 columns for the text
+			name:  "narrow",
+			width: 5,
+			want:  "The quick brown fox\njumps over the lazy\ndog.\n\n\n\u00a0• a list item long\n   enough to wrap\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := render(t, Options{Width: tt.width}, source); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLineBreaks(t *testing.T) {
+	t.Parallel()
+
+	got := render(t, Options{}, "soft\nbreak  \nhard\\\nescaped\n")
+	want := "soft break\nhard\nescaped\n"
+	if got != want {
+		t.Errorf("render() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderLinkModes(t *testing.T) {
+	t.Parallel()
+
+	source := "[one](https://a.example), [two](https://b.example) and [again](https://a.example).\n"
+	tests := []struct {
+		mode LinkMode
+		want string
+	}{
+		{LinkInline, "one (https://a.example), two (https://b.example) and again (https://a.example).\n"},
+		{LinkFootnote, "one[1], two[2] and again[1].\n\n[1] https://a.example\n[2] https://b.example\n"},
+		{LinkHidden, "one, two and again.\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.mode.String(), func(t *testing.T) {
+			t.Parallel()
+
+			if got := render(t, Options{LinkMode: tt.mode}, source); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinkMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    LinkMode
+		wantErr bool
+	}{
+		{"", LinkInline, false},
+		{"Footnote", LinkFootnote, false},
+		{"reference", LinkFootnote, false},
+		{"none", LinkHidden, false},
+		{"sidenote", LinkInline, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLinkMode(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLinkMode(%q) = %v, %v, want %v (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRenderHyperlinks(t *testing.T) {
+	t.Parallel()
+
+	source := "[docs](https://a.example) <https://b.example> <me@example.com>\n"
+
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileANSI)
+	got, err := NewWithOptions(tm, Options{Hyperlinks: true, LinkMode: LinkHidden}).RenderContent([]byte(source), plainHighlighter{})
+	if err != nil {
+		t.Fatalf("RenderContent() returned error: %v", err)
+	}
+	want := "\x1b]8;;https://a.example\x1b\\docs\x1b]8;;\x1b\\ " +
+		"\x1b]8;;https://b.example\x1b\\https://b.example\x1b]8;;\x1b\\ " +
+		"\x1b]8;;mailto:me@example.com\x1b\\me@example.com\x1b]8;;\x1b\\\n"
+	if StripANSI(got) != StripANSI(want) || strings.Count(got, "\x1b]8;;") != 6 {
+		t.Errorf("RenderContent() = %q, want the links %q", got, want)
+	}
+	for _, link := range []string{"\x1b]8;;https://a.example\x1b\\", "\x1b]8;;https://b.example\x1b\\", "\x1b]8;;mailto:me@example.com\x1b\\"} {
+		if !strings.Contains(got, link) {
+			t.Errorf("RenderContent() = %q, want it to contain %q", got, link)
+		}
+	}
+
+	if got := render(t, Options{LinkMode: LinkHidden}, source); strings.Contains(got, "\x1b]8") {
+		t.Errorf("render() without hyperlinks = %q, want no OSC 8 sequences", got)
+	}
+	// Without colors, as with --color=never or when piped, there are no
+	// escape sequences at all
+	if got := render(t, Options{Hyperlinks: true, LinkMode: LinkHidden}, source); strings.Contains(got, "\x1b") {
+		t.Errorf("render() without colors = %q, want no escape sequences", got)
+	}
+}
+
+func TestRenderExtensions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		extensions []string
+		source     string
+		want       string
+	}{
+		{"none", []string{}, "~~gone~~ \"quoted\"\n", "~~gone~~ \"quoted\"\n"},
+		{"strikethrough", []string{"strikethrough"}, "~~gone~~\n", "gone\n"},
+		{"typographer", []string{"typographer"}, "\"quoted\" -- dash\n", "“quoted” – dash\n"},
+		{"footnote", []string{"footnote"}, "note[^1]\n\n[^1]: Text.\n", "note[^1]\n\n\n────────────────────\n\n[^1] Text.\n"},
+		{"default is gfm", nil, "~~gone~~\n", "gone\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := render(t, Options{Extensions: tt.extensions}, tt.source); got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateExtensions(t *testing.T) {
+	t.Parallel()
+
+	if err := ValidateExtensions([]string{"gfm", "footnote"}); err != nil {
+		t.Errorf("ValidateExtensions() of known extensions returned error: %v", err)
+	}
+	if err := ValidateExtensions([]string{"gfm", "mermaid"}); err == nil || !strings.Contains(err.Error(), `"mermaid"`) {
+		t.Errorf("ValidateExtensions() error = %v, want it to name the unknown extension", err)
+	}
+}
@@ -0,0 +1,79 @@
+package renderer
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizeText makes text from a document safe to write to a terminal.
+// Control characters, which could start escape sequences that retitle the
+// window, write to the clipboard or overwrite what was printed, are replaced
+// with the symbols of the Unicode Control Pictures block: ESC shows as ␛.
+// C1 controls and bytes that aren't valid UTF-8 become �. Tabs, newlines and
+// the carriage returns of CRLF line endings are kept.
+func SanitizeText(text string) string {
+	if isSafeText(text) {
+		return text
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + 16)
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b.WriteRune(utf8.RuneError)
+		case r == '\t' || r == '\n':
+			b.WriteRune(r)
+		case r == '\r' && strings.HasPrefix(text[i+1:], "\n"):
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteRune(0x2400 + r)
+		case r == 0x7f:
+			b.WriteRune('␡')
+		case r >= 0x80 && r <= 0x9f:
+			b.WriteRune(utf8.RuneError)
+		default:
+			b.WriteString(text[i : i+size])
+		}
+		i += size
+	}
+	return b.String()
+}
+
+// isSafeText reports whether text has nothing for SanitizeText to replace
+func isSafeText(text string) bool {
+	for i, r := range text {
+		switch {
+		case r == '\t' || r == '\n':
+		case r == '\r':
+			if !strings.HasPrefix(text[i+1:], "\n") {
+				return false
+			}
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r <= 0x9f):
+			return false
+		case r == utf8.RuneError:
+			// An invalid byte, or a replacement character already there
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeLineEndings turns the bare carriage returns of old Mac line
+// endings into newlines, so they break lines instead of showing as ␍.
+// CRLF line endings are left to the parser.
+func normalizeLineEndings(content []byte) []byte {
+	if !bytes.ContainsRune(content, '\r') {
+		return content
+	}
+	normalized := make([]byte, len(content))
+	for i, c := range content {
+		if c == '\r' && (i+1 == len(content) || content[i+1] != '\n') {
+			c = '\n'
+		}
+		normalized[i] = c
+	}
+	return normalized
+}
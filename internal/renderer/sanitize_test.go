@@ -0,0 +1,104 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizeText(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain", "plain text, ünïcödé and 日本語", "plain text, ünïcödé and 日本語"},
+		{"tabs and newlines", "a\tb\nc\r\n", "a\tb\nc\r\n"},
+		{"bare carriage return", "progress\rdone", "progress␍done"},
+		{"CSI", "\x1b[31mred\x1b[0m", "␛[31mred␛[0m"},
+		{"OSC title", "\x1b]0;pwned\x07", "␛]0;pwned␇"},
+		{"OSC 52 clipboard", "\x1b]52;c;cm0gLXJm\x1b\\", "␛]52;c;cm0gLXJm␛\\"},
+		{"C0 controls", "\x00\x01\x08\x0b\x0c\x1f", "␀␁␈␋␌␟"},
+		{"DEL", "a\x7fb", "a␡b"},
+		{"C1 CSI", "\u009b31m", "�31m"},
+		{"C1 OSC and ST", "\u009d0;x\u009c", "�0;x�"},
+		{"invalid UTF-8", "a\xffb\xc2", "a�b�"},
+		{"replacement character is kept", "a�b", "a�b"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := SanitizeText(tt.text)
+			if got != tt.want {
+				t.Errorf("SanitizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if again := SanitizeText(got); again != got {
+				t.Errorf("SanitizeText(%q) = %q, want sanitized text unchanged", got, again)
+			}
+		})
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"a\nb", "a\nb"},
+		{"a\r\nb\r\n", "a\r\nb\r\n"},
+		{"a\rb\r", "a\nb\n"},
+		{"a\r\rb", "a\n\nb"},
+	}
+
+	for _, tt := range tests {
+		if got := string(normalizeLineEndings([]byte(tt.text))); got != tt.want {
+			t.Errorf("normalizeLineEndings(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSanitized(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{"text", "a \x1b[2Jb\n", "a ␛[2Jb\n"},
+		{"code block", "```\n\x1b]0;title\x07\n```\n", "\n  ␛]0;title␇\n"},
+		{"link destination", "[x](http://a\x1b[31m)\n", "x (http://a␛[31m)\n"},
+		// Entities are never decoded into control characters
+		{"decimal entity in destination", "[x](http://a&#27;[31m)\n", "x (http://a&#27;[31m)\n"},
+		{"hex entity in destination", "[x](<http://a&#x9b;b>)\n", "x (http://a&#x9b;b)\n"},
+		{"entity in text", "a &#27;[31m b &#x1b; c &#155;\n", "a &#27;[31m b &#x1b; c &#155;\n"},
+		{"old Mac line endings", "one\rtwo\r\rthree\r", "one two\n\nthree\n"},
+		{"Windows line endings", "one\r\ntwo\r\n", "one two\n"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := render(t, Options{}, tt.source)
+			if got != tt.want {
+				t.Errorf("render(%q) = %q, want %q", tt.source, got, tt.want)
+			}
+			if strings.ContainsAny(got, "\x1b\x07\u009b") {
+				t.Errorf("render(%q) = %q lets control characters through", tt.source, got)
+			}
+		})
+	}
+
+	// Trusted documents keep their escape sequences
+	if got := render(t, Options{AllowRawEscapes: true}, "\x1b[31mred\x1b[0m\n"); got != "\x1b[31mred\x1b[0m\n" {
+		t.Errorf("render() with raw escapes allowed = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package renderer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Heading is a heading of a markdown document together with the extent of
+// the section it introduces
+type Heading struct {
+	Level  int
+	Title  string
+	Anchor string
+	// Line is the 1-based source line the heading starts on
+	Line int
+	// Start and End are the byte offsets of the section in the source: from
+	// the heading up to the next heading of the same or a higher level
+	Start int
+	End   int
+}
+
+// Parse parses source with the same parser and extensions used for rendering
+func (r *Renderer) Parse(source []byte) ast.Node {
+	return r.goldmark.Parser().Parse(text.NewReader(source))
+}
+
+// Headings parses source and returns its headings in document order, with
+// the anchors GitHub-style auto heading IDs would give them
+func (r *Renderer) Headings(source []byte) []Heading {
+	doc := r.Parse(source)
+
+	var headings []Heading
+	for node := doc.FirstChild(); node != nil; node = node.NextSibling() {
+		heading, ok := node.(*ast.Heading)
+		// Empty headings carry no source position, and no title to link to
+		if !ok || heading.Lines().Len() == 0 {
+			continue
+		}
+		start := lineStart(source, heading.Lines().At(0).Start)
+
+		anchor := ""
+		if id, ok := heading.AttributeString("id"); ok {
+			if b, ok := id.([]byte); ok {
+				anchor = string(b)
+			}
+		}
+
+		headings = append(headings, Heading{
+			Level:  heading.Level,
+			Title:  string(heading.Text(source)),
+			Anchor: anchor,
+			Line:   bytes.Count(source[:start], []byte("\n")) + 1,
+			Start:  start,
+			End:    len(source),
+		})
+	}
+
+	for i := range headings {
+		for j := i + 1; j < len(headings); j++ {
+			if headings[j].Level <= headings[i].Level {
+				headings[i].End = headings[j].Start
+				break
+			}
+		}
+	}
+	return headings
+}
+
+// Section returns the part of source under the heading with the given anchor
+func (r *Renderer) Section(source []byte, anchor string) ([]byte, error) {
+	for _, heading := range r.Headings(source) {
+		if heading.Anchor == anchor {
+			return source[heading.Start:heading.End], nil
+		}
+	}
+	return nil, fmt.Errorf("no section with anchor #%s", anchor)
+}
+
+// lineStart returns the offset of the beginning of the line containing offset
+func lineStart(source []byte, offset int) int {
+	if offset > len(source) {
+		offset = len(source)
+	}
+	return bytes.LastIndexByte(source[:offset], '\n') + 1
+}
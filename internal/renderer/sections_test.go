@@ -0,0 +1,96 @@
+package renderer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/theme"
+)
+
+const sectionsSource = `# Guide
+
+Intro.
+
+## Install steps
+
+Run it.
+
+### From source
+
+Build it.
+
+## Usage
+
+Use it.
+`
+
+func TestHeadings(t *testing.T) {
+	t.Parallel()
+
+	r := New(theme.NewWithBackground(theme.BackgroundDark))
+	headings := r.Headings([]byte(sectionsSource))
+
+	want := []struct {
+		level   int
+		title   string
+		anchor  string
+		line    int
+		section string
+	}{
+		{1, "Guide", "guide", 1, sectionsSource},
+		{2, "Install steps", "install-steps", 5, "## Install steps\n\nRun it.\n\n### From source\n\nBuild it.\n\n"},
+		{3, "From source", "from-source", 9, "### From source\n\nBuild it.\n\n"},
+		{2, "Usage", "usage", 13, "## Usage\n\nUse it.\n"},
+	}
+	if len(headings) != len(want) {
+		t.Fatalf("Headings() returned %d headings, want %d: %+v", len(headings), len(want), headings)
+	}
+	for i, w := range want {
+		h := headings[i]
+		if h.Level != w.level || h.Title != w.title || h.Anchor != w.anchor || h.Line != w.line {
+			t.Errorf("heading %d = %+v, want level %d %q #%s on line %d", i, h, w.level, w.title, w.anchor, w.line)
+		}
+		if got := sectionsSource[h.Start:h.End]; got != w.section {
+			t.Errorf("section of %q = %q, want %q", h.Title, got, w.section)
+		}
+	}
+}
+
+func TestSection(t *testing.T) {
+	t.Parallel()
+
+	r := New(theme.NewWithBackground(theme.BackgroundDark))
+
+	tests := []struct {
+		anchor  string
+		want    string
+		wantErr string
+	}{
+		{anchor: "from-source", want: "### From source\n\nBuild it.\n\n"},
+		{anchor: "usage", want: "## Usage\n\nUse it.\n"},
+		{anchor: "Usage", wantErr: "no section with anchor #Usage"},
+		{anchor: "missing", wantErr: "no section with anchor #missing"},
+		{anchor: "", wantErr: "no section with anchor #"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.anchor, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := r.Section([]byte(sectionsSource), tt.anchor)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Section(%q) error = %v, want %q", tt.anchor, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Section(%q) returned error: %v", tt.anchor, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Section(%q) = %q, want %q", tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package renderer
 
 import (
+	"regexp"
 	"strings"
 	"unicode/utf8"
 )
@@ -102,4 +103,80 @@ func TrimTrailingWhitespace(text string) string {
 func EnsureTrailingNewline(text string) string {
 	text = strings.TrimRight(text, "\n")
 	return text + "\n"
-}
\ No newline at end of file
+}
+
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)`)
+
+// StripANSI removes ANSI escape sequences from text
+func StripANSI(text string) string {
+	return ansiPattern.ReplaceAllString(text, "")
+}
+
+// VisibleWidth returns the number of terminal columns text takes up, ignoring
+// ANSI escape sequences and counting wide characters such as CJK twice
+func VisibleWidth(text string) int {
+	return stringWidth(StripANSI(text))
+}
+
+// TruncateANSI cuts text to at most width terminal columns while keeping
+// escape sequences intact, resetting styles if anything was cut
+func TruncateANSI(text string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if VisibleWidth(text) <= width {
+		return text
+	}
+
+	var b strings.Builder
+	visible := 0
+	for len(text) > 0 {
+		if loc := ansiPattern.FindStringIndex(text); loc != nil && loc[0] == 0 {
+			b.WriteString(text[:loc[1]])
+			text = text[loc[1]:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(text)
+		if visible+runeWidth(r) > width {
+			break
+		}
+		b.WriteRune(r)
+		text = text[size:]
+		visible += runeWidth(r)
+	}
+	b.WriteString(Reset)
+	return b.String()
+}
+
+// WrapANSI word-wraps text to width visible columns, ignoring ANSI escape
+// sequences when measuring. Every line after the first, including those
+// after existing newlines, is prefixed with indent.
+func WrapANSI(text string, width int, indent string) string {
+	if width <= 0 {
+		return text
+	}
+
+	var b strings.Builder
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteString("\n" + indent)
+		}
+
+		column := 0
+		for j, word := range strings.Split(line, " ") {
+			wordWidth := VisibleWidth(word)
+			if j > 0 {
+				if column > 0 && wordWidth > 0 && column+1+wordWidth > width {
+					b.WriteString("\n" + indent)
+					column = 0
+				} else {
+					b.WriteString(" ")
+					column++
+				}
+			}
+			b.WriteString(word)
+			column += wordWidth
+		}
+	}
+	return b.String()
+}
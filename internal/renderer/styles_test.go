@@ -0,0 +1,49 @@
+package renderer
+
+import "testing"
+
+func TestVisibleWidth(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"plain", 5},
+		{"\033[1mbold\033[0m", 4},
+		{"日本語", 6},
+		{"한국어 text", 11},
+		{"é", 1},
+		{"\033]8;;https://a.example\033\\link\033]8;;\033\\", 4},
+	}
+
+	for _, tt := range tests {
+		if got := VisibleWidth(tt.text); got != tt.want {
+			t.Errorf("VisibleWidth(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTruncateANSI(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"truncated", 5, "trunc" + Reset},
+		{"\033[1mbold text\033[0m", 4, "\033[1mbold" + Reset},
+		// A wide character that doesn't fit is left out whole
+		{"日本語", 5, "日本" + Reset},
+		{"anything", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := TruncateANSI(tt.text, tt.width); got != tt.want {
+			t.Errorf("TruncateANSI(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package search finds matches in the content of markdown documents, as
+// opposed to their markup, and reports them with their heading path
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Scope selects the kinds of content to search; scopes can be combined
+type Scope uint8
+
+const (
+	// ScopeText is the visible text of the document outside code blocks,
+	// including headings and inline code
+	ScopeText Scope = 1 << iota
+	// ScopeCode is the content of code blocks
+	ScopeCode
+	// ScopeHeadings is the text of headings
+	ScopeHeadings
+	// ScopeLinks is the destinations of links and images
+	ScopeLinks
+)
+
+// scopeNames maps scope names to scopes, in display order
+var scopeNames = []struct {
+	name  string
+	scope Scope
+}{
+	{"text", ScopeText},
+	{"code", ScopeCode},
+	{"headings", ScopeHeadings},
+	{"links", ScopeLinks},
+}
+
+// ParseScope parses a comma separated list of scope names
+func ParseScope(value string) (Scope, error) {
+	var scope Scope
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		found := false
+		for _, s := range scopeNames {
+			if item == s.name || item == strings.TrimSuffix(s.name, "s") {
+				scope |= s.scope
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("invalid search scope %q (want text, code, headings or links)", item)
+		}
+	}
+	if scope == 0 {
+		scope = ScopeText
+	}
+	return scope, nil
+}
+
+// String returns the scope as a comma separated list of names
+func (s Scope) String() string {
+	var names []string
+	for _, candidate := range scopeNames {
+		if s&candidate.scope != 0 {
+			names = append(names, candidate.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Match is a line of content containing at least one match
+type Match struct {
+	// Line is the 1-based source line
+	Line int
+	// Breadcrumb holds the titles of the headings the line is under, from
+	// the outermost in
+	Breadcrumb []string
+	// Text is the content of the line, without markup
+	Text string
+	// Ranges are the byte ranges of the matches within Text
+	Ranges [][2]int
+	// Scope is the kind of content matched
+	Scope Scope
+}
+
+// unit is a line of searchable content
+type unit struct {
+	line       int
+	text       string
+	scope      Scope
+	breadcrumb []string
+}
+
+// Search parses source like r does and returns the lines of content in
+// scope that pattern matches, in document order
+func Search(r *renderer.Renderer, source []byte, pattern *regexp.Regexp, scope Scope) []Match {
+	var matches []Match
+	for _, u := range collect(r.Parse(source), source) {
+		if u.scope&scope == 0 {
+			continue
+		}
+		locations := pattern.FindAllStringIndex(u.text, -1)
+		if len(locations) == 0 {
+			continue
+		}
+
+		ranges := make([][2]int, 0, len(locations))
+		for _, loc := range locations {
+			if loc[0] < loc[1] {
+				ranges = append(ranges, [2]int{loc[0], loc[1]})
+			}
+		}
+		if len(ranges) == 0 {
+			// Only empty matches, as from a pattern like "x*"
+			continue
+		}
+		matches = append(matches, Match{
+			Line:       u.line,
+			Breadcrumb: u.breadcrumb,
+			Text:       u.text,
+			Ranges:     ranges,
+			Scope:      u.scope,
+		})
+	}
+	return matches
+}
+
+// collector gathers the searchable lines of a document
+type collector struct {
+	source   []byte
+	units    []unit
+	headings []*ast.Heading
+	titles   []string
+}
+
+func collect(doc ast.Node, source []byte) []unit {
+	c := &collector{source: source}
+	_ = ast.Walk(doc, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.Heading:
+			for len(c.headings) > 0 && c.headings[len(c.headings)-1].Level >= n.Level {
+				c.headings = c.headings[:len(c.headings)-1]
+				c.titles = c.titles[:len(c.titles)-1]
+			}
+			c.headings = append(c.headings, n)
+			c.titles = append(c.titles, string(n.Text(source)))
+			c.addInline(n, ScopeText|ScopeHeadings)
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph, *ast.TextBlock, *extast.TableCell:
+			c.addInline(n, ScopeText)
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				text := strings.TrimRight(string(segment.Value(source)), "\r\n")
+				c.add(c.lineOf(segment.Start), text, ScopeCode)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Link destinations are added after the text of their line
+	sort.SliceStable(c.units, func(i, j int) bool { return c.units[i].line < c.units[j].line })
+	return c.units
+}
+
+func (c *collector) add(line int, text string, scope Scope) {
+	breadcrumb := make([]string, len(c.titles))
+	copy(breadcrumb, c.titles)
+	c.units = append(c.units, unit{line: line, text: text, scope: scope, breadcrumb: breadcrumb})
+}
+
+// addInline adds the text of a block's inline content line by line, and the
+// destinations of the links in it
+func (c *collector) addInline(block ast.Node, scope Scope) {
+	if block.Lines().Len() == 0 {
+		return
+	}
+
+	var lines []int
+	texts := map[int]*strings.Builder{}
+	line := c.lineOf(block.Lines().At(0).Start)
+	write := func(s string) {
+		b, ok := texts[line]
+		if !ok {
+			b = &strings.Builder{}
+			texts[line] = b
+			lines = append(lines, line)
+		}
+		b.WriteString(s)
+	}
+
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+			switch n := child.(type) {
+			case *ast.Text:
+				line = c.lineOf(n.Segment.Start)
+				write(string(n.Segment.Value(c.source)))
+			case *ast.String:
+				write(string(n.Value))
+			case *ast.RawHTML:
+				// Markup, not content
+			case *ast.AutoLink:
+				write(string(n.Label(c.source)))
+				c.add(line, string(n.URL(c.source)), ScopeLinks)
+			case *ast.Link:
+				walk(n)
+				c.add(line, string(n.Destination), ScopeLinks)
+			case *ast.Image:
+				walk(n)
+				c.add(line, string(n.Destination), ScopeLinks)
+			default:
+				walk(child)
+			}
+		}
+	}
+	walk(block)
+
+	for _, l := range lines {
+		if text := strings.TrimSpace(texts[l].String()); text != "" {
+			c.add(l, text, scope)
+		}
+	}
+}
+
+// lineOf returns the 1-based line of a source offset
+func (c *collector) lineOf(offset int) int {
+	return bytes.Count(c.source[:min(offset, len(c.source))], []byte("\n")) + 1
+}
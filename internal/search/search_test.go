@@ -0,0 +1,151 @@
+package search
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+const document = `# Setup
+
+Install the **package** first.
+
+## Linux
+
+### Packages
+
+Use the package manager, see [apt docs](https://example.com/package).
+
+` + "```sh\napt install package\n```" + `
+
+## macOS
+
+Run ` + "`brew install package`" + `.
+
+<!-- package note -->
+`
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+
+	type result struct {
+		Line       int
+		Breadcrumb string
+		Text       string
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		scope   Scope
+		want    []result
+	}{
+		{
+			name:    "text",
+			pattern: "package",
+			scope:   ScopeText,
+			want: []result{
+				{3, "Setup", "Install the package first."},
+				{9, "Setup › Linux › Packages", "Use the package manager, see apt docs."},
+				{17, "Setup › macOS", "Run brew install package."},
+			},
+		},
+		{
+			name:    "code",
+			pattern: "package",
+			scope:   ScopeCode,
+			want:    []result{{12, "Setup › Linux › Packages", "apt install package"}},
+		},
+		{
+			name:    "headings",
+			pattern: "(?i)package",
+			scope:   ScopeHeadings,
+			want:    []result{{7, "Setup › Linux › Packages", "Packages"}},
+		},
+		{
+			name:    "links",
+			pattern: "package",
+			scope:   ScopeLinks,
+			want:    []result{{9, "Setup › Linux › Packages", "https://example.com/package"}},
+		},
+		{
+			name:    "combined",
+			pattern: "apt",
+			scope:   ScopeText | ScopeCode,
+			want: []result{
+				{9, "Setup › Linux › Packages", "Use the package manager, see apt docs."},
+				{12, "Setup › Linux › Packages", "apt install package"},
+			},
+		},
+		{
+			name:    "markup is not searched",
+			pattern: `\*\*|note`,
+			scope:   ScopeText | ScopeCode | ScopeHeadings | ScopeLinks,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got []result
+			for _, m := range Search(r, []byte(document), regexp.MustCompile(tt.pattern), tt.scope) {
+				got = append(got, result{m.Line, strings.Join(m.Breadcrumb, " › "), m.Text})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchRanges(t *testing.T) {
+	t.Parallel()
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	matches := Search(r, []byte("one two one\n"), regexp.MustCompile("one"), ScopeText)
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	if want := [][2]int{{0, 3}, {8, 11}}; !reflect.DeepEqual(matches[0].Ranges, want) {
+		t.Errorf("Ranges = %v, want %v", matches[0].Ranges, want)
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value   string
+		want    Scope
+		wantErr bool
+	}{
+		{"", ScopeText, false},
+		{"text", ScopeText, false},
+		{"code,headings", ScopeCode | ScopeHeadings, false},
+		{"Link, heading", ScopeLinks | ScopeHeadings, false},
+		{"comments", 0, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.value, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseScope(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseScope(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseScope(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
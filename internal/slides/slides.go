@@ -0,0 +1,159 @@
+// Package slides splits a markdown document into the slides of a
+// presentation
+package slides
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codehakase/md/internal/format"
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Ways to split a document into slides
+const (
+	// SplitAuto splits on thematic breaks if the document has any, and on
+	// headings otherwise
+	SplitAuto = "auto"
+	// SplitRule starts a new slide after each thematic break
+	SplitRule = "rule"
+	// SplitHeading starts a new slide at each level 1 or 2 heading
+	SplitHeading = "heading"
+)
+
+// ParseSplit validates a split mode name
+func ParseSplit(name string) (string, error) {
+	switch name {
+	case SplitAuto, SplitRule, SplitHeading:
+		return name, nil
+	}
+	return "", fmt.Errorf("invalid split mode %q: use %s, %s or %s", name, SplitAuto, SplitRule, SplitHeading)
+}
+
+// Slide is one slide of a presentation
+type Slide struct {
+	// Title is the text of the slide's first heading, if it has one
+	Title string
+	// Notes are the speaker notes of the slide, the HTML comments among its
+	// blocks
+	Notes []string
+	// blocks are the formatted markdown of the slide's top-level blocks
+	blocks []block
+}
+
+// block is a top-level block of a slide. Lists are kept as their items, one
+// per step of the slide.
+type block struct {
+	text  string
+	items []string
+}
+
+// Split parses source like r does and splits it into slides. Front matter is
+// left out, as are the thematic breaks slides are split on.
+func Split(r *renderer.Renderer, source []byte, mode string) []Slide {
+	blocks := format.Blocks(r, source, format.DefaultOptions())
+	if mode == SplitAuto {
+		mode = SplitHeading
+		for _, b := range blocks {
+			if b.Kind == "ThematicBreak" {
+				mode = SplitRule
+				break
+			}
+		}
+	}
+
+	var deck []Slide
+	var current Slide
+	flush := func() {
+		if len(current.blocks) > 0 || len(current.Notes) > 0 {
+			deck = append(deck, current)
+		}
+		current = Slide{}
+	}
+	for _, b := range blocks {
+		switch {
+		case b.Kind == "FrontMatter":
+			continue
+		case b.Kind == "ThematicBreak" && mode == SplitRule:
+			flush()
+			continue
+		case b.Kind == "HTMLBlock" && isComment(b.Text):
+			current.Notes = append(current.Notes, commentText(b.Text))
+			continue
+		case b.Kind == "Heading":
+			level := len(b.Text) - len(strings.TrimLeft(b.Text, "#"))
+			if mode == SplitHeading && level <= 2 {
+				flush()
+			}
+			if current.Title == "" {
+				current.Title = strings.TrimSpace(b.Text[level:])
+			}
+		}
+
+		if b.Kind == "List" {
+			current.blocks = append(current.blocks, block{text: b.Text, items: listItems(b.Text)})
+		} else {
+			current.blocks = append(current.blocks, block{text: b.Text})
+		}
+	}
+	flush()
+	return deck
+}
+
+// Steps returns the number of steps of a slide revealed item by item: one
+// for the slide without any list items, and one more for each item
+func (s Slide) Steps() int {
+	steps := 1
+	for _, b := range s.blocks {
+		steps += len(b.items)
+	}
+	return steps
+}
+
+// Markdown returns the markdown of the slide with the first step list items
+// shown. The blocks after an item that isn't shown yet are hidden too, so a
+// slide unfolds in reading order. A negative step shows the whole slide.
+func (s Slide) Markdown(step int) string {
+	var parts []string
+	for _, b := range s.blocks {
+		if step < 0 || len(b.items) == 0 {
+			parts = append(parts, b.text)
+			continue
+		}
+		shown := min(step, len(b.items))
+		if shown > 0 {
+			parts = append(parts, strings.TrimRight(strings.Join(b.items[:shown], "\n"), "\n"))
+		}
+		step -= shown
+		if shown < len(b.items) {
+			break
+		}
+	}
+	return strings.Join(parts, "\n\n") + "\n"
+}
+
+// listItems splits a formatted list into its items. Formatting indents
+// everything but the markers of the list's own items, so an item starts at
+// each line that isn't indented.
+func listItems(list string) []string {
+	var items []string
+	for _, line := range strings.Split(list, "\n") {
+		if len(items) == 0 || (line != "" && line[0] != ' ') {
+			items = append(items, line)
+			continue
+		}
+		items[len(items)-1] += "\n" + line
+	}
+	return items
+}
+
+func isComment(html string) bool {
+	html = strings.TrimSpace(html)
+	return strings.HasPrefix(html, "<!--") && strings.HasSuffix(html, "-->")
+}
+
+// commentText returns the text of an HTML comment
+func commentText(html string) string {
+	html = strings.TrimSpace(html)
+	return strings.TrimSpace(html[len("<!--") : len(html)-len("-->")])
+}
@@ -0,0 +1,140 @@
+package slides
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		source     string
+		mode       string
+		wantTitles []string
+		wantSlides []string
+	}{
+		{
+			name:       "rules",
+			source:     "# Deck\n\nIntro.\n\n---\n\n## Second\n\nText.\n\n---\n\nNo heading.\n",
+			mode:       SplitAuto,
+			wantTitles: []string{"Deck", "Second", ""},
+			wantSlides: []string{"# Deck\n\nIntro.\n", "## Second\n\nText.\n", "No heading.\n"},
+		},
+		{
+			name:       "headings",
+			source:     "Preface.\n\n# One\n\nA.\n\n### Detail\n\nB.\n\n## Two\n\nC.\n",
+			mode:       SplitAuto,
+			wantTitles: []string{"", "One", "Two"},
+			wantSlides: []string{"Preface.\n", "# One\n\nA.\n\n### Detail\n\nB.\n", "## Two\n\nC.\n"},
+		},
+		{
+			name:       "headings with rules",
+			source:     "# One\n\nA.\n\n---\n\n# Two\n",
+			mode:       SplitHeading,
+			wantTitles: []string{"One", "Two"},
+			wantSlides: []string{"# One\n\nA.\n\n---\n", "# Two\n"},
+		},
+		{
+			name:       "rules with headings",
+			source:     "# One\n\n# Two\n\n---\n\nThree.\n",
+			mode:       SplitRule,
+			wantTitles: []string{"One", ""},
+			wantSlides: []string{"# One\n\n# Two\n", "Three.\n"},
+		},
+		{
+			name:       "front matter and empty slides",
+			source:     "---\ntitle: Deck\n---\n\n---\n\nOnly.\n\n---\n",
+			mode:       SplitAuto,
+			wantTitles: []string{""},
+			wantSlides: []string{"Only.\n"},
+		},
+	}
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var titles, slides []string
+			for _, slide := range Split(r, []byte(tt.source), tt.mode) {
+				titles = append(titles, slide.Title)
+				slides = append(slides, slide.Markdown(-1))
+			}
+			if !reflect.DeepEqual(titles, tt.wantTitles) {
+				t.Errorf("titles = %q, want %q", titles, tt.wantTitles)
+			}
+			if !reflect.DeepEqual(slides, tt.wantSlides) {
+				t.Errorf("slides = %q, want %q", slides, tt.wantSlides)
+			}
+		})
+	}
+}
+
+func TestNotes(t *testing.T) {
+	t.Parallel()
+
+	source := "# Slide\n\n<!-- Mention the demo -->\n\nText.\n\n<!--\nSecond note\n-->\n\n---\n\nNext.\n"
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	deck := Split(r, []byte(source), SplitAuto)
+	if len(deck) != 2 {
+		t.Fatalf("Split() = %d slides, want 2", len(deck))
+	}
+	if want := []string{"Mention the demo", "Second note"}; !reflect.DeepEqual(deck[0].Notes, want) {
+		t.Errorf("Notes = %q, want %q", deck[0].Notes, want)
+	}
+	if got, want := deck[0].Markdown(-1), "# Slide\n\nText.\n"; got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+	if deck[1].Notes != nil {
+		t.Errorf("second slide Notes = %q, want none", deck[1].Notes)
+	}
+}
+
+func TestSteps(t *testing.T) {
+	t.Parallel()
+
+	source := "# Plan\n\n- one\n- two\n  - nested\n\nMiddle.\n\n1. three\n\nEnd.\n"
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	deck := Split(r, []byte(source), SplitAuto)
+	if len(deck) != 1 {
+		t.Fatalf("Split() = %d slides, want 1", len(deck))
+	}
+	slide := deck[0]
+
+	want := []string{
+		"# Plan\n",
+		"# Plan\n\n- one\n",
+		"# Plan\n\n- one\n- two\n  - nested\n\nMiddle.\n",
+		"# Plan\n\n- one\n- two\n  - nested\n\nMiddle.\n\n1. three\n\nEnd.\n",
+	}
+	if got := slide.Steps(); got != len(want) {
+		t.Fatalf("Steps() = %d, want %d", got, len(want))
+	}
+	for step, w := range want {
+		if got := slide.Markdown(step); got != w {
+			t.Errorf("Markdown(%d) = %q, want %q", step, got, w)
+		}
+	}
+	if got := slide.Markdown(-1); got != want[len(want)-1] {
+		t.Errorf("Markdown(-1) = %q, want %q", got, want[len(want)-1])
+	}
+}
+
+func TestParseSplit(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{SplitAuto, SplitRule, SplitHeading} {
+		if _, err := ParseSplit(name); err != nil {
+			t.Errorf("ParseSplit(%q) error = %v", name, err)
+		}
+	}
+	if _, err := ParseSplit("page"); err == nil {
+		t.Error("ParseSplit(\"page\") succeeded, want an error")
+	}
+}
@@ -0,0 +1,290 @@
+// Package stats computes word counts, reading time and readability scores
+// for markdown documents, counting prose only
+package stats
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// DefaultWordsPerMinute is the reading speed used for reading time estimates
+const DefaultWordsPerMinute = 200
+
+// Stats are the metrics of a document or a section of one
+type Stats struct {
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	Sentences  int `json:"sentences"`
+	Syllables  int `json:"syllables"`
+	Headings   int `json:"headings"`
+	Links      int `json:"links"`
+	Images     int `json:"images"`
+	// CodeBlocks counts code blocks by language, "none" for blocks without one
+	CodeBlocks map[string]int `json:"code_blocks"`
+
+	ReadingMinutes     float64 `json:"reading_minutes"`
+	FleschReadingEase  float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+}
+
+// Section holds the metrics of the content between a heading and the next
+// heading of any level. Content before the first heading forms a section
+// with an empty title.
+type Section struct {
+	Title  string `json:"title"`
+	Anchor string `json:"anchor,omitempty"`
+	Level  int    `json:"level"`
+	Line   int    `json:"line"`
+	Stats
+}
+
+// Report holds the metrics of a document and of each of its sections
+type Report struct {
+	File string `json:"file"`
+	Stats
+	Sections []Section `json:"sections"`
+}
+
+// Analyze parses source like r does and computes its metrics. Code blocks,
+// inline code and raw HTML are not counted as prose.
+func Analyze(r *renderer.Renderer, source []byte, wordsPerMinute int) *Report {
+	if wordsPerMinute <= 0 {
+		wordsPerMinute = DefaultWordsPerMinute
+	}
+
+	report := &Report{Stats: Stats{CodeBlocks: map[string]int{}}}
+	sections := []*Section{{Line: 1, Stats: Stats{CodeBlocks: map[string]int{}}}}
+	current := sections[0]
+	counters := func() []*Stats { return []*Stats{&report.Stats, &current.Stats} }
+
+	_ = ast.Walk(r.Parse(source), func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch n := node.(type) {
+		case *ast.Heading:
+			current = &Section{
+				Title:  string(n.Text(source)),
+				Anchor: headingAnchor(n),
+				Level:  n.Level,
+				Line:   headingLine(n, source),
+				Stats:  Stats{CodeBlocks: map[string]int{}},
+			}
+			sections = append(sections, current)
+			for _, s := range counters() {
+				s.Headings++
+			}
+		case *ast.FencedCodeBlock:
+			language := "none"
+			if n.Info != nil {
+				language = codeLanguage(string(n.Info.Text(source)))
+			}
+			for _, s := range counters() {
+				s.CodeBlocks[language]++
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock:
+			for _, s := range counters() {
+				s.CodeBlocks["none"]++
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.HTMLBlock:
+			return ast.WalkSkipChildren, nil
+		case *ast.Link, *ast.AutoLink:
+			for _, s := range counters() {
+				s.Links++
+			}
+		case *ast.Image:
+			for _, s := range counters() {
+				s.Images++
+			}
+		}
+
+		// Blocks whose children are inline text are counted as a whole
+		if isTextBlock(node) {
+			var text strings.Builder
+			collectText(&text, node, source)
+			for _, s := range counters() {
+				s.addText(text.String())
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	report.finish(wordsPerMinute)
+	for _, section := range sections {
+		if section.Title == "" && section.Level == 0 && section.isEmpty() {
+			continue
+		}
+		section.finish(wordsPerMinute)
+		report.Sections = append(report.Sections, *section)
+	}
+	return report
+}
+
+// isTextBlock reports whether node is a block holding inline content
+func isTextBlock(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.Paragraph, *ast.Heading, *ast.TextBlock, *extast.TableCell:
+		return true
+	}
+	return false
+}
+
+// collectText appends the prose inside node, leaving out inline code, raw
+// HTML, image descriptions and bare URLs
+func collectText(b *strings.Builder, node ast.Node, source []byte) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() || n.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		case *ast.CodeSpan, *ast.RawHTML, *ast.Image, *ast.AutoLink:
+			// Keep the words on either side apart
+			b.WriteByte(' ')
+		default:
+			collectText(b, child, source)
+		}
+	}
+}
+
+// addText counts the words, characters, sentences and syllables of a block
+func (s *Stats) addText(text string) {
+	words := 0
+	for _, word := range strings.Fields(text) {
+		if strings.IndexFunc(word, isWordRune) < 0 {
+			continue
+		}
+		words++
+		s.Syllables += syllables(word)
+	}
+	if words == 0 {
+		return
+	}
+	s.Words += words
+
+	for _, r := range text {
+		if !unicode.IsSpace(r) {
+			s.Characters++
+		}
+	}
+
+	// A block that doesn't end in punctuation, such as a heading or a list
+	// item, still counts as a sentence
+	sentences := 0
+	runes := []rune(text)
+	for i, r := range runes {
+		// Only punctuation followed by a space ends a sentence, which skips
+		// decimals and versions such as 1.5
+		if !isTerminator(r) || (i+1 < len(runes) && isTerminator(runes[i+1])) {
+			continue
+		}
+		if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) || strings.ContainsRune(`"')`, runes[i+1]) {
+			sentences++
+		}
+	}
+	trimmed := strings.TrimRightFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"')`, r)
+	})
+	if last, _ := utf8.DecodeLastRuneInString(trimmed); !isTerminator(last) {
+		sentences++
+	}
+	s.Sentences += sentences
+}
+
+// finish computes the derived metrics
+func (s *Stats) finish(wordsPerMinute int) {
+	s.ReadingMinutes = round(float64(s.Words)/float64(wordsPerMinute), 2)
+	if s.Words == 0 || s.Sentences == 0 {
+		return
+	}
+	wordsPerSentence := float64(s.Words) / float64(s.Sentences)
+	syllablesPerWord := float64(s.Syllables) / float64(s.Words)
+	s.FleschReadingEase = round(206.835-1.015*wordsPerSentence-84.6*syllablesPerWord, 1)
+	s.FleschKincaidGrade = round(0.39*wordsPerSentence+11.8*syllablesPerWord-15.59, 1)
+}
+
+func (s *Stats) isEmpty() bool {
+	return s.Words == 0 && s.Links == 0 && s.Images == 0 && len(s.CodeBlocks) == 0
+}
+
+func isTerminator(r rune) bool {
+	return r == '.' || r == '!' || r == '?'
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// syllables estimates the number of syllables in an English word by counting
+// groups of vowels, ignoring a silent final e
+func syllables(word string) int {
+	word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool { return !unicode.IsLetter(r) }))
+	if word == "" {
+		return 1
+	}
+
+	count := 0
+	previousVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && count > 1 {
+		count--
+	}
+	return max(count, 1)
+}
+
+// codeLanguage returns the language named by a fenced code block's info
+// string, lower-cased, or "none"
+func codeLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return "none"
+	}
+	language, _, _ := strings.Cut(fields[0], "{")
+	if language == "" || strings.Contains(language, "=") {
+		return "none"
+	}
+	return strings.ToLower(language)
+}
+
+// headingAnchor returns the id the parser gave a heading
+func headingAnchor(heading *ast.Heading) string {
+	if id, ok := heading.AttributeString("id"); ok {
+		if b, ok := id.([]byte); ok {
+			return string(b)
+		}
+	}
+	return ""
+}
+
+// headingLine returns the 1-based source line of a heading
+func headingLine(heading *ast.Heading, source []byte) int {
+	if heading.Lines().Len() == 0 {
+		return 0
+	}
+	return bytes.Count(source[:heading.Lines().At(0).Start], []byte("\n")) + 1
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,116 @@
+package stats
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	source := []byte(`Intro text here.
+
+# Guide
+
+The cat sat on the mat. It was happy! See [the docs](https://example.com) and ` + "`inline code`" + `.
+
+` + "```go\nfunc main() { words in code are not counted }\n```" + `
+
+## Install
+
+- Download the binary
+- Run it
+
+![diagram](d.png)
+
+` + "```\nplain\n```\n")
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	report := Analyze(r, source, 100)
+
+	if report.Words != 23 {
+		t.Errorf("Words = %d, want 23", report.Words)
+	}
+	if report.Sentences != 8 {
+		t.Errorf("Sentences = %d, want 8", report.Sentences)
+	}
+	if report.Headings != 2 || report.Links != 1 || report.Images != 1 {
+		t.Errorf("Headings, Links, Images = %d, %d, %d; want 2, 1, 1", report.Headings, report.Links, report.Images)
+	}
+	if want := map[string]int{"go": 1, "none": 1}; !reflect.DeepEqual(report.CodeBlocks, want) {
+		t.Errorf("CodeBlocks = %v, want %v", report.CodeBlocks, want)
+	}
+	if report.ReadingMinutes != 0.23 {
+		t.Errorf("ReadingMinutes = %v, want 0.23", report.ReadingMinutes)
+	}
+
+	var titles []string
+	for _, section := range report.Sections {
+		titles = append(titles, section.Title)
+	}
+	if want := []string{"", "Guide", "Install"}; !reflect.DeepEqual(titles, want) {
+		t.Fatalf("section titles = %q, want %q", titles, want)
+	}
+	install := report.Sections[2]
+	if install.Words != 6 || install.Line != 11 || install.Anchor != "install" || install.CodeBlocks["none"] != 1 {
+		t.Errorf("Install section = %+v", install)
+	}
+}
+
+func TestReadability(t *testing.T) {
+	t.Parallel()
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	simple := Analyze(r, []byte("The cat sat. The dog ran. We had fun.\n"), 0)
+	complex := Analyze(r, []byte("Comprehensive documentation necessitates considerable organizational responsibility and deliberate consideration.\n"), 0)
+
+	if simple.FleschReadingEase <= complex.FleschReadingEase {
+		t.Errorf("reading ease of simple text (%v) should exceed complex text (%v)", simple.FleschReadingEase, complex.FleschReadingEase)
+	}
+	if simple.FleschKincaidGrade >= complex.FleschKincaidGrade {
+		t.Errorf("grade of simple text (%v) should be below complex text (%v)", simple.FleschKincaidGrade, complex.FleschKincaidGrade)
+	}
+}
+
+func TestSyllables(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]int{
+		"cat":           1,
+		"make":          1,
+		"table":         2,
+		"documentation": 5,
+		"rhythm":        1,
+		"Reading,":      2,
+		"42":            1,
+	}
+	for word, want := range tests {
+		if got := syllables(word); got != want {
+			t.Errorf("syllables(%q) = %d, want %d", word, got, want)
+		}
+	}
+}
+
+func TestAddTextSentences(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"One. Two! Three?", 3},
+		{"Version 1.5 is out. Really...", 2},
+		{"A heading", 1},
+		{"He said \"stop.\" Then left", 2},
+	}
+	for _, tt := range tests {
+		var s Stats
+		s.addText(tt.text)
+		if s.Sentences != tt.want {
+			t.Errorf("sentences in %q = %d, want %d", tt.text, s.Sentences, tt.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package structure
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Node is a node of a document's syntax tree
+type Node struct {
+	// Kind is the goldmark node kind, such as Heading or FencedCodeBlock
+	Kind string `json:"kind"`
+	// Position is the source range of the node's content, not counting
+	// markers such as "#" or code fences. Nodes made up by the parser, such
+	// as typographer quotes, have none.
+	Position *Range `json:"position,omitempty"`
+	// Attributes are the HTML attributes of the node, such as heading ids
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Properties are the kind specific fields of the node, such as a heading's
+	// level or a link's destination
+	Properties map[string]interface{} `json:"properties,omitempty"`
+	// Text is the literal content of text, code and HTML nodes
+	Text     string  `json:"text,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// Range is a span of source, from Start up to but not including End
+type Range struct {
+	Start Point `json:"start"`
+	End   Point `json:"end"`
+}
+
+// Point is a position in the source, with a 1-based line and column, the
+// column counted in characters
+type Point struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// AST parses source like r does and returns its syntax tree
+func AST(r *renderer.Renderer, source []byte) *Node {
+	return convert(r.Parse(source), source)
+}
+
+func convert(node ast.Node, source []byte) *Node {
+	n := &Node{
+		Kind:       node.Kind().String(),
+		Properties: properties(node, source),
+		Text:       literal(node, source),
+	}
+	for _, attr := range node.Attributes() {
+		if n.Attributes == nil {
+			n.Attributes = map[string]string{}
+		}
+		if value, ok := attr.Value.([]byte); ok {
+			n.Attributes[string(attr.Name)] = string(value)
+		} else {
+			n.Attributes[string(attr.Name)] = fmt.Sprint(attr.Value)
+		}
+	}
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		n.Children = append(n.Children, convert(child, source))
+	}
+
+	if start, end, ok := span(node, source, n.Children); ok {
+		// Block lines include their line ending; end ranges on the line their
+		// content ends on
+		for end > start && (source[end-1] == '\n' || source[end-1] == '\r') {
+			end--
+		}
+		n.Position = &Range{Start: point(source, start), End: point(source, end)}
+	}
+	return n
+}
+
+// span returns the source offsets a node covers: the whole source for the
+// document, its own segments, or those of its children for containers such
+// as lists and links
+func span(node ast.Node, source []byte, children []*Node) (int, int, bool) {
+	switch n := node.(type) {
+	case *ast.Document:
+		return 0, len(source), true
+	case *ast.Text:
+		return n.Segment.Start, n.Segment.Stop, true
+	case *ast.RawHTML:
+		if n.Segments.Len() > 0 {
+			return n.Segments.At(0).Start, n.Segments.At(n.Segments.Len() - 1).Stop, true
+		}
+	}
+	if node.Type() != ast.TypeInline && node.Lines().Len() > 0 {
+		lines := node.Lines()
+		return lines.At(0).Start, lines.At(lines.Len() - 1).Stop, true
+	}
+
+	start, end, ok := 0, 0, false
+	for _, child := range children {
+		if child.Position == nil {
+			continue
+		}
+		if !ok || child.Position.Start.Offset < start {
+			start = child.Position.Start.Offset
+		}
+		if !ok || child.Position.End.Offset > end {
+			end = child.Position.End.Offset
+		}
+		ok = true
+	}
+	return start, end, ok
+}
+
+// point converts an offset to a Point
+func point(source []byte, offset int) Point {
+	offset = min(max(offset, 0), len(source))
+	lineStart := bytes.LastIndexByte(source[:offset], '\n') + 1
+	return Point{
+		Line:   bytes.Count(source[:lineStart], []byte("\n")) + 1,
+		Column: utf8.RuneCount(source[lineStart:offset]) + 1,
+		Offset: offset,
+	}
+}
+
+// properties returns the kind specific fields of a node
+func properties(node ast.Node, source []byte) map[string]interface{} {
+	p := map[string]interface{}{}
+	switch n := node.(type) {
+	case *ast.Heading:
+		p["level"] = n.Level
+	case *ast.List:
+		p["ordered"] = n.IsOrdered()
+		p["marker"] = string(rune(n.Marker))
+		p["tight"] = n.IsTight
+		if n.IsOrdered() {
+			p["start"] = n.Start
+		}
+	case *ast.ListItem:
+		p["offset"] = n.Offset
+	case *ast.Emphasis:
+		p["level"] = n.Level
+	case *ast.Link:
+		p["destination"] = string(n.Destination)
+		if len(n.Title) > 0 {
+			p["title"] = string(n.Title)
+		}
+	case *ast.Image:
+		p["destination"] = string(n.Destination)
+		if len(n.Title) > 0 {
+			p["title"] = string(n.Title)
+		}
+	case *ast.AutoLink:
+		p["url"] = string(n.URL(source))
+		if n.AutoLinkType == ast.AutoLinkEmail {
+			p["type"] = "email"
+		} else {
+			p["type"] = "url"
+		}
+	case *ast.FencedCodeBlock:
+		if n.Info != nil {
+			p["info"] = string(n.Info.Text(source))
+		}
+		if language := n.Language(source); language != nil {
+			p["language"] = string(language)
+		}
+	case *ast.HTMLBlock:
+		p["html_block_type"] = int(n.HTMLBlockType)
+	case *ast.Text:
+		if n.SoftLineBreak() {
+			p["soft_line_break"] = true
+		}
+		if n.HardLineBreak() {
+			p["hard_line_break"] = true
+		}
+		if n.IsRaw() {
+			p["raw"] = true
+		}
+	case *extast.Table:
+		alignments := make([]string, len(n.Alignments))
+		for i, alignment := range n.Alignments {
+			alignments[i] = alignment.String()
+		}
+		p["alignments"] = alignments
+	case *extast.TableCell:
+		p["alignment"] = n.Alignment.String()
+	case *extast.TaskCheckBox:
+		p["checked"] = n.IsChecked
+	case *extast.Footnote:
+		p["ref"] = string(n.Ref)
+		p["index"] = n.Index
+	case *extast.FootnoteLink:
+		p["index"] = n.Index
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	return p
+}
+
+// literal returns the literal content of text, code and HTML nodes
+func literal(node ast.Node, source []byte) string {
+	switch n := node.(type) {
+	case *ast.Text:
+		return string(n.Segment.Value(source))
+	case *ast.String:
+		return string(n.Value)
+	case *ast.RawHTML:
+		var b bytes.Buffer
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			b.Write(segment.Value(source))
+		}
+		return b.String()
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		return linesText(node, source)
+	case *ast.HTMLBlock:
+		text := linesText(node, source)
+		if n.HasClosure() {
+			text += string(n.ClosureLine.Value(source))
+		}
+		return text
+	}
+	return ""
+}
+
+func linesText(node ast.Node, source []byte) string {
+	var b bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		b.Write(segment.Value(source))
+	}
+	return b.String()
+}
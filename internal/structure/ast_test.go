@@ -0,0 +1,61 @@
+package structure
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestAST(t *testing.T) {
+	t.Parallel()
+
+	source := []byte("# Title\n\nSee [docs](https://example.com \"Docs\").\n\n```go\nfunc main() {}\n```\n\n- [x] done\n")
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	doc := AST(r, source)
+
+	if doc.Kind != "Document" {
+		t.Fatalf("root kind = %q, want Document", doc.Kind)
+	}
+	var kinds []string
+	for _, child := range doc.Children {
+		kinds = append(kinds, child.Kind)
+	}
+	if want := []string{"Heading", "Paragraph", "FencedCodeBlock", "List"}; !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("top level kinds = %q, want %q", kinds, want)
+	}
+
+	heading := doc.Children[0]
+	if heading.Properties["level"] != 1 || heading.Attributes["id"] != "title" {
+		t.Errorf("heading properties = %v, attributes = %v", heading.Properties, heading.Attributes)
+	}
+	if want := (Range{Start: Point{1, 3, 2}, End: Point{1, 8, 7}}); heading.Position == nil || *heading.Position != want {
+		t.Errorf("heading position = %+v, want %+v", heading.Position, want)
+	}
+
+	link := doc.Children[1].Children[1]
+	if link.Kind != "Link" || link.Properties["destination"] != "https://example.com" || link.Properties["title"] != "Docs" {
+		t.Errorf("link = %+v", link)
+	}
+	if link.Position == nil || link.Position.Start != (Point{3, 6, 14}) {
+		t.Errorf("link position = %+v, want the position of its text", link.Position)
+	}
+
+	code := doc.Children[2]
+	if code.Properties["language"] != "go" || code.Text != "func main() {}\n" {
+		t.Errorf("code block properties = %v, text = %q", code.Properties, code.Text)
+	}
+	if code.Position == nil || code.Position.Start.Line != 6 || code.Position.End.Line != 6 {
+		t.Errorf("code block position = %+v, want line 6", code.Position)
+	}
+
+	list := doc.Children[3]
+	if list.Position == nil || list.Position.Start.Line != 9 {
+		t.Errorf("list position = %+v, want line 9 from its items", list.Position)
+	}
+	checkBox := list.Children[0].Children[0].Children[0]
+	if checkBox.Kind != "TaskCheckBox" || checkBox.Properties["checked"] != true {
+		t.Errorf("task check box = %+v", checkBox)
+	}
+}
@@ -0,0 +1,61 @@
+// Package structure describes markdown documents for tooling: their outline
+// of headings and their full syntax tree, parsed with the same configuration
+// as the renderer
+package structure
+
+import (
+	"bytes"
+	"unicode"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// Heading is an entry of a document outline
+type Heading struct {
+	Level  int    `json:"level"`
+	Title  string `json:"title"`
+	Anchor string `json:"anchor"`
+	// StartLine and EndLine are the 1-based source lines of the section the
+	// heading introduces, up to the next heading of the same or a higher
+	// level, without trailing blank lines
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Children are the headings of the subsections
+	Children []*Heading `json:"children"`
+}
+
+// Outline parses source like r does and returns its headings as a tree.
+// A heading that skips levels, such as an h3 directly under an h1, is nested
+// under the closest heading of a lower level.
+func Outline(r *renderer.Renderer, source []byte) []*Heading {
+	roots := []*Heading{}
+	var stack []*Heading
+	for _, h := range r.Headings(source) {
+		heading := &Heading{
+			Level:     h.Level,
+			Title:     h.Title,
+			Anchor:    h.Anchor,
+			StartLine: h.Line,
+			EndLine:   endLine(source, h.Start, h.End),
+			Children:  []*Heading{},
+		}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		stack = append(stack, heading)
+	}
+	return roots
+}
+
+// endLine returns the last line of source[start:end] that isn't blank
+func endLine(source []byte, start, end int) int {
+	content := bytes.TrimRightFunc(source[start:end], unicode.IsSpace)
+	return bytes.Count(source[:start+len(content)], []byte("\n")) + 1
+}
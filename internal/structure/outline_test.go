@@ -0,0 +1,70 @@
+package structure
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestOutline(t *testing.T) {
+	t.Parallel()
+
+	source := []byte(`Intro
+
+# Setup
+
+Text.
+
+## Linux
+
+### Packages
+
+More.
+
+## macOS
+
+Mac.
+
+# Usage
+
+Done.
+`)
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	got := Outline(r, source)
+
+	want := []*Heading{
+		{Level: 1, Title: "Setup", Anchor: "setup", StartLine: 3, EndLine: 15, Children: []*Heading{
+			{Level: 2, Title: "Linux", Anchor: "linux", StartLine: 7, EndLine: 11, Children: []*Heading{
+				{Level: 3, Title: "Packages", Anchor: "packages", StartLine: 9, EndLine: 11, Children: []*Heading{}},
+			}},
+			{Level: 2, Title: "macOS", Anchor: "macos", StartLine: 13, EndLine: 15, Children: []*Heading{}},
+		}},
+		{Level: 1, Title: "Usage", Anchor: "usage", StartLine: 17, EndLine: 19, Children: []*Heading{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Outline() mismatch\n got: %s\nwant: %s", dumpOutline(got), dumpOutline(want))
+	}
+}
+
+func TestOutlineSkippedLevels(t *testing.T) {
+	t.Parallel()
+
+	r := renderer.New(theme.NewWithBackground(theme.BackgroundDark))
+	got := Outline(r, []byte("### Deep\n\n# Top\n\n### Nested\n"))
+
+	if len(got) != 2 || got[0].Title != "Deep" || got[1].Title != "Top" {
+		t.Fatalf("roots = %s, want Deep and Top", dumpOutline(got))
+	}
+	if len(got[1].Children) != 1 || got[1].Children[0].Title != "Nested" {
+		t.Errorf("Top children = %s, want Nested", dumpOutline(got[1].Children))
+	}
+}
+
+func dumpOutline(headings []*Heading) string {
+	b, _ := json.Marshal(headings)
+	return string(b)
+}
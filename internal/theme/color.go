@@ -0,0 +1,243 @@
+package theme
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ColorType identifies how a Color is specified
+type ColorType int
+
+const (
+	// ColorNone means no color is set and the terminal default is used
+	ColorNone ColorType = iota
+	// ColorANSI is one of the 16 basic terminal colors (0-15)
+	ColorANSI
+	// ColorANSI256 is an index into the xterm 256-color palette
+	ColorANSI256
+	// ColorRGB is a 24-bit true color
+	ColorRGB
+)
+
+// Color is a terminal color that can be emitted at any supported color depth
+type Color struct {
+	Type    ColorType
+	Index   uint8
+	R, G, B uint8
+}
+
+// ANSIColor returns one of the 16 basic terminal colors
+func ANSIColor(index uint8) Color {
+	return Color{Type: ColorANSI, Index: index % 16}
+}
+
+// ANSI256Color returns a color from the xterm 256-color palette
+func ANSI256Color(index uint8) Color {
+	return Color{Type: ColorANSI256, Index: index}
+}
+
+// RGBColor returns a 24-bit true color
+func RGBColor(r, g, b uint8) Color {
+	return Color{Type: ColorRGB, R: r, G: g, B: b}
+}
+
+// IsSet reports whether a color was specified
+func (c Color) IsSet() bool {
+	return c.Type != ColorNone
+}
+
+// RGB returns the red, green and blue components of the color, using the
+// default xterm palette for indexed colors
+func (c Color) RGB() (uint8, uint8, uint8) {
+	switch c.Type {
+	case ColorRGB:
+		return c.R, c.G, c.B
+	case ColorANSI:
+		p := ansiPalette[c.Index%16]
+		return p[0], p[1], p[2]
+	case ColorANSI256:
+		return xterm256ToRGB(c.Index)
+	default:
+		return 0, 0, 0
+	}
+}
+
+// Convert returns the nearest color that can be displayed with the given profile
+func (c Color) Convert(profile ColorProfile) Color {
+	if !c.IsSet() || profile == ProfileNone {
+		return Color{}
+	}
+
+	switch profile {
+	case ProfileANSI:
+		if c.Type == ColorANSI {
+			return c
+		}
+		r, g, b := c.RGB()
+		return ANSIColor(nearestANSI(r, g, b))
+	case ProfileANSI256:
+		if c.Type != ColorRGB {
+			return c
+		}
+		return ANSI256Color(nearestANSI256(c.R, c.G, c.B))
+	default:
+		return c
+	}
+}
+
+// sgr returns the SGR parameters selecting the color as foreground or background
+func (c Color) sgr(background bool) string {
+	switch c.Type {
+	case ColorANSI:
+		base := 30
+		if c.Index >= 8 {
+			base = 90 - 8
+		}
+		if background {
+			base += 10
+		}
+		return fmt.Sprintf("%d", base+int(c.Index))
+	case ColorANSI256:
+		if background {
+			return fmt.Sprintf("48;5;%d", c.Index)
+		}
+		return fmt.Sprintf("38;5;%d", c.Index)
+	case ColorRGB:
+		if background {
+			return fmt.Sprintf("48;2;%d;%d;%d", c.R, c.G, c.B)
+		}
+		return fmt.Sprintf("38;2;%d;%d;%d", c.R, c.G, c.B)
+	default:
+		return ""
+	}
+}
+
+// Style describes how a markdown element is displayed
+type Style struct {
+	Foreground    Color
+	Background    Color
+	Bold          bool
+	Italic        bool
+	Underline     bool
+	Strikethrough bool
+}
+
+// Sequence returns the escape sequence that applies the style with the given
+// color profile. With ProfileNone no escape sequences are produced at all.
+func (s Style) Sequence(profile ColorProfile) string {
+	if profile == ProfileNone {
+		return ""
+	}
+
+	var params []string
+	if s.Bold {
+		params = append(params, "1")
+	}
+	if s.Italic {
+		params = append(params, "3")
+	}
+	if s.Underline {
+		params = append(params, "4")
+	}
+	if s.Strikethrough {
+		params = append(params, "9")
+	}
+	if fg := s.Foreground.Convert(profile); fg.IsSet() {
+		params = append(params, fg.sgr(false))
+	}
+	if bg := s.Background.Convert(profile); bg.IsSet() {
+		params = append(params, bg.sgr(true))
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
+
+// ansiPalette holds the default xterm RGB values of the 16 basic colors
+var ansiPalette = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels are the component values of the 6x6x6 color cube in the 256-color palette
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func xterm256ToRGB(index uint8) (uint8, uint8, uint8) {
+	switch {
+	case index < 16:
+		p := ansiPalette[index]
+		return p[0], p[1], p[2]
+	case index < 232:
+		i := index - 16
+		return cubeLevels[i/36], cubeLevels[(i/6)%6], cubeLevels[i%6]
+	default:
+		gray := 8 + (index-232)*10
+		return gray, gray, gray
+	}
+}
+
+// nearestANSI256 returns the closest color cube or grayscale entry of the
+// 256-color palette. The first 16 entries are skipped because terminals
+// commonly redefine them.
+func nearestANSI256(r, g, b uint8) uint8 {
+	cube := func(v uint8) uint8 {
+		best := uint8(0)
+		for i, level := range cubeLevels {
+			if absDiff(v, level) < absDiff(v, cubeLevels[best]) {
+				best = uint8(i)
+			}
+		}
+		return best
+	}
+	ci := 16 + 36*cube(r) + 6*cube(g) + cube(b)
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	gi := uint8(232)
+	if avg > 8 {
+		step := (avg - 8 + 5) / 10
+		if step > 23 {
+			step = 23
+		}
+		gi = uint8(232 + step)
+	}
+
+	cr, cg, cb := xterm256ToRGB(ci)
+	gr, gg, gb := xterm256ToRGB(gi)
+	if colorDistance(r, g, b, gr, gg, gb) < colorDistance(r, g, b, cr, cg, cb) {
+		return gi
+	}
+	return ci
+}
+
+// nearestANSI returns the closest of the 16 basic colors
+func nearestANSI(r, g, b uint8) uint8 {
+	best := 0
+	bestDistance := -1
+	for i, p := range ansiPalette {
+		d := colorDistance(r, g, b, p[0], p[1], p[2])
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return uint8(best)
+}
+
+// colorDistance returns a perceptually weighted squared distance between two colors
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) int {
+	rmean := (int(r1) + int(r2)) / 2
+	dr := int(r1) - int(r2)
+	dg := int(g1) - int(g2)
+	db := int(b1) - int(b2)
+	return ((512+rmean)*dr*dr)>>8 + 4*dg*dg + ((767-rmean)*db*db)>>8
+}
+
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,198 @@
+package theme
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestStyleSequence(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		style    Style
+		profile  ColorProfile
+		expected string
+	}{
+		{"bold bright cyan", Style{Bold: true, Foreground: ANSIColor(14)}, ProfileANSI256, "\033[1;96m"},
+		{"underlined blue", Style{Underline: true, Foreground: ANSIColor(4)}, ProfileANSI256, "\033[4;34m"},
+		{"256 color kept", Style{Foreground: ANSI256Color(208)}, ProfileANSI256, "\033[38;5;208m"},
+		{"256 color downsampled", Style{Foreground: ANSI256Color(196)}, ProfileANSI, "\033[91m"},
+		{"rgb kept", Style{Foreground: RGBColor(1, 2, 3)}, ProfileTrueColor, "\033[38;2;1;2;3m"},
+		{"rgb to 256", Style{Foreground: RGBColor(255, 135, 0)}, ProfileANSI256, "\033[38;5;208m"},
+		{"rgb gray to 256", Style{Foreground: RGBColor(128, 128, 128)}, ProfileANSI256, "\033[38;5;244m"},
+		{"background", Style{Background: ANSIColor(9)}, ProfileANSI, "\033[101m"},
+		{"no color profile", Style{Bold: true, Foreground: ANSIColor(1)}, ProfileNone, ""},
+		{"empty style", Style{}, ProfileTrueColor, ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tt.style.Sequence(tt.profile); got != tt.expected {
+				t.Errorf("Sequence() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestThemeManagerColorProfile(t *testing.T) {
+	t.Parallel()
+
+	tm := NewWithBackground(BackgroundDark)
+	if got := tm.GetColor(Header1); got != "\033[1;96m" {
+		t.Errorf("default GetColor(Header1) = %q, want %q", got, "\033[1;96m")
+	}
+
+	tm.SetColorProfile(ProfileNone)
+	if tm.SupportsColor() {
+		t.Error("SupportsColor() should be false without colors")
+	}
+	if got := tm.Style("text", Code); got != "text" {
+		t.Errorf("Style() without colors = %q, want plain text", got)
+	}
+
+	tm.SetColorProfile(ProfileANSI)
+	if got := tm.GetColor(Code); got != "\033[33m" {
+		t.Errorf("GetColor(Code) with 16 colors = %q, want %q", got, "\033[33m")
+	}
+}
+
+func TestParseColorMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    ColorMode
+		wantErr bool
+	}{
+		{"", ColorAuto, false},
+		{"auto", ColorAuto, false},
+		{"ALWAYS", ColorAlways, false},
+		{"never", ColorNever, false},
+		{"sometimes", ColorAuto, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColorMode(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseColorMode(%q) = %v, %v; want %v, error %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestDetectColorProfile(t *testing.T) {
+	t.Setenv("TERMINFO", t.TempDir())
+	t.Setenv("TERMINFO_DIRS", "")
+	t.Setenv("COLORTERM", "")
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	t.Setenv("TERM", "xterm-256color")
+
+	if got := DetectColorProfile(ColorNever); got != ProfileNone {
+		t.Errorf("never = %v, want none", got)
+	}
+	if got := DetectColorProfile(ColorAuto); got != ProfileNone {
+		t.Errorf("auto without a terminal = %v, want none", got)
+	}
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	if got := DetectColorProfile(ColorAuto); got != ProfileANSI256 {
+		t.Errorf("auto with CLICOLOR_FORCE = %v, want 256", got)
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if got := DetectColorProfile(ColorAuto); got != ProfileNone {
+		t.Errorf("auto with NO_COLOR = %v, want none", got)
+	}
+	if got := DetectColorProfile(ColorAlways); got != ProfileANSI256 {
+		t.Errorf("always with NO_COLOR = %v, want 256", got)
+	}
+
+	t.Setenv("COLORTERM", "truecolor")
+	if got := DetectColorProfile(ColorAlways); got != ProfileTrueColor {
+		t.Errorf("always with COLORTERM=truecolor = %v, want truecolor", got)
+	}
+
+	t.Setenv("COLORTERM", "")
+	t.Setenv("TERM", "dumb")
+	if got := envColorProfile(); got != ProfileNone {
+		t.Errorf("TERM=dumb = %v, want none", got)
+	}
+}
+
+func TestParseTerminfoColors(t *testing.T) {
+	t.Parallel()
+
+	build := func(magic uint16, numberSize int, colors int) []byte {
+		names := []byte("test|test terminal\x00") // 19 bytes, forces alignment padding
+		bools := []byte{1, 0}
+		header := []uint16{magic, uint16(len(names)), uint16(len(bools)), 14, 0, 0}
+
+		data := make([]byte, 0, 128)
+		for _, h := range header {
+			data = binary.LittleEndian.AppendUint16(data, h)
+		}
+		data = append(data, names...)
+		data = append(data, bools...)
+		if len(data)%2 != 0 {
+			data = append(data, 0)
+		}
+		for i := 0; i < 14; i++ {
+			value := -1
+			if i == 13 {
+				value = colors
+			}
+			if numberSize == 4 {
+				data = binary.LittleEndian.AppendUint32(data, uint32(int32(value)))
+			} else {
+				data = binary.LittleEndian.AppendUint16(data, uint16(int16(value)))
+			}
+		}
+		return data
+	}
+
+	if colors, ok := parseTerminfoColors(build(0o432, 2, 256)); !ok || colors != 256 {
+		t.Errorf("legacy format = %d, %v; want 256, true", colors, ok)
+	}
+	if colors, ok := parseTerminfoColors(build(0o1036, 4, 1<<24)); !ok || colors != 1<<24 {
+		t.Errorf("32-bit format = %d, %v; want %d, true", colors, ok, 1<<24)
+	}
+	if _, ok := parseTerminfoColors([]byte("garbage")); ok {
+		t.Error("garbage input should not parse")
+	}
+}
+
+func TestCodeBackground(t *testing.T) {
+	t.Parallel()
+
+	tm := NewWithBackground(BackgroundDark)
+	tm.SetColorProfile(ProfileTrueColor)
+	// monokai's background is #272822
+	if got := tm.CodeBackground(); got != "\033[48;2;39;40;34m" {
+		t.Errorf("CodeBackground() = %q, want monokai's background", got)
+	}
+
+	tm.SetColorProfile(ProfileNone)
+	if got := tm.CodeBackground(); got != "" {
+		t.Errorf("CodeBackground() without colors = %q, want none", got)
+	}
+}
+
+func TestCodeHighlight(t *testing.T) {
+	t.Parallel()
+
+	tm := NewWithBackground(BackgroundDark)
+	tm.SetColorProfile(ProfileTrueColor)
+	highlight := tm.CodeHighlight()
+	if highlight == "" || highlight == tm.CodeBackground() {
+		t.Errorf("CodeHighlight() = %q, want a color that differs from the background", highlight)
+	}
+
+	tm.SetColorProfile(ProfileNone)
+	if got := tm.CodeHighlight(); got != "" {
+		t.Errorf("CodeHighlight() without colors = %q, want none", got)
+	}
+}
@@ -3,6 +3,7 @@ package theme
 import (
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -30,23 +31,16 @@ func (bt BackgroundType) String() string {
 	}
 }
 
-// DetectTerminalBackground attempts to detect the terminal background preference
-// Returns BackgroundDark as the safe default for v1 implementation
+// DetectTerminalBackground attempts to detect the terminal background preference.
+// The terminal is asked for its colors first; environment heuristics are only
+// used when it does not answer, with BackgroundDark as the final default.
 func DetectTerminalBackground() BackgroundType {
-	if theme := os.Getenv("COLORFGBG"); theme != "" {
-		// COLORFGBG format is typically "foreground;background"
-		// Lower numbers (0-7) typically indicate darker colors
-		// Higher numbers (8-15) typically indicate lighter colors
-		parts := strings.Split(theme, ";")
-		if len(parts) >= 2 {
-			bg := parts[len(parts)-1]
-			// Simple heuristic: if background is 0-7, it's likely dark
-			if bg >= "0" && bg <= "7" {
-				return BackgroundDark
-			} else if bg >= "8" && bg <= "15" {
-				return BackgroundLight
-			}
-		}
+	if bg := queryBackground(); bg != BackgroundUnknown {
+		return bg
+	}
+
+	if bg := colorFGBGBackground(os.Getenv("COLORFGBG")); bg != BackgroundUnknown {
+		return bg
 	}
 
 	if isDarkThemeEnvironment() {
@@ -71,6 +65,25 @@ func DetectTerminalBackground() BackgroundType {
 	return BackgroundDark
 }
 
+// colorFGBGBackground interprets the COLORFGBG variable set by rxvt and
+// others, in the format "foreground;background" or "foreground;other;background".
+// Background colors 0-6 and 8 are the dark ones of the 16-color palette.
+func colorFGBGBackground(value string) BackgroundType {
+	parts := strings.Split(value, ";")
+	if len(parts) < 2 {
+		return BackgroundUnknown
+	}
+
+	bg, err := strconv.Atoi(strings.TrimSpace(parts[len(parts)-1]))
+	if err != nil || bg < 0 || bg > 15 {
+		return BackgroundUnknown
+	}
+	if bg <= 6 || bg == 8 {
+		return BackgroundDark
+	}
+	return BackgroundLight
+}
+
 func isDarkThemeEnvironment() bool {
 	darkIndicators := []string{
 		"DARK_MODE=1",
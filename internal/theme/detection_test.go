@@ -0,0 +1,76 @@
+package theme
+
+import "testing"
+
+func TestColorFGBGBackground(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		value    string
+		expected BackgroundType
+	}{
+		{"", BackgroundUnknown},
+		{"15;0", BackgroundDark},
+		{"0;15", BackgroundLight},
+		{"0;10", BackgroundLight},
+		{"15;8", BackgroundDark},
+		{"0;7", BackgroundLight},
+		{"15;default;0", BackgroundDark},
+		{"15;default", BackgroundUnknown},
+		{"7;42", BackgroundUnknown},
+	}
+
+	for _, tt := range tests {
+		if got := colorFGBGBackground(tt.value); got != tt.expected {
+			t.Errorf("colorFGBGBackground(%q) = %v, want %v", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestParseOSCColors(t *testing.T) {
+	t.Parallel()
+
+	reply := []byte("\033]10;rgb:e5e5/e5e5/e5e5\033\\\033]11;rgb:1c/1c/1c\a\033[?62;22c")
+	fg, bg, fgOK, bgOK := parseOSCColors(reply)
+	if !fgOK || !bgOK {
+		t.Fatalf("parseOSCColors() fgOK=%v bgOK=%v, want both", fgOK, bgOK)
+	}
+	if fg != RGBColor(229, 229, 229) {
+		t.Errorf("foreground = %+v, want rgb(229,229,229)", fg)
+	}
+	if bg != RGBColor(28, 28, 28) {
+		t.Errorf("background = %+v, want rgb(28,28,28)", bg)
+	}
+
+	if _, _, fgOK, bgOK := parseOSCColors([]byte("\033[?1;2c")); fgOK || bgOK {
+		t.Error("parseOSCColors() should report nothing for a terminal without OSC support")
+	}
+}
+
+func TestClassifyBackground(t *testing.T) {
+	t.Parallel()
+
+	white, black := RGBColor(255, 255, 255), RGBColor(0, 0, 0)
+	solarizedLight := RGBColor(253, 246, 227)
+	gray := RGBColor(128, 128, 128)
+
+	tests := []struct {
+		name       string
+		fg, bg     Color
+		fgOK, bgOK bool
+		expected   BackgroundType
+	}{
+		{"no reply", Color{}, Color{}, false, false, BackgroundUnknown},
+		{"dark background", Color{}, black, false, true, BackgroundDark},
+		{"light background", Color{}, solarizedLight, false, true, BackgroundLight},
+		{"foreground only", white, Color{}, true, false, BackgroundDark},
+		{"mid gray background with black text", black, gray, true, true, BackgroundLight},
+		{"mid gray background with white text", white, gray, true, true, BackgroundDark},
+	}
+
+	for _, tt := range tests {
+		if got := classifyBackground(tt.fg, tt.bg, tt.fgOK, tt.bgOK); got != tt.expected {
+			t.Errorf("%s: classifyBackground() = %v, want %v", tt.name, got, tt.expected)
+		}
+	}
+}
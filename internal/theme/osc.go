@@ -0,0 +1,96 @@
+package theme
+
+import (
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// oscTimeout bounds how long md waits for the terminal to answer a color query
+const oscTimeout = 150 * time.Millisecond
+
+var (
+	oscOnce       sync.Once
+	oscBackground BackgroundType
+)
+
+// queryBackground asks the terminal for its colors with OSC 10/11 and
+// classifies the background. The terminal is queried at most once per process.
+func queryBackground() BackgroundType {
+	oscOnce.Do(func() {
+		oscBackground = BackgroundUnknown
+		// Only query when md owns the screen; a pager reading the same tty would race for the reply
+		if !term.IsTerminal(int(os.Stdout.Fd())) || os.Getenv("TERM") == "dumb" {
+			return
+		}
+		fg, bg, fgOK, bgOK := queryTerminalColors(oscTimeout)
+		oscBackground = classifyBackground(fg, bg, fgOK, bgOK)
+	})
+	return oscBackground
+}
+
+// classifyBackground decides between a light and dark background from the
+// colors reported by the terminal. When both colors are known the background
+// is dark if it is darker than the foreground.
+func classifyBackground(fg, bg Color, fgOK, bgOK bool) BackgroundType {
+	switch {
+	case bgOK && fgOK && luminance(bg) != luminance(fg):
+		if luminance(bg) < luminance(fg) {
+			return BackgroundDark
+		}
+		return BackgroundLight
+	case bgOK:
+		if luminance(bg) < 0.5 {
+			return BackgroundDark
+		}
+		return BackgroundLight
+	case fgOK:
+		if luminance(fg) < 0.5 {
+			return BackgroundLight
+		}
+		return BackgroundDark
+	default:
+		return BackgroundUnknown
+	}
+}
+
+// oscColorPattern matches replies such as "ESC ] 11 ; rgb:ffff/ffff/ffff BEL"
+var oscColorPattern = regexp.MustCompile(`\x1b\](1[01]);rgba?:([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})/([0-9a-fA-F]{1,4})`)
+
+// parseOSCColors extracts the foreground (OSC 10) and background (OSC 11) colors from terminal replies
+func parseOSCColors(reply []byte) (fg, bg Color, fgOK, bgOK bool) {
+	for _, m := range oscColorPattern.FindAllSubmatch(reply, -1) {
+		color := RGBColor(scaleHex(m[2]), scaleHex(m[3]), scaleHex(m[4]))
+		if string(m[1]) == "10" {
+			fg, fgOK = color, true
+		} else {
+			bg, bgOK = color, true
+		}
+	}
+	return fg, bg, fgOK, bgOK
+}
+
+// scaleHex converts a 1-4 digit hex color component to 8 bits
+func scaleHex(digits []byte) uint8 {
+	value, _ := strconv.ParseUint(string(digits), 16, 32)
+	max := uint64(1)<<(4*len(digits)) - 1
+	return uint8((value*255 + max/2) / max)
+}
+
+// luminance returns the relative luminance of a color between 0 and 1
+func luminance(c Color) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	r, g, b := c.RGB()
+	return 0.2126*linear(r) + 0.7152*linear(g) + 0.0722*linear(b)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package theme
+
+import "time"
+
+// queryTerminalColors is not supported on this platform
+func queryTerminalColors(timeout time.Duration) (fg, bg Color, fgOK, bgOK bool) {
+	return
+}
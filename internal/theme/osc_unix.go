@@ -0,0 +1,68 @@
+//go:build unix
+
+package theme
+
+import (
+	"bytes"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// queryTerminalColors asks the controlling terminal for its foreground and
+// background colors. A primary device attributes request is sent last: every
+// terminal answers it, so its reply marks the end of the colors the terminal
+// knows about and avoids waiting for the full timeout.
+func queryTerminalColors(timeout time.Duration) (fg, bg Color, fgOK, bgOK bool) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	defer tty.Close()
+
+	fd := int(tty.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return
+	}
+	defer term.Restore(fd, state)
+
+	if _, err := tty.WriteString("\033]10;?\033\\\033]11;?\033\\\033[c"); err != nil {
+		return
+	}
+
+	var reply []byte
+	buf := make([]byte, 256)
+	deadline := time.Now().Add(timeout)
+	for !deviceAttributesReceived(reply) {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(remaining/time.Millisecond)+1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n == 0 {
+			break
+		}
+
+		n, err = tty.Read(buf)
+		if err != nil {
+			break
+		}
+		reply = append(reply, buf[:n]...)
+	}
+
+	return parseOSCColors(reply)
+}
+
+// deviceAttributesReceived reports whether reply contains a complete "ESC [ ? ... c" response
+func deviceAttributesReceived(reply []byte) bool {
+	start := bytes.Index(reply, []byte("\033[?"))
+	return start != -1 && bytes.IndexByte(reply[start:], 'c') != -1
+}
@@ -0,0 +1,243 @@
+package theme
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// ColorProfile is the color depth supported by the terminal
+type ColorProfile int
+
+const (
+	// ProfileNone disables all escape sequences
+	ProfileNone ColorProfile = iota
+	// ProfileANSI supports the 16 basic colors
+	ProfileANSI
+	// ProfileANSI256 supports the xterm 256-color palette
+	ProfileANSI256
+	// ProfileTrueColor supports 24-bit colors
+	ProfileTrueColor
+)
+
+// String returns a string representation of the color profile
+func (cp ColorProfile) String() string {
+	switch cp {
+	case ProfileANSI:
+		return "16"
+	case ProfileANSI256:
+		return "256"
+	case ProfileTrueColor:
+		return "truecolor"
+	default:
+		return "none"
+	}
+}
+
+// ColorMode is the user's choice of when to emit colors
+type ColorMode int
+
+const (
+	// ColorAuto emits colors only when stdout is a terminal that supports them
+	ColorAuto ColorMode = iota
+	// ColorAlways emits colors even when stdout is not a terminal
+	ColorAlways
+	// ColorNever disables colors
+	ColorNever
+)
+
+// String returns a string representation of the color mode
+func (cm ColorMode) String() string {
+	switch cm {
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	default:
+		return "auto"
+	}
+}
+
+// ParseColorMode parses the value of the --color flag
+func ParseColorMode(value string) (ColorMode, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "auto":
+		return ColorAuto, nil
+	case "always", "force":
+		return ColorAlways, nil
+	case "never", "none", "off":
+		return ColorNever, nil
+	default:
+		return ColorAuto, fmt.Errorf("invalid color mode %q (want auto, always or never)", value)
+	}
+}
+
+// DetectColorProfile determines the color depth to use for the given mode.
+//
+// In auto mode NO_COLOR disables colors, and colors are only used when
+// stdout is a terminal unless CLICOLOR_FORCE is set. The depth itself is
+// taken from COLORTERM, TERM and the terminfo database.
+func DetectColorProfile(mode ColorMode) ColorProfile {
+	if mode == ColorNever {
+		return ProfileNone
+	}
+
+	forced := mode == ColorAlways
+	if mode == ColorAuto {
+		if os.Getenv("NO_COLOR") != "" {
+			return ProfileNone
+		}
+		if force := os.Getenv("CLICOLOR_FORCE"); force != "" && force != "0" {
+			forced = true
+		}
+		if !forced && !term.IsTerminal(int(os.Stdout.Fd())) {
+			return ProfileNone
+		}
+	}
+
+	profile := envColorProfile()
+	if profile == ProfileNone && forced {
+		return ProfileANSI256
+	}
+	return profile
+}
+
+// envColorProfile derives the color depth from the environment
+func envColorProfile() ColorProfile {
+	colorterm := strings.ToLower(os.Getenv("COLORTERM"))
+	if colorterm == "truecolor" || colorterm == "24bit" {
+		return ProfileTrueColor
+	}
+
+	termName := os.Getenv("TERM")
+	if termName == "" || termName == "dumb" {
+		if os.Getenv("WT_SESSION") != "" {
+			return ProfileTrueColor
+		}
+		return ProfileNone
+	}
+
+	lower := strings.ToLower(termName)
+	if strings.Contains(lower, "truecolor") || strings.Contains(lower, "24bit") || strings.Contains(lower, "direct") {
+		return ProfileTrueColor
+	}
+
+	if colors, ok := terminfoColors(termName); ok {
+		switch {
+		case colors >= 1<<24:
+			return ProfileTrueColor
+		case colors >= 256:
+			return ProfileANSI256
+		case colors >= 8:
+			return ProfileANSI
+		default:
+			return ProfileNone
+		}
+	}
+
+	if strings.Contains(lower, "256color") {
+		return ProfileANSI256
+	}
+	return ProfileANSI
+}
+
+// terminfoColors reads the "colors" capability of a terminal from the compiled terminfo database
+func terminfoColors(termName string) (int, bool) {
+	path := findTerminfo(termName)
+	if path == "" {
+		return 0, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	return parseTerminfoColors(data)
+}
+
+// findTerminfo locates the compiled terminfo entry for a terminal
+func findTerminfo(termName string) string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	if list := os.Getenv("TERMINFO_DIRS"); list != "" {
+		for _, dir := range strings.Split(list, ":") {
+			if dir == "" {
+				dir = "/usr/share/terminfo"
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo")
+
+	for _, dir := range dirs {
+		// Entries live under their first letter, or its hex code on case-insensitive filesystems
+		for _, sub := range []string{termName[:1], fmt.Sprintf("%x", termName[0])} {
+			path := filepath.Join(dir, sub, termName)
+			if _, err := os.Stat(path); err == nil {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// parseTerminfoColors extracts the "colors" number capability from a compiled
+// terminfo entry in either the legacy or the extended 32-bit number format
+func parseTerminfoColors(data []byte) (int, bool) {
+	const (
+		magicLegacy = 0o432
+		magic32Bit  = 0o1036
+		colorsIndex = 13
+	)
+
+	if len(data) < 12 {
+		return 0, false
+	}
+	header := make([]int, 6)
+	for i := range header {
+		header[i] = int(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+
+	numberSize := 2
+	switch header[0] {
+	case magicLegacy:
+	case magic32Bit:
+		numberSize = 4
+	default:
+		return 0, false
+	}
+
+	namesSize, boolCount, numCount := header[1], header[2], header[3]
+	if numCount <= colorsIndex {
+		return 0, false
+	}
+
+	offset := 12 + namesSize + boolCount
+	if offset%2 != 0 {
+		offset++
+	}
+	offset += colorsIndex * numberSize
+	if offset+numberSize > len(data) {
+		return 0, false
+	}
+
+	var colors int
+	if numberSize == 4 {
+		colors = int(int32(binary.LittleEndian.Uint32(data[offset:])))
+	} else {
+		colors = int(int16(binary.LittleEndian.Uint16(data[offset:])))
+	}
+	if colors < 0 {
+		return 0, false
+	}
+	return colors, true
+}
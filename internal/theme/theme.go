@@ -10,13 +10,19 @@ package theme
 
 import (
 	"fmt"
-	"os"
-	"strings"
+	"sort"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
 )
 
 // ThemeManager manages styling and theming for the markdown renderer
 type ThemeManager struct {
 	backgroundType BackgroundType
+	name           string
+	variant        string
+	profile        ColorProfile
+	styles         map[ColorKey]Style
 	colors         map[string]string
 	chromaTheme    string
 }
@@ -26,7 +32,9 @@ type TerminalTheme struct {
 	Background     BackgroundType
 	ChromaTheme    string
 	ColorScheme    string
+	ColorProfile   ColorProfile
 	IsHighContrast bool
+	Variant        string
 }
 
 // ColorKey represents different styling elements
@@ -59,6 +67,14 @@ const (
 	TableHeader ColorKey = "table_header"
 	TableBorder ColorKey = "table_border"
 
+	// Diagnostics shown in the document, such as missing embedded files
+	Warning ColorKey = "warning"
+	// Search matches, as shown by md grep
+	Match ColorKey = "match"
+	// Added and removed content, as shown by md diff
+	Inserted ColorKey = "inserted"
+	Deleted  ColorKey = "deleted"
+
 	// Special
 	Reset ColorKey = "reset"
 )
@@ -93,24 +109,21 @@ const (
 	ANSIBrightWhite   = "\033[97m"
 )
 
-// New creates a new theme manager with terminal background detection
+// New creates a new theme manager with terminal background detection.
+// Styles are emitted with 256 colors until SetColorMode or SetColorProfile is called.
 func New() *ThemeManager {
-	bgType := DetectTerminalBackground()
-	tm := &ThemeManager{
-		backgroundType: bgType,
-		chromaTheme:    getDefaultChromaTheme(bgType),
-	}
-	tm.colors = tm.buildColorScheme(bgType)
-	return tm
+	return NewWithBackground(DetectTerminalBackground())
 }
 
 // NewWithBackground creates a new theme manager with explicit background type
 func NewWithBackground(bgType BackgroundType) *ThemeManager {
 	tm := &ThemeManager{
 		backgroundType: bgType,
+		profile:        ProfileANSI256,
 		chromaTheme:    getDefaultChromaTheme(bgType),
 	}
-	tm.colors = tm.buildColorScheme(bgType)
+	tm.styles = tm.buildColorScheme(bgType)
+	tm.colors = tm.buildColors()
 	return tm
 }
 
@@ -125,7 +138,7 @@ func getDefaultChromaTheme(bgType BackgroundType) string {
 	}
 }
 
-func (tm *ThemeManager) buildColorScheme(bgType BackgroundType) map[string]string {
+func (tm *ThemeManager) buildColorScheme(bgType BackgroundType) map[ColorKey]Style {
 	switch bgType {
 	case BackgroundLight:
 		return tm.buildLightColorScheme()
@@ -136,48 +149,156 @@ func (tm *ThemeManager) buildColorScheme(bgType BackgroundType) map[string]strin
 	}
 }
 
-func (tm *ThemeManager) buildDarkColorScheme() map[string]string {
-	return map[string]string{
-		string(Header1):       "\033[1;96m",     // Bold Bright Cyan
-		string(Header2):       "\033[1;94m",     // Bold Bright Blue
-		string(Header3):       "\033[1;95m",     // Bold Bright Magenta
-		string(Header4):       "\033[1;93m",     // Bold Bright Yellow
-		string(Header5):       "\033[1;92m",     // Bold Bright Green
-		string(Header6):       "\033[1;91m",     // Bold Bright Red
-		string(Bold):          "\033[1m",        // Bold
-		string(Italic):        "\033[3m",        // Italic
-		string(Strikethrough): "\033[9m",        // Strikethrough
-		string(Code):          "\033[38;5;208m", // Orange (256-color)
-		string(BlockQuote):    "\033[38;5;244m", // Gray (256-color)
-		string(Link):          "\033[4;94m",     // Underlined Bright Blue
-		string(BulletPoint):   "\033[1;97m",     // Bold Bright White
-		string(OrderedList):   "\033[1;97m",     // Bold Bright White
-		string(TableHeader):   "\033[1;97m",     // Bold Bright White
-		string(TableBorder):   "\033[38;5;244m", // Gray (256-color)
-		string(Reset):         "\033[0m",        // Reset
+// matchStyle highlights search matches in the dark and light themes
+var matchStyle = Style{Bold: true, Foreground: ANSIColor(0), Background: ANSIColor(11)}
+
+func (tm *ThemeManager) buildDarkColorScheme() map[ColorKey]Style {
+	return map[ColorKey]Style{
+		Header1:       {Bold: true, Foreground: ANSIColor(14)},      // Bold Bright Cyan
+		Header2:       {Bold: true, Foreground: ANSIColor(12)},      // Bold Bright Blue
+		Header3:       {Bold: true, Foreground: ANSIColor(13)},      // Bold Bright Magenta
+		Header4:       {Bold: true, Foreground: ANSIColor(11)},      // Bold Bright Yellow
+		Header5:       {Bold: true, Foreground: ANSIColor(10)},      // Bold Bright Green
+		Header6:       {Bold: true, Foreground: ANSIColor(9)},       // Bold Bright Red
+		Bold:          {Bold: true},                                 // Bold
+		Italic:        {Italic: true},                               // Italic
+		Strikethrough: {Strikethrough: true},                        // Strikethrough
+		Code:          {Foreground: ANSI256Color(208)},              // Orange (256-color)
+		BlockQuote:    {Foreground: ANSI256Color(244)},              // Gray (256-color)
+		Link:          {Underline: true, Foreground: ANSIColor(12)}, // Underlined Bright Blue
+		BulletPoint:   {Bold: true, Foreground: ANSIColor(15)},      // Bold Bright White
+		OrderedList:   {Bold: true, Foreground: ANSIColor(15)},      // Bold Bright White
+		TableHeader:   {Bold: true, Foreground: ANSIColor(15)},      // Bold Bright White
+		TableBorder:   {Foreground: ANSI256Color(244)},              // Gray (256-color)
+		Warning:       {Bold: true, Foreground: ANSIColor(11)},      // Bold Bright Yellow
+		Match:         matchStyle,                                   // Black on Bright Yellow
+		Inserted:      {Foreground: ANSIColor(10)},                  // Bright Green
+		Deleted:       {Foreground: ANSIColor(9)},                   // Bright Red
+	}
+}
+
+func (tm *ThemeManager) buildLightColorScheme() map[ColorKey]Style {
+	return map[ColorKey]Style{
+		Header1:       {Bold: true, Foreground: ANSIColor(4)},      // Bold Blue
+		Header2:       {Bold: true, Foreground: ANSIColor(6)},      // Bold Cyan
+		Header3:       {Bold: true, Foreground: ANSIColor(5)},      // Bold Magenta
+		Header4:       {Bold: true, Foreground: ANSIColor(3)},      // Bold Yellow
+		Header5:       {Bold: true, Foreground: ANSIColor(2)},      // Bold Green
+		Header6:       {Bold: true, Foreground: ANSIColor(1)},      // Bold Red
+		Bold:          {Bold: true},                                // Bold
+		Italic:        {Italic: true},                              // Italic
+		Strikethrough: {Strikethrough: true},                       // Strikethrough
+		Code:          {Foreground: ANSI256Color(166)},             // Dark Orange (256-color)
+		BlockQuote:    {Foreground: ANSI256Color(240)},             // Dark Gray (256-color)
+		Link:          {Underline: true, Foreground: ANSIColor(4)}, // Underlined Blue
+		BulletPoint:   {Bold: true, Foreground: ANSIColor(0)},      // Bold Black
+		OrderedList:   {Bold: true, Foreground: ANSIColor(0)},      // Bold Black
+		TableHeader:   {Bold: true, Foreground: ANSIColor(0)},      // Bold Black
+		TableBorder:   {Foreground: ANSI256Color(240)},             // Dark Gray (256-color)
+		Warning:       {Bold: true, Foreground: ANSIColor(1)},      // Bold Red
+		Match:         matchStyle,                                  // Black on Bright Yellow
+		Inserted:      {Foreground: ANSIColor(2)},                  // Green
+		Deleted:       {Foreground: ANSIColor(1)},                  // Red
 	}
 }
 
-func (tm *ThemeManager) buildLightColorScheme() map[string]string {
-	return map[string]string{
-		string(Header1):       "\033[1;34m",     // Bold Blue
-		string(Header2):       "\033[1;36m",     // Bold Cyan
-		string(Header3):       "\033[1;35m",     // Bold Magenta
-		string(Header4):       "\033[1;33m",     // Bold Yellow
-		string(Header5):       "\033[1;32m",     // Bold Green
-		string(Header6):       "\033[1;31m",     // Bold Red
-		string(Bold):          "\033[1m",        // Bold
-		string(Italic):        "\033[3m",        // Italic
-		string(Strikethrough): "\033[9m",        // Strikethrough
-		string(Code):          "\033[38;5;166m", // Dark Orange (256-color)
-		string(BlockQuote):    "\033[38;5;240m", // Dark Gray (256-color)
-		string(Link):          "\033[4;34m",     // Underlined Blue
-		string(BulletPoint):   "\033[1;30m",     // Bold Black
-		string(OrderedList):   "\033[1;30m",     // Bold Black
-		string(TableHeader):   "\033[1;30m",     // Bold Black
-		string(TableBorder):   "\033[38;5;240m", // Dark Gray (256-color)
-		string(Reset):         "\033[0m",        // Reset
+// BuiltinThemes returns the names of the themes compiled into md. The
+// accessibility variants are also available by their variant name alone,
+// which picks the light or dark palette from the detected background.
+func BuiltinThemes() []string {
+	names := []string{"dark", "light", VariantHighContrast, VariantDeuteranopia, VariantProtanopia}
+	for _, vt := range variantThemes {
+		names = append(names, vt.name)
+	}
+	return names
+}
+
+// IsBuiltinTheme reports whether name is one of BuiltinThemes
+func IsBuiltinTheme(name string) bool {
+	for _, builtin := range BuiltinThemes() {
+		if name == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinTheme returns the styles, background, Chroma style and
+// accessibility variant of a built-in theme
+func (tm *ThemeManager) builtinTheme(name string) (map[ColorKey]Style, BackgroundType, string, string) {
+	if vt, ok := lookupVariant(name, tm.backgroundType); ok {
+		return vt.styles(), vt.background, vt.chromaName(), vt.variant
+	}
+
+	switch name {
+	case "light":
+		return tm.buildLightColorScheme(), BackgroundLight, getDefaultChromaTheme(BackgroundLight), ""
+	case "dark":
+		return tm.buildDarkColorScheme(), BackgroundDark, getDefaultChromaTheme(BackgroundDark), ""
+	default:
+		return tm.buildColorScheme(tm.backgroundType), tm.backgroundType, getDefaultChromaTheme(tm.backgroundType), ""
+	}
+}
+
+// SetTheme selects a theme by name: "auto" keeps the scheme matching the
+// detected background, built-in names select that scheme, and anything else
+// is loaded as a user theme from ThemeDir or a file path
+func (tm *ThemeManager) SetTheme(name string) error {
+	switch {
+	case name == "" || name == "auto":
+		return nil
+	case IsBuiltinTheme(name):
+		tm.styles, tm.backgroundType, tm.chromaTheme, tm.variant = tm.builtinTheme(name)
+		tm.name = name
+		if vt, ok := lookupVariant(name, tm.backgroundType); ok {
+			tm.name = vt.name
+		}
+		tm.colors = tm.buildColors()
+		return nil
+	}
+
+	def, err := LoadTheme(name)
+	if err != nil {
+		return err
+	}
+	tm.ApplyTheme(def)
+	return nil
+}
+
+// ApplyTheme applies a user theme on top of its base theme
+func (tm *ThemeManager) ApplyTheme(def *ThemeDefinition) {
+	styles, bgType, chromaTheme, variant := tm.builtinTheme(def.Base)
+	for key, spec := range def.Styles {
+		styles[key] = spec.apply(styles[key])
+	}
+
+	tm.styles = styles
+	tm.backgroundType = bgType
+	tm.name = def.Name
+	tm.variant = variant
+	tm.chromaTheme = chromaTheme
+	if def.Chroma != "" {
+		tm.chromaTheme = def.Chroma
+	}
+	tm.colors = tm.buildColors()
+}
+
+// GetStyle returns the style of an element
+func (tm *ThemeManager) GetStyle(key ColorKey) Style {
+	return tm.styles[key]
+}
+
+// buildColors renders every style to an escape sequence for the current color profile
+func (tm *ThemeManager) buildColors() map[string]string {
+	colors := make(map[string]string, len(tm.styles)+1)
+	for key, style := range tm.styles {
+		colors[string(key)] = style.Sequence(tm.profile)
+	}
+	colors[string(Reset)] = ""
+	if tm.profile != ProfileNone {
+		colors[string(Reset)] = ANSIReset
 	}
+	return colors
 }
 
 func (tm *ThemeManager) GetColor(key ColorKey) string {
@@ -205,19 +326,94 @@ func (tm *ThemeManager) GetChromaTheme() string {
 	return tm.chromaTheme
 }
 
+// CodeBackground returns the escape sequence that paints the background color
+// of the code theme, or "" if the theme has none or colors are disabled
+func (tm *ThemeManager) CodeBackground() string {
+	bg := styles.Get(tm.chromaTheme).Get(chroma.Background).Background
+	if !bg.IsSet() {
+		return ""
+	}
+	return Style{Background: RGBColor(bg.Red(), bg.Green(), bg.Blue())}.Sequence(tm.profile)
+}
+
+// CodeHighlight returns the escape sequence that paints the background of
+// emphasized code lines: the code theme's line highlight color, or its
+// background brightened or darkened when it doesn't define one
+func (tm *ThemeManager) CodeHighlight() string {
+	style := styles.Get(tm.chromaTheme)
+	bg := style.Get(chroma.Background).Background
+	highlight := style.Get(chroma.LineHighlight).Background
+	if !highlight.IsSet() || highlight == bg {
+		highlight = bg.BrightenOrDarken(0.15)
+	}
+
+	color := RGBColor(highlight.Red(), highlight.Green(), highlight.Blue())
+	switch {
+	case tm.profile == ProfileANSI:
+		// Subtle shades collapse to the terminal's own background with 16 colors
+		color = ANSIColor(8)
+		if tm.backgroundType == BackgroundLight {
+			color = ANSIColor(7)
+		}
+	case !bg.IsSet():
+		color = ANSI256Color(237)
+		if tm.backgroundType == BackgroundLight {
+			color = ANSI256Color(254)
+		}
+	}
+	return Style{Background: color}.Sequence(tm.profile)
+}
+
 // GetTerminalTheme returns detailed terminal theme information
 func (tm *ThemeManager) GetTerminalTheme() TerminalTheme {
 	return TerminalTheme{
 		Background:     tm.backgroundType,
 		ChromaTheme:    tm.chromaTheme,
 		ColorScheme:    tm.getColorSchemeName(),
+		ColorProfile:   tm.profile,
 		IsHighContrast: tm.isHighContrast(),
+		Variant:        tm.variant,
 	}
 }
 
 // SetChromaTheme allows overriding the default chroma theme
-func (tm *ThemeManager) SetChromaTheme(themeName string) {
+func (tm *ThemeManager) SetChromaTheme(themeName string) error {
+	if err := ValidateChromaTheme(themeName); err != nil {
+		return err
+	}
 	tm.chromaTheme = themeName
+	return nil
+}
+
+// ChromaThemes returns the names of all Chroma styles usable as code themes
+func ChromaThemes() []string {
+	names := styles.Names()
+	sort.Strings(names)
+	return names
+}
+
+// ValidateChromaTheme reports an error if name is not a registered Chroma style
+func ValidateChromaTheme(name string) error {
+	if _, ok := styles.Registry[name]; !ok {
+		return fmt.Errorf("unknown code theme %q (run 'md code-themes' to list available themes)", name)
+	}
+	return nil
+}
+
+// SetColorMode detects the color profile for the given mode and restyles all elements
+func (tm *ThemeManager) SetColorMode(mode ColorMode) {
+	tm.SetColorProfile(DetectColorProfile(mode))
+}
+
+// SetColorProfile restyles all elements for an explicit color profile
+func (tm *ThemeManager) SetColorProfile(profile ColorProfile) {
+	tm.profile = profile
+	tm.colors = tm.buildColors()
+}
+
+// ColorProfile returns the color depth styles are emitted with
+func (tm *ThemeManager) ColorProfile() ColorProfile {
+	return tm.profile
 }
 
 // GetBackgroundType returns the detected background type
@@ -236,6 +432,9 @@ func (tm *ThemeManager) IsDarkBackground() bool {
 }
 
 func (tm *ThemeManager) getColorSchemeName() string {
+	if tm.name != "" {
+		return tm.name
+	}
 	switch tm.backgroundType {
 	case BackgroundLight:
 		return "light"
@@ -247,9 +446,7 @@ func (tm *ThemeManager) getColorSchemeName() string {
 }
 
 func (tm *ThemeManager) isHighContrast() bool {
-	// For v1, we don't have high contrast variants
-	// This can be extended in future versions
-	return false
+	return tm.variant == VariantHighContrast
 }
 
 func (tm *ThemeManager) GetANSIConstant(name string) string {
@@ -284,40 +481,7 @@ func (tm *ThemeManager) GetANSIConstant(name string) string {
 	return ANSIReset
 }
 
+// SupportsColor reports whether styles are emitted with any color at all
 func (tm *ThemeManager) SupportsColor() bool {
-	// Check common environment variables that indicate color support
-	term, exists := os.LookupEnv("TERM")
-	if !exists {
-		return false
-	}
-
-	// Check for color support indicators
-	if term == "dumb" {
-		return false
-	}
-
-	// Check for COLORTERM environment variable
-	if colorterm, exists := os.LookupEnv("COLORTERM"); exists {
-		if colorterm == "truecolor" || colorterm == "24bit" {
-			return true
-		}
-	}
-
-	// Check for common color-supporting terminals
-	colorTerms := []string{
-		"xterm", "xterm-256color", "xterm-color",
-		"screen", "screen-256color",
-		"tmux", "tmux-256color",
-		"rxvt", "rxvt-unicode",
-		"alacritty", "kitty", "wezterm",
-	}
-
-	for _, colorTerm := range colorTerms {
-		if strings.Contains(term, colorTerm) {
-			return true
-		}
-	}
-
-	return true // Default to supporting color
+	return tm.profile != ProfileNone
 }
-
@@ -0,0 +1,358 @@
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/codehakase/md/internal/xdg"
+)
+
+// themeExtensions are the file formats accepted for user themes, in lookup order
+var themeExtensions = []string{".yaml", ".yml", ".toml", ".json"}
+
+// ColorKeys lists every element that can be styled by a theme
+var ColorKeys = []ColorKey{
+	Header1, Header2, Header3, Header4, Header5, Header6,
+	Bold, Italic, Strikethrough, Code,
+	BlockQuote, Link,
+	BulletPoint, OrderedList,
+	TableHeader, TableBorder,
+	Warning, Match, Inserted, Deleted,
+}
+
+// StyleSpec is a partial style from a theme file. Unset fields are inherited
+// from the base theme.
+type StyleSpec struct {
+	Foreground    *Color
+	Background    *Color
+	Bold          *bool
+	Italic        *bool
+	Underline     *bool
+	Strikethrough *bool
+}
+
+// apply returns base with every field set in the spec overridden
+func (s StyleSpec) apply(base Style) Style {
+	if s.Foreground != nil {
+		base.Foreground = *s.Foreground
+	}
+	if s.Background != nil {
+		base.Background = *s.Background
+	}
+	if s.Bold != nil {
+		base.Bold = *s.Bold
+	}
+	if s.Italic != nil {
+		base.Italic = *s.Italic
+	}
+	if s.Underline != nil {
+		base.Underline = *s.Underline
+	}
+	if s.Strikethrough != nil {
+		base.Strikethrough = *s.Strikethrough
+	}
+	return base
+}
+
+// ThemeDefinition is a user theme loaded from a file
+type ThemeDefinition struct {
+	Name   string
+	Path   string
+	Base   string
+	Chroma string
+	Styles map[ColorKey]StyleSpec
+}
+
+// ThemeDir returns the directory user themes are loaded from
+func ThemeDir() string {
+	return xdg.ConfigPath("themes")
+}
+
+// AvailableThemes returns the names of the built-in themes followed by the
+// user themes found in ThemeDir
+func AvailableThemes() []string {
+	names := BuiltinThemes()
+
+	entries, err := os.ReadDir(ThemeDir())
+	if err != nil {
+		return names
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		seen[name] = true
+	}
+
+	var user []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		name := strings.TrimSuffix(entry.Name(), ext)
+		if entry.IsDir() || !isThemeExtension(ext) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		user = append(user, name)
+	}
+	sort.Strings(user)
+
+	return append(names, user...)
+}
+
+// LoadTheme loads a user theme by name from ThemeDir, or from a file path
+func LoadTheme(nameOrPath string) (*ThemeDefinition, error) {
+	path, err := findThemeFile(nameOrPath)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme %s: %w", path, err)
+	}
+
+	def, err := ParseTheme(data, filepath.Ext(path))
+	if err != nil {
+		return nil, fmt.Errorf("theme %s: %w", path, err)
+	}
+	def.Path = path
+	if def.Name == "" {
+		def.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return def, nil
+}
+
+// findThemeFile resolves a theme name or path to an existing file
+func findThemeFile(nameOrPath string) (string, error) {
+	if strings.ContainsRune(nameOrPath, filepath.Separator) || isThemeExtension(filepath.Ext(nameOrPath)) {
+		if _, err := os.Stat(nameOrPath); err != nil {
+			return "", fmt.Errorf("theme file not found: %s", nameOrPath)
+		}
+		return nameOrPath, nil
+	}
+
+	for _, ext := range themeExtensions {
+		path := filepath.Join(ThemeDir(), nameOrPath+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("unknown theme %q (available: %s)", nameOrPath, strings.Join(AvailableThemes(), ", "))
+}
+
+func isThemeExtension(ext string) bool {
+	for _, e := range themeExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTheme parses a theme in the format given by its file extension
+// (.yaml, .yml, .toml or .json) and validates every entry
+func ParseTheme(data []byte, ext string) (*ThemeDefinition, error) {
+	raw := map[string]interface{}{}
+
+	var err error
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("unsupported theme format %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", strings.TrimPrefix(ext, "."), err)
+	}
+
+	def := &ThemeDefinition{Styles: map[ColorKey]StyleSpec{}}
+	for _, field := range sortedKeys(raw) {
+		value := raw[field]
+		switch field {
+		case "name", "base", "chroma":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: expected a string, got %v", field, value)
+			}
+			switch field {
+			case "name":
+				def.Name = s
+			case "base":
+				if !IsBuiltinTheme(s) {
+					return nil, fmt.Errorf("base: unknown built-in theme %q (want one of %s)", s, strings.Join(BuiltinThemes(), ", "))
+				}
+				def.Base = s
+			case "chroma":
+				if err := ValidateChromaTheme(s); err != nil {
+					return nil, fmt.Errorf("chroma: %w", err)
+				}
+				def.Chroma = s
+			}
+		case "colors":
+			colors, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("colors: expected a table of elements")
+			}
+			for _, key := range sortedKeys(colors) {
+				spec, err := parseStyleSpec(key, colors[key])
+				if err != nil {
+					return nil, err
+				}
+				def.Styles[ColorKey(key)] = spec
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown field (want name, base, chroma or colors)", field)
+		}
+	}
+	return def, nil
+}
+
+// parseStyleSpec validates the style of a single element
+func parseStyleSpec(key string, entry interface{}) (StyleSpec, error) {
+	var spec StyleSpec
+
+	if !isColorKey(key) {
+		return spec, fmt.Errorf("colors.%s: unknown element", key)
+	}
+
+	// A bare value is shorthand for the foreground color
+	attrs, ok := entry.(map[string]interface{})
+	if !ok {
+		attrs = map[string]interface{}{"foreground": entry}
+	}
+
+	for _, attr := range sortedKeys(attrs) {
+		value := attrs[attr]
+		name := fmt.Sprintf("colors.%s.%s", key, attr)
+		switch attr {
+		case "foreground", "fg", "background", "bg":
+			color, err := ParseColor(value)
+			if err != nil {
+				return spec, fmt.Errorf("%s: %w", name, err)
+			}
+			if attr == "foreground" || attr == "fg" {
+				spec.Foreground = &color
+			} else {
+				spec.Background = &color
+			}
+		case "bold", "italic", "underline", "strikethrough":
+			b, ok := value.(bool)
+			if !ok {
+				return spec, fmt.Errorf("%s: expected true or false, got %v", name, value)
+			}
+			switch attr {
+			case "bold":
+				spec.Bold = &b
+			case "italic":
+				spec.Italic = &b
+			case "underline":
+				spec.Underline = &b
+			case "strikethrough":
+				spec.Strikethrough = &b
+			}
+		default:
+			return spec, fmt.Errorf("%s: unknown attribute (want foreground, background, bold, italic, underline or strikethrough)", name)
+		}
+	}
+	return spec, nil
+}
+
+// sortedKeys returns the keys of m in order so validation errors are deterministic
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isColorKey(key string) bool {
+	for _, k := range ColorKeys {
+		if string(k) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// namedColors maps color names to the 16 basic terminal colors
+var namedColors = map[string]uint8{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+	"bright_black": 8, "bright_red": 9, "bright_green": 10, "bright_yellow": 11,
+	"bright_blue": 12, "bright_magenta": 13, "bright_cyan": 14, "bright_white": 15,
+	"gray": 8, "grey": 8,
+}
+
+// ParseColor parses a theme color: "#rgb" or "#rrggbb" hex, a name such as
+// "red" or "bright_blue", an xterm 256-color index, or "default" for no color
+func ParseColor(value interface{}) (Color, error) {
+	switch v := value.(type) {
+	case int:
+		return parseColorIndex(int64(v))
+	case int64:
+		return parseColorIndex(v)
+	case float64:
+		if v != float64(int64(v)) {
+			return Color{}, fmt.Errorf("invalid color index %v", v)
+		}
+		return parseColorIndex(int64(v))
+	case string:
+		return parseColorString(v)
+	default:
+		return Color{}, fmt.Errorf("invalid color %v", value)
+	}
+}
+
+func parseColorIndex(index int64) (Color, error) {
+	if index < 0 || index > 255 {
+		return Color{}, fmt.Errorf("color index %d out of range 0-255", index)
+	}
+	return ANSI256Color(uint8(index)), nil
+}
+
+func parseColorString(value string) (Color, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+
+	if s == "" || s == "default" || s == "none" {
+		return Color{}, nil
+	}
+
+	if strings.HasPrefix(s, "#") {
+		hex := s[1:]
+		if len(hex) == 3 {
+			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+		}
+		if len(hex) != 6 {
+			return Color{}, fmt.Errorf("invalid hex color %q", value)
+		}
+		rgb, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil {
+			return Color{}, fmt.Errorf("invalid hex color %q", value)
+		}
+		return RGBColor(uint8(rgb>>16), uint8(rgb>>8), uint8(rgb)), nil
+	}
+
+	name := strings.NewReplacer("-", "_", " ", "_").Replace(s)
+	if index, ok := namedColors[name]; ok {
+		return ANSIColor(index), nil
+	}
+
+	if index, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return parseColorIndex(index)
+	}
+
+	return Color{}, fmt.Errorf("unknown color %q", value)
+}
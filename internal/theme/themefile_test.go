@@ -0,0 +1,175 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseThemeFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		ext  string
+		data string
+	}{
+		{
+			name: "yaml",
+			ext:  ".yaml",
+			data: `
+name: ocean
+base: light
+colors:
+  header1:
+    foreground: "#268bd2"
+    underline: true
+  code: 208
+`,
+		},
+		{
+			name: "toml",
+			ext:  ".toml",
+			data: `
+name = "ocean"
+base = "light"
+
+[colors.header1]
+foreground = "#268bd2"
+underline = true
+
+[colors.code]
+foreground = 208
+`,
+		},
+		{
+			name: "json",
+			ext:  ".json",
+			data: `{"name": "ocean", "base": "light", "colors": {
+				"header1": {"foreground": "#268bd2", "underline": true},
+				"code": {"foreground": 208}}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			def, err := ParseTheme([]byte(tt.data), tt.ext)
+			if err != nil {
+				t.Fatalf("ParseTheme() returned error: %v", err)
+			}
+			if def.Name != "ocean" || def.Base != "light" {
+				t.Errorf("ParseTheme() name=%q base=%q, want ocean/light", def.Name, def.Base)
+			}
+
+			tm := NewWithBackground(BackgroundDark)
+			tm.SetColorProfile(ProfileTrueColor)
+			tm.ApplyTheme(def)
+
+			if got := tm.GetColor(Header1); got != "\033[1;4;38;2;38;139;210m" {
+				t.Errorf("header1 = %q, want bold inherited from base with new color", got)
+			}
+			if got := tm.GetColor(Code); got != "\033[38;5;208m" {
+				t.Errorf("code = %q, want 256-color 208", got)
+			}
+			if got := tm.GetColor(BlockQuote); got != "\033[38;5;240m" {
+				t.Errorf("blockquote = %q, want light base style", got)
+			}
+			if !tm.IsLightBackground() {
+				t.Error("theme based on light should report a light background")
+			}
+			if got := tm.GetTerminalTheme().ColorScheme; got != "ocean" {
+				t.Errorf("ColorScheme = %q, want ocean", got)
+			}
+		})
+	}
+}
+
+func TestParseThemeValidation(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"unknown element", "colors:\n  header7: red\n", "colors.header7: unknown element"},
+		{"bad hex", "colors:\n  code:\n    foreground: \"#12345\"\n", "colors.code.foreground: invalid hex color"},
+		{"unknown color name", "colors:\n  link:\n    background: chartreuse\n", "colors.link.background: unknown color"},
+		{"bad attribute", "colors:\n  link:\n    blink: true\n", "colors.link.blink: unknown attribute"},
+		{"bad bool", "colors:\n  bold:\n    bold: yes please\n", "colors.bold.bold: expected true or false"},
+		{"index out of range", "colors:\n  code: 300\n", "colors.code.foreground: color index 300 out of range"},
+		{"unknown base", "base: sepia\n", "base: unknown built-in theme"},
+		{"unknown field", "colours: {}\n", "colours: unknown field"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ParseTheme([]byte(tt.data), ".yaml")
+			if err == nil {
+				t.Fatalf("ParseTheme() should fail with %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseTheme() error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    interface{}
+		expected Color
+	}{
+		{"#fff", RGBColor(255, 255, 255)},
+		{"#0A0b0C", RGBColor(10, 11, 12)},
+		{"bright-blue", ANSIColor(12)},
+		{"Gray", ANSIColor(8)},
+		{"default", Color{}},
+		{"42", ANSI256Color(42)},
+		{7, ANSI256Color(7)},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseColor(tt.input)
+		if err != nil {
+			t.Errorf("ParseColor(%v) returned error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseColor(%v) = %+v, want %+v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestSetThemeFromFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "mine.toml")
+	if err := os.WriteFile(path, []byte("chroma = \"dracula\"\n[colors.link]\nforeground = \"red\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tm := NewWithBackground(BackgroundDark)
+	if err := tm.SetTheme(path); err != nil {
+		t.Fatalf("SetTheme() returned error: %v", err)
+	}
+	if got := tm.GetColor(Link); got != "\033[4;31m" {
+		t.Errorf("link = %q, want underline inherited with red", got)
+	}
+	if got := tm.GetChromaTheme(); got != "dracula" {
+		t.Errorf("GetChromaTheme() = %q, want dracula", got)
+	}
+
+	if err := tm.SetTheme(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("SetTheme() with a missing file should fail")
+	}
+}
@@ -0,0 +1,255 @@
+package theme
+
+import (
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+)
+
+// Accessibility variants of the built-in themes
+const (
+	VariantHighContrast = "high-contrast"
+	VariantDeuteranopia = "deuteranopia"
+	VariantProtanopia   = "protanopia"
+)
+
+// variantTheme is a built-in theme tuned for accessibility, paired with a
+// Chroma style of the same palette so code blocks match the prose
+type variantTheme struct {
+	name       string
+	variant    string
+	background BackgroundType
+	styles     func() map[ColorKey]Style
+	chroma     chroma.StyleEntries
+}
+
+// chromaName returns the name the theme's Chroma style is registered under
+func (vt variantTheme) chromaName() string {
+	return "md-" + vt.name
+}
+
+// Palettes for the color-blind-safe variants are based on the Okabe-Ito
+// palette, which stays distinguishable with red-green color blindness.
+// Differences are carried by blue/orange/yellow contrast and by bold,
+// italic and underline rather than by red versus green.
+var variantThemes = []variantTheme{
+	{
+		name:       "high-contrast-dark",
+		variant:    VariantHighContrast,
+		background: BackgroundDark,
+		styles: func() map[ColorKey]Style {
+			return map[ColorKey]Style{
+				Header1:       {Bold: true, Underline: true, Foreground: mustColor("#ffffff")},
+				Header2:       {Bold: true, Foreground: mustColor("#ffff00")},
+				Header3:       {Bold: true, Foreground: mustColor("#00ffff")},
+				Header4:       {Bold: true, Foreground: mustColor("#ffffff")},
+				Header5:       {Bold: true, Foreground: mustColor("#ffff00")},
+				Header6:       {Bold: true, Foreground: mustColor("#00ffff")},
+				Bold:          {Bold: true},
+				Italic:        {Italic: true},
+				Strikethrough: {Strikethrough: true},
+				Code:          {Foreground: mustColor("#00ff00")},
+				BlockQuote:    {Italic: true, Foreground: mustColor("#ffffff")},
+				Link:          {Bold: true, Underline: true, Foreground: mustColor("#00ffff")},
+				BulletPoint:   {Bold: true, Foreground: mustColor("#ffff00")},
+				OrderedList:   {Bold: true, Foreground: mustColor("#ffff00")},
+				TableHeader:   {Bold: true, Foreground: mustColor("#ffffff")},
+				TableBorder:   {Foreground: mustColor("#ffffff")},
+				Warning:       {Bold: true, Foreground: mustColor("#ffff00")},
+				Match:         {Bold: true, Foreground: mustColor("#000000"), Background: mustColor("#ffff00")},
+				Inserted:      {Bold: true, Foreground: mustColor("#00ff00")},
+				Deleted:       {Bold: true, Foreground: mustColor("#ff5f5f")},
+			}
+		},
+		chroma: chroma.StyleEntries{
+			chroma.Text:            "#ffffff",
+			chroma.Comment:         "italic #c0c0c0",
+			chroma.Keyword:         "bold #ffff00",
+			chroma.Operator:        "#ffffff",
+			chroma.Punctuation:     "#ffffff",
+			chroma.Name:            "#ffffff",
+			chroma.NameFunction:    "bold #00ffff",
+			chroma.NameClass:       "bold #00ffff",
+			chroma.NameBuiltin:     "#00ffff",
+			chroma.NameTag:         "bold #ffff00",
+			chroma.NameAttribute:   "#00ffff",
+			chroma.Literal:         "#ff80ff",
+			chroma.LiteralNumber:   "#ff80ff",
+			chroma.LiteralString:   "#00ff00",
+			chroma.GenericDeleted:  "bold #ff8080",
+			chroma.GenericInserted: "bold #00ff00",
+			chroma.GenericEmph:     "italic",
+			chroma.GenericStrong:   "bold",
+			chroma.Error:           "bold underline #ff8080",
+		},
+	},
+	{
+		name:       "high-contrast-light",
+		variant:    VariantHighContrast,
+		background: BackgroundLight,
+		styles: func() map[ColorKey]Style {
+			return map[ColorKey]Style{
+				Header1:       {Bold: true, Underline: true, Foreground: mustColor("#000000")},
+				Header2:       {Bold: true, Foreground: mustColor("#0000b0")},
+				Header3:       {Bold: true, Foreground: mustColor("#6a0080")},
+				Header4:       {Bold: true, Foreground: mustColor("#000000")},
+				Header5:       {Bold: true, Foreground: mustColor("#0000b0")},
+				Header6:       {Bold: true, Foreground: mustColor("#6a0080")},
+				Bold:          {Bold: true},
+				Italic:        {Italic: true},
+				Strikethrough: {Strikethrough: true},
+				Code:          {Foreground: mustColor("#803000")},
+				BlockQuote:    {Italic: true, Foreground: mustColor("#000000")},
+				Link:          {Bold: true, Underline: true, Foreground: mustColor("#0000b0")},
+				BulletPoint:   {Bold: true, Foreground: mustColor("#000000")},
+				OrderedList:   {Bold: true, Foreground: mustColor("#000000")},
+				TableHeader:   {Bold: true, Foreground: mustColor("#000000")},
+				TableBorder:   {Foreground: mustColor("#000000")},
+				Warning:       {Bold: true, Foreground: mustColor("#a00000")},
+				Match:         {Bold: true, Foreground: mustColor("#ffffff"), Background: mustColor("#0000b0")},
+				Inserted:      {Bold: true, Foreground: mustColor("#006000")},
+				Deleted:       {Bold: true, Foreground: mustColor("#a00000")},
+			}
+		},
+		chroma: chroma.StyleEntries{
+			chroma.Text:            "#000000",
+			chroma.Comment:         "italic #404040",
+			chroma.Keyword:         "bold #0000b0",
+			chroma.Operator:        "#000000",
+			chroma.Punctuation:     "#000000",
+			chroma.Name:            "#000000",
+			chroma.NameFunction:    "bold #6a0080",
+			chroma.NameClass:       "bold #6a0080",
+			chroma.NameBuiltin:     "#0000b0",
+			chroma.NameTag:         "bold #0000b0",
+			chroma.NameAttribute:   "#6a0080",
+			chroma.Literal:         "#803000",
+			chroma.LiteralNumber:   "#803000",
+			chroma.LiteralString:   "#005000",
+			chroma.GenericDeleted:  "bold #a00000",
+			chroma.GenericInserted: "bold #005000",
+			chroma.GenericEmph:     "italic",
+			chroma.GenericStrong:   "bold",
+			chroma.Error:           "bold underline #a00000",
+		},
+	},
+	{
+		name:       "deuteranopia-dark",
+		variant:    VariantDeuteranopia,
+		background: BackgroundDark,
+		styles: func() map[ColorKey]Style {
+			return colorBlindStyles("#56b4e9", "#e69f00", "#f0e442", "#cc79a7", "#ffffff", "#999999")
+		},
+		chroma: colorBlindChroma("#ffffff", "#999999", "#56b4e9", "#e69f00", "#f0e442", "#cc79a7"),
+	},
+	{
+		name:       "deuteranopia-light",
+		variant:    VariantDeuteranopia,
+		background: BackgroundLight,
+		styles: func() map[ColorKey]Style {
+			return colorBlindStyles("#0072b2", "#d55e00", "#8f5b00", "#a6427d", "#000000", "#666666")
+		},
+		chroma: colorBlindChroma("#000000", "#666666", "#0072b2", "#d55e00", "#8f5b00", "#a6427d"),
+	},
+	{
+		// Protanopes see long-wavelength reds as dark, so vermillion is
+		// replaced by brighter orange and purple is shifted towards blue
+		name:       "protanopia-dark",
+		variant:    VariantProtanopia,
+		background: BackgroundDark,
+		styles: func() map[ColorKey]Style {
+			return colorBlindStyles("#56b4e9", "#f0a020", "#f0e442", "#9a8fe0", "#ffffff", "#999999")
+		},
+		chroma: colorBlindChroma("#ffffff", "#999999", "#56b4e9", "#f0a020", "#f0e442", "#9a8fe0"),
+	},
+	{
+		name:       "protanopia-light",
+		variant:    VariantProtanopia,
+		background: BackgroundLight,
+		styles: func() map[ColorKey]Style {
+			return colorBlindStyles("#0072b2", "#a86400", "#7a6a00", "#5a4fb0", "#000000", "#666666")
+		},
+		chroma: colorBlindChroma("#000000", "#666666", "#0072b2", "#a86400", "#7a6a00", "#5a4fb0"),
+	},
+}
+
+// colorBlindStyles builds markdown styles from a four color accent palette
+// plus the text and muted colors
+func colorBlindStyles(blue, orange, yellow, purple, text, muted string) map[ColorKey]Style {
+	return map[ColorKey]Style{
+		Header1:       {Bold: true, Underline: true, Foreground: mustColor(blue)},
+		Header2:       {Bold: true, Foreground: mustColor(orange)},
+		Header3:       {Bold: true, Foreground: mustColor(yellow)},
+		Header4:       {Bold: true, Foreground: mustColor(purple)},
+		Header5:       {Bold: true, Foreground: mustColor(blue)},
+		Header6:       {Bold: true, Foreground: mustColor(text)},
+		Bold:          {Bold: true},
+		Italic:        {Italic: true},
+		Strikethrough: {Strikethrough: true},
+		Code:          {Foreground: mustColor(orange)},
+		BlockQuote:    {Italic: true, Foreground: mustColor(muted)},
+		Link:          {Underline: true, Foreground: mustColor(blue)},
+		BulletPoint:   {Bold: true, Foreground: mustColor(text)},
+		OrderedList:   {Bold: true, Foreground: mustColor(text)},
+		TableHeader:   {Bold: true, Foreground: mustColor(text)},
+		TableBorder:   {Foreground: mustColor(muted)},
+		Warning:       {Bold: true, Foreground: mustColor(orange)},
+		Match:         {Bold: true, Foreground: mustColor("#000000"), Background: mustColor(yellow)},
+		Inserted:      {Bold: true, Foreground: mustColor(blue)},
+		Deleted:       {Bold: true, Foreground: mustColor(orange)},
+	}
+}
+
+// colorBlindChroma builds a Chroma style from the same palette. Diff
+// insertions and deletions use blue and orange instead of green and red.
+func colorBlindChroma(text, muted, blue, orange, yellow, purple string) chroma.StyleEntries {
+	return chroma.StyleEntries{
+		chroma.Text:            text,
+		chroma.Comment:         "italic " + muted,
+		chroma.Keyword:         "bold " + blue,
+		chroma.Operator:        text,
+		chroma.Punctuation:     text,
+		chroma.Name:            text,
+		chroma.NameFunction:    yellow,
+		chroma.NameClass:       "bold " + yellow,
+		chroma.NameBuiltin:     blue,
+		chroma.NameTag:         blue,
+		chroma.NameAttribute:   yellow,
+		chroma.Literal:         purple,
+		chroma.LiteralNumber:   purple,
+		chroma.LiteralString:   orange,
+		chroma.GenericDeleted:  "bold " + orange,
+		chroma.GenericInserted: "bold " + blue,
+		chroma.GenericEmph:     "italic",
+		chroma.GenericStrong:   "bold",
+		chroma.Error:           "bold underline " + orange,
+	}
+}
+
+func init() {
+	for _, vt := range variantThemes {
+		styles.Register(chroma.MustNewStyle(vt.chromaName(), vt.chroma))
+	}
+}
+
+// lookupVariant finds an accessibility theme by its full name, or by its
+// variant name alone using the given background to pick light or dark
+func lookupVariant(name string, bgType BackgroundType) (variantTheme, bool) {
+	if bgType != BackgroundLight {
+		bgType = BackgroundDark
+	}
+	for _, vt := range variantThemes {
+		if vt.name == name || (vt.variant == name && vt.background == bgType) {
+			return vt, true
+		}
+	}
+	return variantTheme{}, false
+}
+
+// mustColor parses a color literal used by the built-in palettes
+func mustColor(value string) Color {
+	color, err := parseColorString(value)
+	if err != nil {
+		panic(err)
+	}
+	return color
+}
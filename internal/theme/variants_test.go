@@ -0,0 +1,95 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/alecthomas/chroma"
+	"github.com/alecthomas/chroma/styles"
+)
+
+func TestVariantThemes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		background   BackgroundType
+		wantScheme   string
+		wantBg       BackgroundType
+		wantVariant  string
+		highContrast bool
+	}{
+		{"high-contrast", BackgroundDark, "high-contrast-dark", BackgroundDark, VariantHighContrast, true},
+		{"high-contrast", BackgroundLight, "high-contrast-light", BackgroundLight, VariantHighContrast, true},
+		{"high-contrast-light", BackgroundDark, "high-contrast-light", BackgroundLight, VariantHighContrast, true},
+		{"deuteranopia", BackgroundUnknown, "deuteranopia-dark", BackgroundDark, VariantDeuteranopia, false},
+		{"protanopia-light", BackgroundDark, "protanopia-light", BackgroundLight, VariantProtanopia, false},
+		{"dark", BackgroundLight, "dark", BackgroundDark, "", false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name+"/"+tt.background.String(), func(t *testing.T) {
+			t.Parallel()
+
+			tm := NewWithBackground(tt.background)
+			if err := tm.SetTheme(tt.name); err != nil {
+				t.Fatalf("SetTheme(%q) returned error: %v", tt.name, err)
+			}
+
+			info := tm.GetTerminalTheme()
+			if info.ColorScheme != tt.wantScheme {
+				t.Errorf("ColorScheme = %q, want %q", info.ColorScheme, tt.wantScheme)
+			}
+			if info.Background != tt.wantBg {
+				t.Errorf("Background = %v, want %v", info.Background, tt.wantBg)
+			}
+			if info.Variant != tt.wantVariant {
+				t.Errorf("Variant = %q, want %q", info.Variant, tt.wantVariant)
+			}
+			if info.IsHighContrast != tt.highContrast {
+				t.Errorf("IsHighContrast = %v, want %v", info.IsHighContrast, tt.highContrast)
+			}
+			if tt.wantVariant != "" && info.ChromaTheme != "md-"+tt.wantScheme {
+				t.Errorf("ChromaTheme = %q, want md-%s", info.ChromaTheme, tt.wantScheme)
+			}
+		})
+	}
+}
+
+func TestVariantPalettesAreComplete(t *testing.T) {
+	t.Parallel()
+
+	for _, vt := range variantThemes {
+		palette := vt.styles()
+		for _, key := range ColorKeys {
+			if _, ok := palette[key]; !ok {
+				t.Errorf("%s: missing style for %s", vt.name, key)
+			}
+		}
+		if err := ValidateChromaTheme(vt.chromaName()); err != nil {
+			t.Errorf("%s: %v", vt.name, err)
+		}
+		if !IsBuiltinTheme(vt.name) {
+			t.Errorf("%s should be a built-in theme", vt.name)
+		}
+	}
+}
+
+func TestColorBlindChromaAvoidsRedGreen(t *testing.T) {
+	t.Parallel()
+
+	for _, vt := range variantThemes {
+		if vt.variant == VariantHighContrast {
+			continue
+		}
+		style := styles.Get(vt.chromaName())
+		deleted := style.Get(chroma.GenericDeleted).Colour
+		inserted := style.Get(chroma.GenericInserted).Colour
+
+		// Deletions and insertions must differ in the blue channel, which is
+		// unaffected by red-green color blindness
+		if absDiff(deleted.Blue(), inserted.Blue()) < 96 {
+			t.Errorf("%s: diff colors %s and %s are too close in blue", vt.name, deleted, inserted)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package viewer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// foldView tracks which folds of a rendered document are closed and maps
+// between document lines and the lines currently visible on screen
+type foldView struct {
+	lines  []string
+	folds  []renderer.Fold
+	closed []bool
+}
+
+// newFoldView creates a fold view with every fold open
+func newFoldView(doc *renderer.Document) *foldView {
+	content := strings.TrimRight(doc.Content, "\n")
+	return &foldView{
+		lines:  strings.Split(content, "\n"),
+		folds:  doc.Folds,
+		closed: make([]bool, len(doc.Folds)),
+	}
+}
+
+// visible returns the document lines that are shown with the current fold state
+func (fv *foldView) visible() []int {
+	visible := make([]int, 0, len(fv.lines))
+	for i := 0; i < len(fv.lines); i++ {
+		visible = append(visible, i)
+		if end := fv.hiddenUntil(i); end > i {
+			i = end
+		}
+	}
+	return visible
+}
+
+// hiddenUntil returns the last line hidden by a closed fold starting at line,
+// or line itself if no closed fold starts there
+func (fv *foldView) hiddenUntil(line int) int {
+	end := line
+	for i, f := range fv.folds {
+		if fv.closed[i] && f.Start == line && f.End > end {
+			end = f.End
+		}
+	}
+	return end
+}
+
+// innermost returns the index of the smallest fold containing line, optionally
+// restricted to folds in the given state, or -1 if there is none
+func (fv *foldView) innermost(line int, match func(closed bool) bool) int {
+	best := -1
+	for i, f := range fv.folds {
+		if !f.Contains(line) || !match(fv.closed[i]) {
+			continue
+		}
+		if best == -1 || f.Lines() < fv.folds[best].Lines() {
+			best = i
+		}
+	}
+	return best
+}
+
+// outermost returns the index of the largest fold containing line that is in the given state, or -1
+func (fv *foldView) outermost(line int, match func(closed bool) bool) int {
+	best := -1
+	for i, f := range fv.folds {
+		if !f.Contains(line) || !match(fv.closed[i]) {
+			continue
+		}
+		if best == -1 || f.Lines() > fv.folds[best].Lines() {
+			best = i
+		}
+	}
+	return best
+}
+
+func isOpen(closed bool) bool   { return !closed }
+func isClosed(closed bool) bool { return closed }
+
+// close closes the innermost open fold containing line (zc) and returns the
+// line the cursor should move to
+func (fv *foldView) close(line int) int {
+	i := fv.innermost(line, isOpen)
+	if i == -1 {
+		return line
+	}
+	fv.closed[i] = true
+	return fv.folds[i].Start
+}
+
+// open opens the closed fold hiding content at line (zo)
+func (fv *foldView) open(line int) int {
+	if i := fv.outermost(line, isClosed); i != -1 {
+		fv.closed[i] = false
+	}
+	return line
+}
+
+// toggle opens the fold at line if it is closed and closes it otherwise (za)
+func (fv *foldView) toggle(line int) int {
+	if fv.outermost(line, isClosed) != -1 {
+		return fv.open(line)
+	}
+	return fv.close(line)
+}
+
+// closeAll closes every fold (zM)
+func (fv *foldView) closeAll() {
+	for i := range fv.closed {
+		fv.closed[i] = true
+	}
+}
+
+// openAll opens every fold (zR)
+func (fv *foldView) openAll() {
+	for i := range fv.closed {
+		fv.closed[i] = false
+	}
+}
+
+// reveal opens every fold that hides line, e.g. when a search lands inside it
+func (fv *foldView) reveal(line int) {
+	for i, f := range fv.folds {
+		if fv.closed[i] && line > f.Start && line <= f.End {
+			fv.closed[i] = false
+		}
+	}
+}
+
+// display returns the text shown for a visible line, including the
+// "… N lines" indicator when the line starts a closed fold
+func (fv *foldView) display(line int) string {
+	text := fv.lines[line]
+	if end := fv.hiddenUntil(line); end > line {
+		text += fmt.Sprintf(" %s… %d lines%s", renderer.Dim, end-line, renderer.Reset)
+	}
+	return text
+}
@@ -0,0 +1,128 @@
+package viewer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+func newTestFoldView() *foldView {
+	// 0 # Title
+	// 1 intro
+	// 2 ## Setup
+	// 3 text
+	// 4   code line 1
+	// 5   code line 2
+	// 6 ## Usage
+	// 7 end
+	content := strings.Join([]string{
+		"# Title", "intro", "## Setup", "text", "  code line 1", "  code line 2", "## Usage", "end",
+	}, "\n") + "\n"
+
+	return newFoldView(&renderer.Document{
+		Content: content,
+		Folds: []renderer.Fold{
+			{Kind: renderer.FoldSection, Level: 1, Start: 0, End: 7},
+			{Kind: renderer.FoldSection, Level: 2, Start: 2, End: 5},
+			{Kind: renderer.FoldCodeBlock, Start: 4, End: 5},
+			{Kind: renderer.FoldSection, Level: 2, Start: 6, End: 7},
+		},
+	})
+}
+
+func TestFoldViewVisible(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		apply   func(fv *foldView)
+		visible []int
+	}{
+		{
+			name:    "all open",
+			apply:   func(fv *foldView) {},
+			visible: []int{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:    "close code block",
+			apply:   func(fv *foldView) { fv.close(5) },
+			visible: []int{0, 1, 2, 3, 4, 6, 7},
+		},
+		{
+			name:    "close section from text line",
+			apply:   func(fv *foldView) { fv.close(3) },
+			visible: []int{0, 1, 2, 6, 7},
+		},
+		{
+			name:    "close twice closes parent",
+			apply:   func(fv *foldView) { fv.close(4); fv.close(4) },
+			visible: []int{0, 1, 2, 6, 7},
+		},
+		{
+			name:    "close all",
+			apply:   func(fv *foldView) { fv.closeAll() },
+			visible: []int{0},
+		},
+		{
+			name:    "close all then open one level",
+			apply:   func(fv *foldView) { fv.closeAll(); fv.open(0) },
+			visible: []int{0, 1, 2, 6},
+		},
+		{
+			name:    "toggle twice",
+			apply:   func(fv *foldView) { fv.toggle(3); fv.toggle(2) },
+			visible: []int{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:    "open all",
+			apply:   func(fv *foldView) { fv.closeAll(); fv.openAll() },
+			visible: []int{0, 1, 2, 3, 4, 5, 6, 7},
+		},
+		{
+			name:    "reveal opens enclosing folds",
+			apply:   func(fv *foldView) { fv.closeAll(); fv.reveal(5) },
+			visible: []int{0, 1, 2, 3, 4, 5, 6},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fv := newTestFoldView()
+			tt.apply(fv)
+
+			if got := fv.visible(); !reflect.DeepEqual(got, tt.visible) {
+				t.Errorf("visible() = %v, want %v", got, tt.visible)
+			}
+		})
+	}
+}
+
+func TestFoldViewCloseMovesCursorToFoldStart(t *testing.T) {
+	t.Parallel()
+
+	fv := newTestFoldView()
+	if got := fv.close(3); got != 2 {
+		t.Errorf("close(3) = %d, want 2", got)
+	}
+}
+
+func TestFoldViewDisplayIndicator(t *testing.T) {
+	t.Parallel()
+
+	fv := newTestFoldView()
+	fv.close(3)
+
+	got := renderer.StripANSI(fv.display(2))
+	if !contains(got, "## Setup") || !contains(got, "… 3 lines") {
+		t.Errorf("display(2) = %q, want heading with fold indicator", got)
+	}
+
+	if got := fv.display(6); got != "## Usage" {
+		t.Errorf("display(6) = %q, want unchanged line", got)
+	}
+}
@@ -0,0 +1,342 @@
+package viewer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+// interactivePager is the built-in full screen viewer. Unlike less it knows
+// the structure of the rendered document, which allows sections, code
+// blocks and <details> blocks to be folded.
+type interactivePager struct {
+	in  *os.File
+	out *os.File
+
+	view    *foldView
+	cursor  int
+	top     int
+	width   int
+	height  int
+	pending string
+	search  string
+	message string
+	marks   map[string]int
+}
+
+// newInteractivePager creates an interactive pager for the given document
+func newInteractivePager(doc *renderer.Document, in, out *os.File) *interactivePager {
+	return &interactivePager{
+		in:     in,
+		out:    out,
+		view:   newFoldView(doc),
+		marks:  map[string]int{},
+		width:  80,
+		height: 24,
+	}
+}
+
+// isInteractiveTerminal reports whether both ends of the viewer are attached to a terminal
+func isInteractiveTerminal(in, out *os.File) bool {
+	return term.IsTerminal(int(in.Fd())) && term.IsTerminal(int(out.Fd()))
+}
+
+// run takes over the terminal until the user quits
+func (ip *interactivePager) run() error {
+	fd := int(ip.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(ip.out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(ip.out, "\033[?25h\033[?1049l")
+
+	buf := make([]byte, 32)
+	for {
+		ip.draw()
+
+		n, err := ip.in.Read(buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		if quit := ip.handleKey(string(buf[:n])); quit {
+			return nil
+		}
+	}
+}
+
+// rows returns the number of content rows, leaving one for the status line
+func (ip *interactivePager) rows() int {
+	if ip.height < 2 {
+		return 1
+	}
+	return ip.height - 1
+}
+
+// handleKey applies a key press and reports whether the viewer should exit
+func (ip *interactivePager) handleKey(key string) bool {
+	ip.message = ""
+
+	if ip.pending != "" {
+		prefix := ip.pending
+		ip.pending = ""
+		switch {
+		case prefix == "m" && isMarkName(key):
+			ip.marks[key] = ip.cursor
+			ip.message = "Mark " + key + " set"
+		case (prefix == "'" || prefix == "`") && isMarkName(key):
+			ip.jumpToMark(key)
+		}
+		switch prefix + key {
+		case "gg":
+			ip.cursor = 0
+		case "zc":
+			ip.cursor = ip.view.close(ip.cursor)
+		case "zo":
+			ip.cursor = ip.view.open(ip.cursor)
+		case "za":
+			ip.cursor = ip.view.toggle(ip.cursor)
+		case "zM":
+			ip.view.closeAll()
+		case "zR":
+			ip.view.openAll()
+		}
+		ip.snap()
+		ip.scroll()
+		return false
+	}
+
+	switch key {
+	case "q", "Q", "\x03":
+		return true
+	case "j", "\x1b[B", "\r", "\x0e":
+		ip.move(1)
+	case "k", "\x1b[A", "\x10":
+		ip.move(-1)
+	case "\x04":
+		ip.move(ip.rows() / 2)
+	case "\x15":
+		ip.move(-ip.rows() / 2)
+	case " ", "f", "\x06", "\x1b[6~":
+		ip.move(ip.rows())
+	case "b", "\x02", "\x1b[5~":
+		ip.move(-ip.rows())
+	case "G", "\x1b[F":
+		ip.move(len(ip.view.lines))
+	case "\x1b[H":
+		ip.cursor = 0
+	case "g", "z", "m", "'", "`":
+		ip.pending = key
+	case "/":
+		if query, ok := ip.prompt("/"); ok && query != "" {
+			ip.search = query
+			ip.findNext(1)
+		}
+	case "n":
+		ip.findNext(1)
+	case "N":
+		ip.findNext(-1)
+	}
+
+	ip.scroll()
+	return false
+}
+
+// move moves the cursor by delta visible lines
+func (ip *interactivePager) move(delta int) {
+	visible := ip.view.visible()
+	pos := indexOf(visible, ip.cursor) + delta
+	if pos < 0 {
+		pos = 0
+	}
+	if pos >= len(visible) {
+		pos = len(visible) - 1
+	}
+	ip.cursor = visible[pos]
+}
+
+// snap moves the cursor onto the visible line that hides it after folds changed
+func (ip *interactivePager) snap() {
+	visible := ip.view.visible()
+	ip.cursor = visible[indexOf(visible, ip.cursor)]
+}
+
+// scroll adjusts the viewport so that the cursor stays on screen
+func (ip *interactivePager) scroll() {
+	pos := indexOf(ip.view.visible(), ip.cursor)
+	if pos < ip.top {
+		ip.top = pos
+	}
+	if pos >= ip.top+ip.rows() {
+		ip.top = pos - ip.rows() + 1
+	}
+}
+
+// findNext moves the cursor to the next line matching the current search, opening folds as needed
+func (ip *interactivePager) findNext(direction int) {
+	if ip.search == "" {
+		return
+	}
+
+	query := ip.search
+	ignoreCase := strings.ToLower(query) == query
+	if ignoreCase {
+		query = strings.ToLower(query)
+	}
+
+	total := len(ip.view.lines)
+	for step := 1; step <= total; step++ {
+		line := ((ip.cursor+direction*step)%total + total) % total
+		text := renderer.StripANSI(ip.view.lines[line])
+		if ignoreCase {
+			text = strings.ToLower(text)
+		}
+		if strings.Contains(text, query) {
+			ip.view.reveal(line)
+			ip.cursor = line
+			return
+		}
+	}
+	ip.message = "Pattern not found: " + ip.search
+}
+
+// jumpToMark moves the cursor to a bookmark set with m{a-z}
+func (ip *interactivePager) jumpToMark(name string) {
+	line, ok := ip.marks[name]
+	if !ok {
+		ip.message = "Mark " + name + " not set"
+		return
+	}
+	ip.view.reveal(line)
+	ip.cursor = line
+}
+
+// restore positions the cursor and bookmarks from a remembered file state.
+// unchanged reports whether the file content still matches the state's hash.
+func (ip *interactivePager) restore(state FileState, unchanged bool) {
+	lines := len(ip.view.lines)
+	ip.cursor = resolvePosition(state.Position, unchanged, ip.view.folds, lines)
+	for name, pos := range state.Bookmarks {
+		ip.marks[name] = resolvePosition(pos, unchanged, ip.view.folds, lines)
+	}
+	ip.top = ip.cursor
+}
+
+// state returns the cursor position and bookmarks for persisting
+func (ip *interactivePager) state(hash string) FileState {
+	state := FileState{
+		Hash:     hash,
+		Position: positionAt(ip.cursor, ip.view.folds),
+	}
+	if len(ip.marks) > 0 {
+		state.Bookmarks = map[string]Position{}
+		for name, line := range ip.marks {
+			state.Bookmarks[name] = positionAt(line, ip.view.folds)
+		}
+	}
+	return state
+}
+
+// isMarkName reports whether key names a bookmark
+func isMarkName(key string) bool {
+	return len(key) == 1 && key[0] >= 'a' && key[0] <= 'z'
+}
+
+// prompt reads a line of input on the status line
+func (ip *interactivePager) prompt(label string) (string, bool) {
+	var input []rune
+	buf := make([]byte, 32)
+	for {
+		ip.drawStatus(label + string(input))
+
+		n, err := ip.in.Read(buf)
+		if err != nil {
+			return "", false
+		}
+		switch key := string(buf[:n]); key {
+		case "\r", "\n":
+			return string(input), true
+		case "\x1b", "\x03":
+			return "", false
+		case "\x7f", "\b":
+			if len(input) == 0 {
+				return "", false
+			}
+			input = input[:len(input)-1]
+		default:
+			if !strings.HasPrefix(key, "\x1b") {
+				input = append(input, []rune(key)...)
+			}
+		}
+	}
+}
+
+// draw repaints the whole screen
+func (ip *interactivePager) draw() {
+	if w, h, err := term.GetSize(int(ip.out.Fd())); err == nil {
+		ip.width, ip.height = w, h
+	}
+	ip.scroll()
+
+	var buf bytes.Buffer
+	buf.WriteString("\033[H")
+
+	visible := ip.view.visible()
+	for row := 0; row < ip.rows(); row++ {
+		pos := ip.top + row
+		if pos < len(visible) {
+			line := visible[pos]
+			if line == ip.cursor {
+				buf.WriteString("\033[7m \033[0m")
+			} else {
+				buf.WriteString(" ")
+			}
+			buf.WriteString(renderer.TruncateANSI(ip.view.display(line), ip.width-1))
+		} else {
+			buf.WriteString(renderer.Dim + "~" + renderer.Reset)
+		}
+		buf.WriteString("\033[K\r\n")
+	}
+	ip.out.Write(buf.Bytes())
+
+	status := ip.message
+	if status == "" {
+		percent := 100
+		if len(ip.view.lines) > 1 {
+			percent = ip.cursor * 100 / (len(ip.view.lines) - 1)
+		}
+		status = fmt.Sprintf("line %d/%d (%d%%)  zc/zo/za fold  zM/zR all  m/' marks  / search  q quit",
+			ip.cursor+1, len(ip.view.lines), percent)
+	}
+	ip.drawStatus(status)
+}
+
+// drawStatus repaints the status line at the bottom of the screen
+func (ip *interactivePager) drawStatus(text string) {
+	text = renderer.TruncateANSI(text, ip.width)
+	fmt.Fprintf(ip.out, "\033[%d;1H\033[7m%s\033[K\033[0m", ip.height, renderer.PadRight(text, ip.width))
+}
+
+// indexOf returns the position of line in visible, or of the nearest visible line before it
+func indexOf(visible []int, line int) int {
+	pos := 0
+	for i, v := range visible {
+		if v > line {
+			break
+		}
+		pos = i
+	}
+	return pos
+}
@@ -4,52 +4,137 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 )
 
-// Pager handles the integration with the less command for displaying content
+// builtinPager is the MD_PAGER value that selects md's own interactive viewer
+const builtinPager = "builtin"
+
+// PagerOptions configures the external pager
+type PagerOptions struct {
+	// Command is the pager command line including any arguments, e.g.
+	// "moar --no-linenumbers". When empty it is taken from $MD_PAGER, then
+	// $PAGER, falling back to less.
+	Command string
+	// Chop cuts long lines at the edge of the screen instead of wrapping
+	// them (passes less -S)
+	Chop bool
+}
+
+// Pager handles the integration with an external pager command for displaying content
 type Pager struct {
-	lessPath string
+	path     string
+	args     []string
+	chop     bool
+	explicit bool
+	// err is why an explicitly chosen pager can't be used
+	err error
 }
 
-// NewPager creates a new Pager instance
+// NewPager creates a new Pager instance configured from the environment
 func NewPager() *Pager {
-	return &Pager{
-		lessPath: findLessCommand(),
+	return NewPagerWithOptions(PagerOptions{})
+}
+
+// NewPagerWithOptions creates a new Pager instance with explicit options
+func NewPagerWithOptions(opts PagerOptions) *Pager {
+	p := &Pager{chop: opts.Chop}
+
+	command, explicit := opts.Command, opts.Command != ""
+	if command == "" {
+		command = os.Getenv("MD_PAGER")
+		explicit = command != ""
+	}
+	if strings.TrimSpace(command) == builtinPager {
+		command, explicit = "", false
 	}
+	if command == "" {
+		command = os.Getenv("PAGER")
+	}
+
+	if fields := splitCommand(command); len(fields) > 0 {
+		path, err := exec.LookPath(fields[0])
+		if err == nil {
+			p.path = path
+			p.args = fields[1:]
+			p.explicit = explicit
+			return p
+		}
+		// A pager the user asked for is never silently replaced
+		if explicit {
+			p.explicit = true
+			p.err = fmt.Errorf("pager %q not found: %w", fields[0], err)
+			return p
+		}
+	}
+
+	p.path = findLessCommand()
+	return p
 }
 
-// IsLessAvailable checks if the less command is available on the system
+// IsAvailable checks if a pager command was found on the system
+func (p *Pager) IsAvailable() bool {
+	return p.path != ""
+}
+
+// IsLessAvailable checks if a pager command was found on the system
+//
+// Deprecated: the pager is no longer necessarily less; use IsAvailable.
 func (p *Pager) IsLessAvailable() bool {
-	return p.lessPath != ""
+	return p.IsAvailable()
+}
+
+// IsExplicit reports whether the pager was chosen with PagerOptions.Command
+// or $MD_PAGER, in which case it takes precedence over the built-in viewer
+func (p *Pager) IsExplicit() bool {
+	return p.explicit
+}
+
+// Err returns why the explicitly chosen pager can't be used, if it can't
+func (p *Pager) Err() error {
+	return p.err
 }
 
-// Display shows the content using less with vim-style navigation options
+// Name returns the base name of the pager command
+func (p *Pager) Name() string {
+	return filepath.Base(p.path)
+}
+
+// isLess reports whether the configured pager is less
+func (p *Pager) isLess() bool {
+	name := strings.TrimSuffix(p.Name(), ".exe")
+	return name == "less"
+}
+
+// Display shows the content using the configured pager
 func (p *Pager) Display(content string) error {
-	if p.lessPath == "" {
-		return fmt.Errorf("less command not available")
+	if p.err != nil {
+		return p.err
 	}
-
-	args := []string{
-		"-R", // Raw control characters (for ANSI colors)
-		"-S", // Chop long lines (don't wrap)
-		"-X", // Don't clear screen on exit
-		"-F", // Quit if entire file fits on screen
-		"-K", // Exit on Ctrl-C
-		"+g", // Start at beginning (gg equivalent)
+	if p.path == "" {
+		return fmt.Errorf("no pager command available")
 	}
 
-	env := append(os.Environ(),
-		"LESS_TERMCAP_md=\033[1;36m",    // Bold cyan for headings
-		"LESS_TERMCAP_us=\033[1;32m",    // Bold green for underline
-		"LESS_TERMCAP_so=\033[1;44;33m", // Bold yellow on blue for standout
-		"LESS_TERMCAP_se=\033[0m",       // End standout
-		"LESS_TERMCAP_ue=\033[0m",       // End underline
-		"LESS_TERMCAP_me=\033[0m",       // End bold/italic
-	)
+	args := p.args
+	env := os.Environ()
+
+	if p.isLess() {
+		args = lessArgs(args, p.chop)
 
-	cmd := exec.Command(p.lessPath, args...)
+		env = append(env,
+			"LESS_TERMCAP_md=\033[1;36m",    // Bold cyan for headings
+			"LESS_TERMCAP_us=\033[1;32m",    // Bold green for underline
+			"LESS_TERMCAP_so=\033[1;44;33m", // Bold yellow on blue for standout
+			"LESS_TERMCAP_se=\033[0m",       // End standout
+			"LESS_TERMCAP_ue=\033[0m",       // End underline
+			"LESS_TERMCAP_me=\033[0m",       // End bold/italic
+		)
+	}
+
+	cmd := exec.Command(p.path, args...)
 	cmd.Env = env
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
@@ -61,7 +146,7 @@ func (p *Pager) Display(content string) error {
 
 	if err := cmd.Start(); err != nil {
 		stdin.Close()
-		return fmt.Errorf("failed to start less: %w", err)
+		return fmt.Errorf("failed to start %s: %w", p.Name(), err)
 	}
 
 	go func() {
@@ -78,17 +163,106 @@ func (p *Pager) Display(content string) error {
 				}
 			}
 		}
-		return fmt.Errorf("less command failed: %w", err)
+		return fmt.Errorf("%s command failed: %w", p.Name(), err)
 	}
 
 	return nil
 }
 
+// lessArgs returns the arguments less is started with, given the user's own
+func lessArgs(args []string, chop bool) []string {
+	// Only pick less options when the user did not pass their own
+	if len(args) == 0 {
+		args = []string{
+			"-X", // Don't clear screen on exit
+			"-F", // Quit if entire file fits on screen
+			"-K", // Exit on Ctrl-C
+			"+g", // Start at beginning (gg equivalent)
+		}
+	}
+	// Without raw control characters less shows md's colors as ESC[...
+	if !hasRawOption(args) {
+		args = append([]string{"-R"}, args...)
+	} else {
+		args = append([]string(nil), args...)
+	}
+	if chop {
+		args = append(args, "-S") // Chop long lines (don't wrap)
+	}
+	return args
+}
+
+// hasRawOption reports whether less arguments include -R or -r, alone or
+// among other short options
+func hasRawOption(args []string) bool {
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return false
+		case strings.HasPrefix(arg, "--"):
+			if strings.EqualFold(arg, "--raw-control-chars") {
+				return true
+			}
+		case strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "-+"):
+			for _, option := range arg[1:] {
+				if option == 'r' || option == 'R' {
+					return true
+				}
+				// The rest of the argument is the value of this option
+				if strings.ContainsRune("bhjkoOpPtTxyz#D", option) {
+					break
+				}
+			}
+		}
+	}
+	return false
+}
+
 // Close performs cleanup (currently no resources to clean up)
 func (p *Pager) Close() error {
 	return nil
 }
 
+// splitCommand splits a pager command line into words, honouring single and
+// double quotes and backslash escapes the way a shell would
+func splitCommand(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != '\'' && r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
 // findLessCommand attempts to locate the less command on the system
 // TODO (codehakase): expand runtime checks, current version is non deterministic
 func findLessCommand() string {
@@ -0,0 +1,184 @@
+package viewer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/xdg"
+)
+
+// Position is a remembered place in a rendered document. Heading records the
+// section the line belonged to so the position can be recovered after edits.
+type Position struct {
+	Line    int    `json:"line"`
+	Heading string `json:"heading,omitempty"`
+}
+
+// FileState is everything remembered about a single file
+type FileState struct {
+	Hash      string              `json:"hash"`
+	Position  Position            `json:"position"`
+	Bookmarks map[string]Position `json:"bookmarks,omitempty"`
+	// Viewed is when the state was last saved
+	Viewed time.Time `json:"viewed,omitempty"`
+}
+
+// maxPositions is how many files the store remembers; the least recently
+// viewed are forgotten first
+const maxPositions = 500
+
+// PositionStore persists reading positions and bookmarks in a JSON state file
+type PositionStore struct {
+	path  string
+	limit int
+}
+
+// NewPositionStore creates a store backed by positions.json in md's XDG state directory
+func NewPositionStore() *PositionStore {
+	return newPositionStore(xdg.StatePath("positions.json"))
+}
+
+func newPositionStore(path string) *PositionStore {
+	return &PositionStore{path: path, limit: maxPositions}
+}
+
+// ContentHash returns the hash used to detect that a file changed since it was last viewed
+func ContentHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// Load returns the remembered state for file, if any
+func (ps *PositionStore) Load(file string) (FileState, bool) {
+	files, err := ps.read()
+	if err != nil {
+		return FileState{}, false
+	}
+	state, ok := files[file]
+	return state, ok
+}
+
+// Save stores the state for file, replacing anything remembered before
+func (ps *PositionStore) Save(file string, state FileState) error {
+	files, err := ps.read()
+	if err != nil {
+		files = map[string]FileState{}
+	}
+	state.Viewed = time.Now()
+	files[file] = state
+	forgetOldest(files, ps.limit)
+
+	data, err := json.MarshalIndent(files, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode positions: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ps.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Write through a temporary file so a concurrent reader never sees a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(ps.path), ".positions-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to write positions: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write positions: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write positions: %w", err)
+	}
+	return os.Rename(tmp.Name(), ps.path)
+}
+
+func (ps *PositionStore) read() (map[string]FileState, error) {
+	data, err := os.ReadFile(ps.path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]FileState{}
+	if err := json.Unmarshal(data, &files); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", ps.path, err)
+	}
+	return files, nil
+}
+
+// forgetOldest removes the least recently viewed files until at most limit
+// are left
+func forgetOldest(files map[string]FileState, limit int) {
+	if len(files) <= limit {
+		return
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return files[names[i]].Viewed.Before(files[names[j]].Viewed)
+	})
+	for _, name := range names[:len(names)-limit] {
+		delete(files, name)
+	}
+}
+
+// positionAt describes line in terms of the nearest heading above it
+func positionAt(line int, folds []renderer.Fold) Position {
+	pos := Position{Line: line}
+	for _, f := range folds {
+		if f.Kind == renderer.FoldSection && f.Start <= line {
+			pos.Heading = f.Title
+		}
+	}
+	return pos
+}
+
+// resolvePosition maps a remembered position back onto a document. When the
+// file is unchanged the line is used as is; otherwise the cursor moves to the
+// heading the position was recorded under, or the nearest line that exists.
+func resolvePosition(pos Position, unchanged bool, folds []renderer.Fold, lines int) int {
+	if unchanged || pos.Heading == "" {
+		return clampLine(pos.Line, lines)
+	}
+
+	best := -1
+	for _, f := range folds {
+		if f.Kind != renderer.FoldSection || f.Title != pos.Heading {
+			continue
+		}
+		if best == -1 || abs(f.Start-pos.Line) < abs(best-pos.Line) {
+			best = f.Start
+		}
+	}
+	if best == -1 {
+		return clampLine(pos.Line, lines)
+	}
+	return best
+}
+
+func clampLine(line, lines int) int {
+	if line >= lines {
+		line = lines - 1
+	}
+	if line < 0 {
+		line = 0
+	}
+	return line
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
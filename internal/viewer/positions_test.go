@@ -0,0 +1,98 @@
+package viewer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+)
+
+func TestPositionStoreRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ps := newPositionStore(filepath.Join(t.TempDir(), "state", "positions.json"))
+
+	if _, ok := ps.Load("/docs/a.md"); ok {
+		t.Fatal("Load() on empty store should report no state")
+	}
+
+	want := FileState{
+		Hash:      ContentHash([]byte("# A\n")),
+		Position:  Position{Line: 12, Heading: "Setup"},
+		Bookmarks: map[string]Position{"a": {Line: 3, Heading: "A"}},
+	}
+	if err := ps.Save("/docs/a.md", want); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+	if err := ps.Save("/docs/b.md", FileState{Hash: "other"}); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	got, ok := ps.Load("/docs/a.md")
+	if !ok {
+		t.Fatal("Load() did not find saved state")
+	}
+	if got.Hash != want.Hash || got.Position != want.Position || got.Bookmarks["a"] != want.Bookmarks["a"] {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPositionStoreForgetsOldest(t *testing.T) {
+	t.Parallel()
+
+	ps := newPositionStore(filepath.Join(t.TempDir(), "positions.json"))
+	ps.limit = 2
+
+	for _, file := range []string{"/docs/a.md", "/docs/b.md", "/docs/c.md"} {
+		if err := ps.Save(file, FileState{Hash: file}); err != nil {
+			t.Fatalf("Save(%s) returned error: %v", file, err)
+		}
+	}
+
+	if _, ok := ps.Load("/docs/a.md"); ok {
+		t.Error("Load() found the least recently viewed file after the store was full")
+	}
+	for _, file := range []string{"/docs/b.md", "/docs/c.md"} {
+		if _, ok := ps.Load(file); !ok {
+			t.Errorf("Load(%s) did not find saved state", file)
+		}
+	}
+}
+
+func TestResolvePosition(t *testing.T) {
+	t.Parallel()
+
+	folds := []renderer.Fold{
+		{Kind: renderer.FoldSection, Level: 1, Start: 0, End: 30, Title: "Guide"},
+		{Kind: renderer.FoldSection, Level: 2, Start: 8, End: 15, Title: "Setup"},
+		{Kind: renderer.FoldCodeBlock, Start: 10, End: 12, Title: "Setup"},
+		{Kind: renderer.FoldSection, Level: 2, Start: 20, End: 30, Title: "Usage"},
+	}
+
+	tests := []struct {
+		name      string
+		pos       Position
+		unchanged bool
+		want      int
+	}{
+		{"unchanged keeps line", Position{Line: 13, Heading: "Setup"}, true, 13},
+		{"changed moves to heading", Position{Line: 13, Heading: "Setup"}, false, 8},
+		{"changed heading gone keeps line", Position{Line: 13, Heading: "Removed"}, false, 13},
+		{"line past end is clamped", Position{Line: 99}, true, 30},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := resolvePosition(tt.pos, tt.unchanged, folds, 31); got != tt.want {
+				t.Errorf("resolvePosition() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := positionAt(17, folds); got.Heading != "Setup" {
+		t.Errorf("positionAt(17).Heading = %q, want %q", got.Heading, "Setup")
+	}
+}
@@ -0,0 +1,339 @@
+package viewer
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/slides"
+)
+
+// SlideRenderer renders the markdown of a slide wrapped to width columns
+type SlideRenderer func(markdown string, width int) (string, error)
+
+// PresentOptions configure a presentation
+type PresentOptions struct {
+	// Reveal shows the list items of each slide one step at a time
+	Reveal bool
+	// Notes, if set, receives the speaker notes of each slide as it is shown,
+	// for example a second terminal
+	Notes io.Writer
+}
+
+// Present shows a deck of slides full screen, one at a time, centered in
+// the terminal. When the terminal can't be driven interactively every slide
+// is printed instead, separated by its number.
+func Present(deck []slides.Slide, render SlideRenderer, options PresentOptions) error {
+	if len(deck) == 0 {
+		return fmt.Errorf("no slides to present")
+	}
+
+	if !isInteractiveTerminal(os.Stdin, os.Stdout) {
+		width := 80
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+			width = w
+		}
+		for i, slide := range deck {
+			content, err := render(slide.Markdown(-1), width)
+			if err != nil {
+				return err
+			}
+			label := fmt.Sprintf("── %d/%d ", i+1, len(deck))
+			fmt.Println(label + strings.Repeat("─", max(width-renderer.VisibleWidth(label), 0)))
+			fmt.Print(renderer.EnsureTrailingNewline(content))
+		}
+		return nil
+	}
+
+	p := newPresenter(deck, render, options, os.Stdin, os.Stdout)
+	return p.run()
+}
+
+// presenter is the full screen slide show
+type presenter struct {
+	in  *os.File
+	out *os.File
+
+	deck    []slides.Slide
+	render  SlideRenderer
+	options PresentOptions
+
+	slide     int
+	step      int
+	showNotes bool
+	// number collects the digits of a slide number to jump to
+	number  string
+	width   int
+	height  int
+	message string
+}
+
+func newPresenter(deck []slides.Slide, render SlideRenderer, options PresentOptions, in, out *os.File) *presenter {
+	return &presenter{
+		in:      in,
+		out:     out,
+		deck:    deck,
+		render:  render,
+		options: options,
+		width:   80,
+		height:  24,
+	}
+}
+
+// run takes over the terminal until the user quits
+func (p *presenter) run() error {
+	fd := int(p.in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("failed to enter raw mode: %w", err)
+	}
+	defer term.Restore(fd, state)
+
+	fmt.Fprint(p.out, "\033[?1049h\033[?25l")
+	defer fmt.Fprint(p.out, "\033[?25h\033[?1049l")
+
+	buf := make([]byte, 32)
+	shown := -1
+	for {
+		if p.slide != shown {
+			p.writeNotes()
+			shown = p.slide
+		}
+		if err := p.draw(); err != nil {
+			return err
+		}
+
+		n, err := p.in.Read(buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+
+		if quit := p.handleKey(string(buf[:n])); quit {
+			return nil
+		}
+	}
+}
+
+// handleKey applies a key press and reports whether the presentation should
+// end
+func (p *presenter) handleKey(key string) bool {
+	p.message = ""
+
+	if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+		p.number += key
+		p.message = "Slide " + p.number
+		return false
+	}
+	if p.number != "" {
+		number := p.number
+		p.number = ""
+		if key == "\r" || key == "g" || key == "G" {
+			p.goTo(number)
+			return false
+		}
+	}
+
+	switch key {
+	case "q", "Q", "\x03":
+		return true
+	case " ", "l", "j", "n", "\r", "\x1b[C", "\x1b[B", "\x1b[6~":
+		p.next()
+	case "h", "k", "p", "\x7f", "\b", "\x1b[D", "\x1b[A", "\x1b[5~":
+		p.previous()
+	case "g", "\x1b[H":
+		p.slide, p.step = 0, 0
+	case "G", "\x1b[F":
+		p.slide, p.step = len(p.deck)-1, 0
+	case "s":
+		p.showNotes = !p.showNotes
+	}
+	return false
+}
+
+// next reveals the next list item of the slide, or moves to the next slide
+func (p *presenter) next() {
+	if p.options.Reveal && p.step < p.deck[p.slide].Steps()-1 {
+		p.step++
+		return
+	}
+	if p.slide < len(p.deck)-1 {
+		p.slide++
+		p.step = 0
+	}
+}
+
+// previous hides the last list item shown, or moves back to the previous
+// slide, shown whole
+func (p *presenter) previous() {
+	if p.options.Reveal && p.step > 0 {
+		p.step--
+		return
+	}
+	if p.slide > 0 {
+		p.slide--
+		p.step = 0
+		if p.options.Reveal {
+			p.step = p.deck[p.slide].Steps() - 1
+		}
+	}
+}
+
+// goTo moves to a slide by its 1-based number
+func (p *presenter) goTo(number string) {
+	n, err := strconv.Atoi(number)
+	if err != nil || n < 1 || n > len(p.deck) {
+		p.message = fmt.Sprintf("No slide %s", number)
+		return
+	}
+	p.slide, p.step = n-1, 0
+}
+
+// markdown returns the part of the current slide to show
+func (p *presenter) markdown() string {
+	if !p.options.Reveal {
+		return p.deck[p.slide].Markdown(-1)
+	}
+	return p.deck[p.slide].Markdown(p.step)
+}
+
+// contentWidth is the width slides are rendered at, leaving a margin that
+// grows with the terminal
+func contentWidth(width int) int {
+	return max(width-2*max(width/10, 2), 20)
+}
+
+// draw repaints the whole screen
+func (p *presenter) draw() error {
+	if w, h, err := term.GetSize(int(p.out.Fd())); err == nil {
+		p.width, p.height = w, h
+	}
+
+	width := contentWidth(p.width)
+	full, err := p.render(p.deck[p.slide].Markdown(-1), width)
+	if err != nil {
+		return err
+	}
+	shown := full
+	if p.options.Reveal {
+		if shown, err = p.render(p.markdown(), width); err != nil {
+			return err
+		}
+	}
+
+	var notes []string
+	if p.showNotes {
+		notes = p.notesPanel()
+	}
+	rows := max(p.height-1-len(notes), 1)
+
+	var buf bytes.Buffer
+	buf.WriteString("\033[H")
+	for _, line := range center(trimLines(full), trimLines(shown), p.width, rows) {
+		buf.WriteString(line)
+		buf.WriteString("\033[K\r\n")
+	}
+	for _, line := range notes {
+		buf.WriteString(renderer.TruncateANSI(line, p.width))
+		buf.WriteString("\033[K\r\n")
+	}
+	p.out.Write(buf.Bytes())
+
+	p.drawStatus()
+	return nil
+}
+
+// notesPanel returns the lines of the speaker notes shown under the slide
+func (p *presenter) notesPanel() []string {
+	lines := []string{renderer.Dim + strings.Repeat("─", p.width) + renderer.Reset}
+	notes := p.deck[p.slide].Notes
+	if len(notes) == 0 {
+		return append(lines, renderer.Dim+"No speaker notes"+renderer.Reset)
+	}
+	for _, note := range notes {
+		lines = append(lines, strings.Split(renderer.WrapANSI(renderer.SanitizeText(note), p.width, ""), "\n")...)
+	}
+	// Keep at least half of the screen for the slide
+	return lines[:min(len(lines), max(p.height/2, 2))]
+}
+
+// drawStatus repaints the status line with the slide title and number
+func (p *presenter) drawStatus() {
+	slide := p.deck[p.slide]
+	left := p.message
+	if left == "" {
+		left = renderer.SanitizeText(slide.Title)
+	}
+	right := fmt.Sprintf("%d/%d", p.slide+1, len(p.deck))
+	if p.options.Reveal && slide.Steps() > 1 {
+		right = strings.Repeat("●", p.step) + strings.Repeat("○", slide.Steps()-1-p.step) + "  " + right
+	}
+	left = renderer.TruncateANSI(left, max(p.width-len([]rune(right))-3, 0))
+	gap := max(p.width-renderer.VisibleWidth(left)-len([]rune(right))-2, 1)
+	fmt.Fprintf(p.out, "\033[%d;1H%s %s%s%s \033[K%s", p.height, renderer.Dim, left, strings.Repeat(" ", gap), right, renderer.Reset)
+}
+
+// writeNotes sends the speaker notes of the current slide, and the title of
+// the next one, to the notes writer
+func (p *presenter) writeNotes() {
+	w := p.options.Notes
+	if w == nil {
+		return
+	}
+	if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(w, "\033[H\033[2J")
+	}
+
+	slide := p.deck[p.slide]
+	fmt.Fprintf(w, "Slide %d/%d: %s\n\n", p.slide+1, len(p.deck), renderer.SanitizeText(slide.Title))
+	for _, note := range slide.Notes {
+		fmt.Fprintln(w, renderer.SanitizeText(note))
+		fmt.Fprintln(w)
+	}
+	if p.slide < len(p.deck)-1 {
+		fmt.Fprintf(w, "Next: %s\n", renderer.SanitizeText(p.deck[p.slide+1].Title))
+	}
+	fmt.Fprintln(w)
+}
+
+// center lays out the shown lines of a slide in a screen of width by rows,
+// centered by the size of the full slide so revealing more of it doesn't
+// move what is already shown. Slides that don't fit are cut.
+func center(full, shown []string, width, rows int) []string {
+	contentWidth := 0
+	for _, line := range full {
+		contentWidth = max(contentWidth, renderer.VisibleWidth(line))
+	}
+	left := strings.Repeat(" ", max((width-contentWidth)/2, 0))
+	top := max((rows-len(full))/2, 0)
+
+	screen := make([]string, rows)
+	for i, line := range shown {
+		if top+i >= rows {
+			break
+		}
+		screen[top+i] = renderer.TruncateANSI(left+line, width)
+	}
+	return screen
+}
+
+// trimLines splits rendered content into lines without the blank lines
+// around it
+func trimLines(content string) []string {
+	lines := strings.Split(content, "\n")
+	for len(lines) > 0 && strings.TrimSpace(renderer.StripANSI(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(renderer.StripANSI(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,89 @@
+package viewer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/codehakase/md/internal/renderer"
+	"github.com/codehakase/md/internal/slides"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func newTestPresenter(reveal bool) *presenter {
+	source := "# One\n\n- a\n- b\n\n---\n\n# Two\n\nText.\n\n---\n\n# Three\n\n- c\n"
+	deck := slides.Split(renderer.New(theme.NewWithBackground(theme.BackgroundDark)), []byte(source), slides.SplitAuto)
+	return newPresenter(deck, nil, PresentOptions{Reveal: reveal}, nil, nil)
+}
+
+func TestPresenterNavigation(t *testing.T) {
+	t.Parallel()
+
+	type position struct{ slide, step int }
+	tests := []struct {
+		name   string
+		reveal bool
+		keys   []string
+		want   []position
+	}{
+		{
+			name: "slides",
+			keys: []string{" ", " ", " ", "h", "\x1b[D", "\x1b[D"},
+			want: []position{{1, 0}, {2, 0}, {2, 0}, {1, 0}, {0, 0}, {0, 0}},
+		},
+		{
+			name:   "reveal",
+			reveal: true,
+			keys:   []string{" ", " ", " ", " ", "\x1b[D", "\x1b[D"},
+			want:   []position{{0, 1}, {0, 2}, {1, 0}, {2, 0}, {1, 0}, {0, 2}},
+		},
+		{
+			name: "jumps",
+			keys: []string{"G", "g", "3", "\r", "1", "g", "9", "\r"},
+			want: []position{{2, 0}, {0, 0}, {0, 0}, {2, 0}, {2, 0}, {0, 0}, {0, 0}, {0, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := newTestPresenter(tt.reveal)
+			for i, key := range tt.keys {
+				if quit := p.handleKey(key); quit {
+					t.Fatalf("key %q quit the presentation", key)
+				}
+				if got := (position{p.slide, p.step}); got != tt.want[i] {
+					t.Errorf("after key %d %q at %+v, want %+v", i, key, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPresenterQuit(t *testing.T) {
+	t.Parallel()
+
+	p := newTestPresenter(false)
+	if !p.handleKey("q") {
+		t.Error("q didn't quit the presentation")
+	}
+}
+
+func TestCenter(t *testing.T) {
+	t.Parallel()
+
+	full := []string{"Title", "a", "b"}
+	got := center(full, full[:2], 11, 5)
+	want := []string{"", "   Title", "   a", "", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("center() = %q, want %q", got, want)
+	}
+
+	// Slides taller than the screen start at the top and are cut
+	got = center(full, full, 11, 2)
+	want = []string{"   Title", "   a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("center() of a tall slide = %q, want %q", got, want)
+	}
+}
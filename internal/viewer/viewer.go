@@ -2,13 +2,21 @@ package viewer
 
 import (
 	"fmt"
+	"os"
 	"runtime"
 	"strings"
+
+	"golang.org/x/term"
+
+	"github.com/codehakase/md/internal/renderer"
 )
 
 // Viewer provides a vim-style interface for viewing rendered markdown content
 type Viewer struct {
-	pager *Pager
+	pager     *Pager
+	positions *PositionStore
+	file      string
+	hash      string
 }
 
 // New creates a new Viewer instance
@@ -18,22 +26,87 @@ func New() *Viewer {
 	}
 }
 
-// DisplayInVimMode displays the given content in a less-like interface with vim-style navigation
+// NewWithPagerOptions creates a new Viewer instance using the given external pager options
+func NewWithPagerOptions(opts PagerOptions) *Viewer {
+	return &Viewer{
+		pager: NewPagerWithOptions(opts),
+	}
+}
+
+// DisplayInVimMode displays the given content in the external pager. When
+// stdout is not a terminal the content is written directly instead.
 func (v *Viewer) DisplayInVimMode(content string) error {
 	if content == "" {
 		return fmt.Errorf("no content to display")
 	}
 
-	if !v.pager.IsLessAvailable() {
+	if !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Print(content)
+		return nil
+	}
+
+	if err := v.pager.Err(); err != nil {
+		return err
+	}
+	if !v.pager.IsAvailable() {
 		return v.fallbackDisplay(content)
 	}
 
 	return v.pager.Display(content)
 }
 
-// fallbackDisplay provides a simple fallback when less is not available
+// RememberPosition enables restoring and saving the reading position and
+// bookmarks of the given file, keyed by its absolute path and content hash
+func (v *Viewer) RememberPosition(filename string, source []byte) {
+	if v.positions == nil {
+		v.positions = NewPositionStore()
+	}
+	v.file = filename
+	v.hash = ContentHash(source)
+}
+
+// DisplayDocument displays a rendered document in the built-in interactive
+// viewer, which supports folding sections with zc/zo/za/zM/zR. It uses the
+// external pager instead when one was chosen explicitly or the terminal
+// cannot be driven interactively.
+func (v *Viewer) DisplayDocument(doc *renderer.Document) error {
+	if doc == nil || doc.Content == "" {
+		return fmt.Errorf("no content to display")
+	}
+
+	if v.pager.IsExplicit() || !isInteractiveTerminal(os.Stdin, os.Stdout) {
+		return v.DisplayInVimMode(doc.Content)
+	}
+
+	// Like less -F, print short documents directly instead of taking over the screen
+	if _, height, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+		if strings.Count(doc.Content, "\n") < height {
+			fmt.Print(doc.Content)
+			return nil
+		}
+	}
+
+	pager := newInteractivePager(doc, os.Stdin, os.Stdout)
+	if v.file != "" {
+		if state, ok := v.positions.Load(v.file); ok {
+			pager.restore(state, state.Hash == v.hash)
+		}
+	}
+
+	if err := pager.run(); err != nil {
+		return err
+	}
+
+	if v.file != "" {
+		// Failing to remember the position must not turn a successful view into an error
+		_ = v.positions.Save(v.file, pager.state(v.hash))
+	}
+	return nil
+}
+
+// fallbackDisplay provides a simple fallback when no pager is available
 func (v *Viewer) fallbackDisplay(content string) error {
-	fmt.Println("Note: 'less' command not available, displaying content directly:")
+	fmt.Println("Note: no pager command available, displaying content directly:")
 	fmt.Println(strings.Repeat("-", 80))
 	fmt.Print(content)
 	if !strings.HasSuffix(content, "\n") {
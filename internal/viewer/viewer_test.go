@@ -1,6 +1,7 @@
 package viewer
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -69,3 +70,97 @@ func containsHelper(s, substr string) bool {
 	return false
 }
 
+func TestSplitCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"less", []string{"less"}},
+		{"  moar   --no-linenumbers ", []string{"moar", "--no-linenumbers"}},
+		{`bat --style="plain, changes"`, []string{"bat", "--style=plain, changes"}},
+		{`most 'a b' c\ d`, []string{"most", "a b", "c d"}},
+		{`less ""`, []string{"less", ""}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.input, func(t *testing.T) {
+			t.Parallel()
+
+			result := splitCommand(tt.input)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("splitCommand(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+			for i := range result {
+				if result[i] != tt.expected[i] {
+					t.Errorf("splitCommand(%q) = %q, want %q", tt.input, result, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPagerWithOptions(t *testing.T) {
+	t.Parallel()
+
+	p := NewPagerWithOptions(PagerOptions{Command: "sh -c cat"})
+	if !p.IsAvailable() {
+		t.Skip("sh not available")
+	}
+	if !p.IsExplicit() {
+		t.Error("pager from PagerOptions.Command should be explicit")
+	}
+	if p.Name() != "sh" || len(p.args) != 2 {
+		t.Errorf("unexpected pager %s %q", p.Name(), p.args)
+	}
+
+	p = NewPagerWithOptions(PagerOptions{Command: builtinPager})
+	if p.IsExplicit() {
+		t.Error("builtin pager should not be explicit")
+	}
+
+	// A pager chosen explicitly but missing is an error, not a fallback to less
+	p = NewPagerWithOptions(PagerOptions{Command: "md-no-such-pager --flag"})
+	if !p.IsExplicit() || p.Err() == nil {
+		t.Errorf("missing explicit pager: IsExplicit() = %v, Err() = %v, want an error", p.IsExplicit(), p.Err())
+	}
+	if err := p.Display("text"); err == nil {
+		t.Error("Display() with a missing pager should fail")
+	}
+}
+
+func TestLessArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args []string
+		chop bool
+		want []string
+	}{
+		{"defaults", nil, false, []string{"-R", "-X", "-F", "-K", "+g"}},
+		{"defaults chopped", nil, true, []string{"-R", "-X", "-F", "-K", "+g", "-S"}},
+		{"user arguments", []string{"-S"}, false, []string{"-R", "-S"}},
+		{"user arguments chopped", []string{"-i"}, true, []string{"-R", "-i", "-S"}},
+		{"user -R", []string{"-R", "-S"}, false, []string{"-R", "-S"}},
+		{"user -r", []string{"-r"}, false, []string{"-r"}},
+		{"combined options", []string{"-iRS"}, false, []string{"-iRS"}},
+		{"long option", []string{"--RAW-CONTROL-CHARS"}, false, []string{"--RAW-CONTROL-CHARS"}},
+		// The r belongs to the prompt, not an option
+		{"option value", []string{"-Pprompt"}, false, []string{"-R", "-Pprompt"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := lessArgs(tt.args, tt.chop); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lessArgs(%q, %v) = %q, want %q", tt.args, tt.chop, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Package xdg resolves the base directories defined by the XDG Base
+// Directory specification that md uses for its configuration and state.
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// appName is the subdirectory md uses within each base directory
+const appName = "md"
+
+// ConfigHome returns $XDG_CONFIG_HOME, defaulting to ~/.config
+func ConfigHome() string {
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// StateHome returns $XDG_STATE_HOME, defaulting to ~/.local/state
+func StateHome() string {
+	return baseDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// ConfigPath returns the path of elem within md's configuration directory
+func ConfigPath(elem ...string) string {
+	return filepath.Join(append([]string{ConfigHome(), appName}, elem...)...)
+}
+
+// StatePath returns the path of elem within md's state directory
+func StatePath(elem ...string) string {
+	return filepath.Join(append([]string{StateHome(), appName}, elem...)...)
+}
+
+// baseDir returns the directory named by env if it is an absolute path, as
+// the specification requires relative paths to be ignored, and otherwise the
+// fallback relative to the user's home directory
+func baseDir(env, fallback string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), fallback)
+	}
+	return filepath.Join(home, fallback)
+}
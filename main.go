@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/codehakase/md/internal/config"
 	"github.com/codehakase/md/internal/highlighter"
 	"github.com/codehakase/md/internal/renderer"
 	"github.com/codehakase/md/internal/theme"
@@ -16,16 +18,30 @@ import (
 var (
 	plainMode bool
 	watchMode bool
+	chopLines bool
+	colorMode string
+	// allowRawEscapes is deliberately not a config setting, so a config file
+	// shipped with an untrusted document can't turn sanitizing off
+	allowRawEscapes bool
 )
 
+// configFlags are the flags that override settings from the config files
+var configFlags = []string{
+	config.KeyTheme, config.KeyCodeTheme, config.KeyWidth, config.KeyPager,
+	config.KeyLinkMode, config.KeyHyperlinks, config.KeyExtensions,
+	config.KeyLineNumbers, config.KeyCodeFrame, config.KeyCodeBackground,
+	config.KeyInlineCode,
+}
+
 var rootCmd = &cobra.Command{
-	Use:   "md [flags] <markdown-file>",
+	Use:   "md [flags] <markdown-file>[#section]",
 	Short: "A markdown renderer and viewer for the terminal",
 	Long: `md is a command-line tool that renders markdown files with syntax highlighting
 and provides options for vim-style navigation.`,
-	Args: cobra.ExactArgs(1),
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeFileArg,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		filename := args[0]
+		filename, anchor := splitAnchor(args[0])
 		if !filepath.IsAbs(filename) {
 			var err error
 			filename, err = filepath.Abs(filename)
@@ -38,22 +54,57 @@ and provides options for vim-style navigation.`,
 			return fmt.Errorf("file not found: %s", filename)
 		}
 
-		themeManager := theme.New()
-		mdRenderer := renderer.New(themeManager)
-		codeHighlighter := highlighter.New(themeManager)
-		mdViewer := viewer.New()
+		mode, err := theme.ParseColorMode(colorMode)
+		if err != nil {
+			return err
+		}
+
+		// Errors from here on are about the settings or the document
+		cmd.SilenceUsage = true
+		cfg, err := loadConfig(cmd, filepath.Dir(filename))
+		if err != nil {
+			return err
+		}
+
+		themeManager, options, codeHighlighter, err := renderSetup(cfg, mode)
+		if err != nil {
+			return err
+		}
+		options.BaseDir = filepath.Dir(filename)
+		options.EmbedRoot = projectRoot(filepath.Dir(filename))
+		options.AllowRawEscapes = allowRawEscapes
+		mdRenderer := renderer.NewWithOptions(themeManager, options)
+		mdViewer := viewer.NewWithPagerOptions(viewer.PagerOptions{
+			Command: cfg.Pager,
+			Chop:    chopLines,
+		})
 
 		renderAndDisplay := func() error {
-			content, err := mdRenderer.RenderFile(filename, codeHighlighter)
+			source, err := os.ReadFile(filename)
+			if err != nil {
+				return fmt.Errorf("error reading file: %v", err)
+			}
+
+			if anchor != "" {
+				source, err = mdRenderer.Section(source, anchor)
+				if err != nil {
+					return fmt.Errorf("%s: %w", filepath.Base(filename), err)
+				}
+			}
+
+			doc, err := mdRenderer.RenderDocument(source, codeHighlighter)
 			if err != nil {
 				return fmt.Errorf("rendering error: %v", err)
 			}
 
 			if plainMode {
-				fmt.Print(content)
+				fmt.Print(doc.Content)
 				return nil
 			} else {
-				return mdViewer.DisplayInVimMode(content)
+				if anchor == "" {
+					mdViewer.RememberPosition(filename, source)
+				}
+				return mdViewer.DisplayDocument(doc)
 			}
 		}
 
@@ -61,11 +112,123 @@ and provides options for vim-style navigation.`,
 	},
 }
 
+// renderSetup builds the theme, renderer options and code highlighter that
+// display documents with the settings in cfg
+func renderSetup(cfg *config.Config, mode theme.ColorMode) (*theme.ThemeManager, renderer.Options, *highlighter.Highlighter, error) {
+	themeManager := theme.New()
+	themeManager.SetColorMode(mode)
+	if err := themeManager.SetTheme(cfg.Theme); err != nil {
+		return nil, renderer.Options{}, nil, settingError(cfg, config.KeyTheme, err)
+	}
+	if cfg.CodeTheme != "" {
+		if err := themeManager.SetChromaTheme(cfg.CodeTheme); err != nil {
+			return nil, renderer.Options{}, nil, settingError(cfg, config.KeyCodeTheme, err)
+		}
+	}
+
+	linkMode, err := renderer.ParseLinkMode(cfg.LinkMode)
+	if err != nil {
+		return nil, renderer.Options{}, nil, settingError(cfg, config.KeyLinkMode, err)
+	}
+	if err := renderer.ValidateExtensions(cfg.Extensions); err != nil {
+		return nil, renderer.Options{}, nil, settingError(cfg, config.KeyExtensions, err)
+	}
+	options := renderer.Options{
+		Width:      cfg.Width,
+		LinkMode:   linkMode,
+		Hyperlinks: cfg.Hyperlinks,
+		Extensions: cfg.Extensions,
+
+		LineNumbers:    cfg.LineNumbers,
+		CodeFrame:      cfg.CodeFrame,
+		CodeBackground: cfg.CodeBackground,
+
+		Limits: renderer.Limits{
+			MaxInputSize:    cfg.MaxInputSize,
+			MaxNesting:      cfg.MaxNesting,
+			MaxTableRows:    cfg.MaxTableRows,
+			MaxTableColumns: cfg.MaxTableColumns,
+			MaxCodeLines:    cfg.MaxCodeLines,
+		},
+	}
+
+	// A broken lexer file only matters to code blocks in its language, so it
+	// doesn't stop documents from rendering; md languages reports it as an error
+	if _, err := highlighter.RegisterLexers(highlighter.LexerDir()); err != nil {
+		fmt.Fprintf(os.Stderr, "md: warning: %v\n", err)
+	}
+	codeHighlighter := highlighter.New(themeManager)
+	codeHighlighter.SetTimeout(cfg.HighlightTimeout)
+	if err := codeHighlighter.Languages().AddAliases(cfg.Aliases); err != nil {
+		return nil, renderer.Options{}, nil, settingError(cfg, config.KeyAliases, err)
+	}
+	if err := codeHighlighter.SetInlineMode(cfg.InlineCode); err != nil {
+		return nil, renderer.Options{}, nil, settingError(cfg, config.KeyInlineCode, err)
+	}
+	return themeManager, options, codeHighlighter, nil
+}
+
+// splitAnchor splits "file.md#section" into the file and the heading anchor
+// of the section to render. Arguments naming an existing file are never split.
+func splitAnchor(arg string) (string, string) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, ""
+	}
+	if i := strings.LastIndex(arg, "#"); i > 0 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
+
+// loadConfig loads the settings for a document in dir and applies the
+// config flags given on the command line on top
+func loadConfig(cmd *cobra.Command, dir string) (*config.Config, error) {
+	cfg, err := config.Load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range configFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || !flag.Changed {
+			continue
+		}
+		if err := cfg.Set(name, flag.Value.String(), config.Origin{Source: config.SourceFlag, Detail: "--" + name}); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// settingError annotates err with where the offending setting was configured
+func settingError(cfg *config.Config, key string, err error) error {
+	origin := cfg.Origin(key)
+	if origin.Source == config.SourceFlag {
+		return err
+	}
+	return fmt.Errorf("%w (%s set by %s)", err, key, origin)
+}
+
 func init() {
 	rootCmd.Flags().BoolVarP(&plainMode, "plain", "p", false, "Render entire markdown")
+	rootCmd.Flags().String(config.KeyPager, "", "External pager command with arguments (default $MD_PAGER, then the built-in viewer; $PAGER only where that can't run)")
+	rootCmd.Flags().String(config.KeyTheme, "auto", "Theme name or file: auto, dark, light, high-contrast, deuteranopia, protanopia or a theme from ~/.config/md/themes")
+	rootCmd.Flags().String(config.KeyCodeTheme, "", "Chroma style for code blocks (default follows the theme; see 'md code-themes')")
+	rootCmd.Flags().Int(config.KeyWidth, 0, "Wrap paragraphs to this many columns (0 disables wrapping)")
+	rootCmd.Flags().String(config.KeyLinkMode, "inline", "How to show link destinations: inline, footnote or hidden")
+	rootCmd.Flags().Bool(config.KeyHyperlinks, false, "Make links clickable with OSC 8 terminal hyperlinks")
+	rootCmd.Flags().String(config.KeyExtensions, "gfm", "Comma separated markdown extensions: "+strings.Join(renderer.Extensions(), ", "))
+	rootCmd.Flags().Bool(config.KeyLineNumbers, false, "Number the lines of code blocks")
+	rootCmd.Flags().Bool(config.KeyCodeFrame, false, "Draw a frame labelled with the language around code blocks")
+	rootCmd.Flags().Bool(config.KeyCodeBackground, false, "Fill code blocks with the code theme's background color")
+	rootCmd.Flags().String(config.KeyInlineCode, "plain", "How to highlight inline code without a {:lang} hint: plain, auto or a language")
+	rootCmd.Flags().StringVar(&colorMode, "color", "auto", "When to use colors: auto, always or never")
+	rootCmd.Flags().BoolVar(&chopLines, "chop", false, "Chop long lines in the external pager instead of wrapping them")
+	rootCmd.Flags().BoolVar(&allowRawEscapes, "allow-raw-escapes", false, "Pass escape sequences in the document through to the terminal; only for trusted documents")
 }
 
 func main() {
+	registerCompletions()
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/codehakase/md/internal/search"
+	"github.com/codehakase/md/internal/stats"
+	"github.com/codehakase/md/internal/theme"
+)
+
+func TestSplitAnchor(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	hashed := filepath.Join(dir, "notes#1.md")
+	if err := os.WriteFile(hashed, []byte("# Notes\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc := filepath.Join(dir, "doc.md")
+
+	tests := []struct {
+		name       string
+		arg        string
+		wantFile   string
+		wantAnchor string
+	}{
+		{"no anchor", doc, doc, ""},
+		{"anchor", doc + "#install", doc, "install"},
+		{"empty anchor", doc + "#", doc, ""},
+		{"file named with #", hashed, hashed, ""},
+		{"anchor in file named with #", hashed + "#notes", hashed, "notes"},
+		{"only an anchor", "#install", "#install", ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			file, anchor := splitAnchor(tt.arg)
+			if file != tt.wantFile || anchor != tt.wantAnchor {
+				t.Errorf("splitAnchor(%q) = %q, %q, want %q, %q", tt.arg, file, anchor, tt.wantFile, tt.wantAnchor)
+			}
+		})
+	}
+}
+
+func TestCompleteFileArg(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	doc := filepath.Join(dir, "guide#2.md")
+	source := "# Guide\n\n## Install steps\n\n## Usage\n\n### Install options\n"
+	if err := os.WriteFile(doc, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		toComplete    string
+		want          []string
+		wantDirective cobra.ShellCompDirective
+	}{
+		{"files", "gui", markdownExtensions, cobra.ShellCompDirectiveFilterFileExt},
+		{
+			name:       "all anchors",
+			toComplete: doc + "#",
+			want: []string{
+				doc + "#guide\tGuide",
+				doc + "#install-steps\tInstall steps",
+				doc + "#usage\tUsage",
+				doc + "#install-options\tInstall options",
+			},
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{
+			name:          "anchor prefix",
+			toComplete:    doc + "#inst",
+			want:          []string{doc + "#install-steps\tInstall steps", doc + "#install-options\tInstall options"},
+			wantDirective: cobra.ShellCompDirectiveNoFileComp,
+		},
+		{"no matching anchor", doc + "#missing", nil, cobra.ShellCompDirectiveNoFileComp},
+		{"missing file", filepath.Join(dir, "missing.md#"), nil, cobra.ShellCompDirectiveNoFileComp},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, directive := completeFileArg(rootCmd, nil, tt.toComplete)
+			if !reflect.DeepEqual(got, tt.want) || directive != tt.wantDirective {
+				t.Errorf("completeFileArg(%q) = %q, %v, want %q, %v", tt.toComplete, got, directive, tt.want, tt.wantDirective)
+			}
+		})
+	}
+}
+
+func TestProjectRoot(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	docs := filepath.Join(root, "docs", "guide")
+	for _, dir := range []string{filepath.Join(root, ".git"), docs} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A project file below the repository doesn't narrow the root
+	if err := os.WriteFile(filepath.Join(root, "docs", ".md.toml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := projectRoot(docs); got != root {
+		t.Errorf("projectRoot() in a repository = %q, want %q", got, root)
+	}
+
+	plain := t.TempDir()
+	if got := projectRoot(plain); got != plain {
+		t.Errorf("projectRoot() outside any project = %q, want %q", got, plain)
+	}
+}
+
+func TestGitShowRejectsOptions(t *testing.T) {
+	t.Parallel()
+
+	output := filepath.Join(t.TempDir(), "x")
+	for _, rev := range []string{"--output=" + output, "-p", ""} {
+		if _, err := gitShow(rev, "README.md"); err == nil || !strings.Contains(err.Error(), "invalid git revision") {
+			t.Errorf("gitShow(%q) error = %v, want the revision rejected", rev, err)
+		}
+	}
+	if matches, _ := filepath.Glob(output + "*"); len(matches) > 0 {
+		t.Errorf("gitShow() created %v", matches)
+	}
+}
+
+func TestOutputSanitizesDocumentNames(t *testing.T) {
+	t.Parallel()
+
+	file := "ev\x1b]0;T\x07.md"
+	tm := theme.NewWithBackground(theme.BackgroundDark)
+	tm.SetColorProfile(theme.ProfileNone)
+
+	var out strings.Builder
+	report := &stats.Report{File: file, Stats: stats.Stats{CodeBlocks: map[string]int{"sh\x1b]0;pwn\x07": 1}}}
+	if err := printStats(&out, report); err != nil {
+		t.Fatal(err)
+	}
+	printMatch(&out, tm, file, search.Match{Line: 1, Text: "text"})
+
+	if got := out.String(); strings.ContainsAny(got, "\x1b\x07") {
+		t.Errorf("output = %q, want control characters replaced", got)
+	}
+	if got := out.String(); !strings.Contains(got, "ev␛]0;T␇.md") || !strings.Contains(got, "sh␛]0;pwn␇ 1") {
+		t.Errorf("output = %q, want the names shown with symbols", got)
+	}
+}
+
+// Not parallel: completions are registered on the package's commands
+func TestRegisterCompletions(t *testing.T) {
+	// Panics if a completion is registered for a flag that doesn't exist
+	registerCompletions()
+
+	if _, ok := grepCmd.GetFlagCompletionFunc("in"); !ok {
+		t.Error("md grep --in has no completion")
+	}
+}